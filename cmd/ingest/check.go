@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// checkPattern prints whether a digest of the source would include path
+// and which pattern or limit decides, then exits with exitNoFiles if the
+// path is excluded. A relative path is relative to the source.
+func checkPattern(path string, cfg *config.Config, report *reporter) {
+	if remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source) {
+		report.fail(exitFailure, "usage", "", "check-pattern requires a local directory as the source")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.Source, path)
+	}
+
+	decision, err := analyzer.Explain(cfg.Source, path, cfg)
+	if err != nil {
+		report.fail(exitFailure, "usage", path, "Cannot check '%s': %v", path, err)
+	}
+
+	name := relativeTo(cfg.Source, path)
+	switch {
+	case decision.Included && decision.Reason == "":
+		fmt.Printf("%s: included, no pattern excludes it\n", name)
+	case decision.Included:
+		fmt.Printf("%s: included by %s\n", name, decision.Reason)
+	case filepath.Clean(decision.Path) != filepath.Clean(config.AbsPath(path)):
+		fmt.Printf("%s: excluded by %s on directory %s\n", name, decision.Reason, relativeTo(cfg.Source, decision.Path))
+	default:
+		fmt.Printf("%s: excluded by %s\n", name, decision.Reason)
+	}
+
+	if !decision.Included {
+		report.exit(exitNoFiles)
+	}
+	report.exit(exitOK)
+}
+
+// relativeTo returns path relative to the source in slash form, or path
+// itself if it lies outside
+func relativeTo(source, path string) string {
+	rel, err := filepath.Rel(config.AbsPath(source), config.AbsPath(path))
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"regexp"
+	"strings"
+)
+
+// commandIgnoredFlags are flags that do not change the digest: logging,
+// profiling, watch mode, and the options file, whose settings are recorded
+// as flags
+var commandIgnoredFlags = map[string]bool{
+	"v": true, "vv": true, "verbose": true, "log-format": true, "error-format": true,
+	"pprof": true, "pprof-output": true, "profile": true, "profile-output": true,
+	"config": true, "config-profile": true,
+	"watch": true, "push-url": true,
+}
+
+// shellSafe matches arguments that need no quoting in a POSIX shell
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_./:=,@%+-]+$`)
+
+// reproductionCommand returns the command line that reproduces a digest:
+// the subcommand, if any, every flag set on the command line, in the
+// environment, or by an options file, and the positional arguments. Flags
+// appear in sorted order.
+func reproductionCommand(flags *flag.FlagSet, subcommand string, args []string) string {
+	parts := []string{appName}
+	if subcommand != "" {
+		parts = append(parts, subcommand)
+	}
+	flags.Visit(func(f *flag.Flag) {
+		if commandIgnoredFlags[f.Name] {
+			return
+		}
+
+		name := "--" + f.Name
+		if len(f.Name) == 1 {
+			name = "-" + f.Name
+		}
+		value := f.Value.String()
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+			if value != "true" {
+				parts = append(parts, name+"="+value)
+			} else {
+				parts = append(parts, name)
+			}
+			return
+		}
+		parts = append(parts, name, shellArg(value))
+	})
+	for _, arg := range args {
+		parts = append(parts, shellArg(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// shellArg quotes an argument for a POSIX shell unless it is safe as is
+func shellArg(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/compress"
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/embed"
+)
+
+// subcommands lists the subcommands offered by completion
+var subcommands = []string{"selftest", "stats", "check-pattern", "patch", "serve", "completion"}
+
+// completionShells lists the shells completion scripts can be generated for
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// completionValues lists the accepted values of enumerated flags
+var completionValues = map[string][]string{
+	"format":         {config.FormatText, config.FormatJSON, config.FormatMarkdown, config.FormatSQLite, config.FormatJSONL, config.FormatChunks, config.FormatMermaid, config.FormatDOT},
+	"compress":       {compress.Gzip, compress.Zstd},
+	"timestamp-from": {config.TimestampNow, config.TimestampGit},
+	"log-format":     {logFormatText, logFormatJSON},
+	"binary":         {config.BinaryPlaceholder, config.BinarySkip, config.BinaryHexdump, config.BinaryBase64},
+	"file-header":    {"default", "markdown", "plain"},
+	"tests":          {config.TestsInclude, config.TestsExclude, config.TestsOnly},
+	"migrations":     {config.MigrationsAll, config.MigrationsLatest, config.MigrationsSchema},
+	"embed":          {embed.OpenAI, embed.Ollama},
+	"pprof":          {profileCPU, profileMem, profileTrace},
+	"preset":         config.PresetNames(),
+}
+
+// completionFileFlags are flags whose value is a path
+var completionFileFlags = map[string]bool{"o": true, "f": true, "files-from": true, "template": true, "pprof-output": true, "config": true, "prompt-file": true, "pricing": true, "metrics-csv": true, "helm-render": true, "helm-values": true}
+
+// completionFlag describes a flag for completion scripts
+type completionFlag struct {
+	Name   string   // Flag name without dashes
+	Usage  string   // One-line description
+	IsBool bool     // Whether the flag takes no value
+	Values []string // Accepted values, if enumerated
+	IsFile bool     // Whether the value is a path
+}
+
+// Option returns the flag as typed on the command line
+func (f completionFlag) Option() string {
+	if len(f.Name) == 1 {
+		return "-" + f.Name
+	}
+	return "--" + f.Name
+}
+
+// runCompletion prints the completion script for the shell named in args
+// and returns the exit code
+func runCompletion(flags *flag.FlagSet, args []string) int {
+	if len(args) != 1 {
+		slog.Error(fmt.Sprintf("Usage: %s completion %s", appName, strings.Join(completionShells, "|")))
+		return exitFailure
+	}
+
+	all := completionFlags(flags)
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(all))
+	case "zsh":
+		fmt.Print(zshCompletion(all))
+	case "fish":
+		fmt.Print(fishCompletion(all))
+	case "powershell":
+		fmt.Print(powershellCompletion(all))
+	default:
+		slog.Error(fmt.Sprintf("Unknown shell '%s' (use %s)", args[0], strings.Join(completionShells, ", ")))
+		return exitFailure
+	}
+	return exitOK
+}
+
+// completionFlags returns the documented flags, skipping the long aliases
+// of short flags, sorted by name
+func completionFlags(flags *flag.FlagSet) []completionFlag {
+	var all []completionFlag
+	flags.VisitAll(func(f *flag.Flag) {
+		if strings.Contains(f.Usage, "(alias for ") {
+			return
+		}
+
+		isBool := false
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok {
+			isBool = b.IsBoolFlag()
+		}
+
+		all = append(all, completionFlag{
+			Name:   f.Name,
+			Usage:  f.Usage,
+			IsBool: isBool,
+			Values: completionValues[f.Name],
+			IsFile: completionFileFlags[f.Name],
+		})
+	})
+
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// bashCompletion generates the bash completion script
+func bashCompletion(flags []completionFlag) string {
+	var options []string
+	var cases strings.Builder
+	for _, f := range flags {
+		options = append(options, f.Option())
+		switch {
+		case f.Values != nil:
+			cases.WriteString(fmt.Sprintf("        %s)\n            COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n            return ;;\n",
+				f.Option(), strings.Join(f.Values, " ")))
+		case f.IsFile:
+			cases.WriteString(fmt.Sprintf("        %s)\n            COMPREPLY=($(compgen -f -- \"$cur\"))\n            return ;;\n", f.Option()))
+		case !f.IsBool:
+			cases.WriteString(fmt.Sprintf("        %s)\n            return ;;\n", f.Option()))
+		}
+	}
+
+	return fmt.Sprintf(`# bash completion for %[1]s
+_%[1]s() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    if [[ $COMP_CWORD -ge 2 && ${COMP_WORDS[1]} == completion ]]; then
+        COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+        return
+    fi
+
+    case "$prev" in
+%[3]s    esac
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "%[4]s" -- "$cur"))
+        return
+    fi
+
+    COMPREPLY=($(compgen -f -- "$cur"))
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY+=($(compgen -W "%[5]s" -- "$cur"))
+    fi
+}
+complete -o filenames -F _%[1]s %[1]s
+`, appName, strings.Join(completionShells, " "), cases.String(), strings.Join(options, " "), strings.Join(subcommands, " "))
+}
+
+// zshCompletion generates the zsh completion script
+func zshCompletion(flags []completionFlag) string {
+	var specs strings.Builder
+	for _, f := range flags {
+		spec := fmt.Sprintf("%s[%s]", f.Option(), zshEscape(f.Usage))
+		switch {
+		case f.Values != nil:
+			spec += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(f.Values, " "))
+		case f.IsFile:
+			spec += fmt.Sprintf(":%s:_files", f.Name)
+		case !f.IsBool:
+			spec += fmt.Sprintf(":%s: ", f.Name)
+		}
+		specs.WriteString(fmt.Sprintf("    '%s' \\\n", spec))
+	}
+
+	return fmt.Sprintf(`#compdef %[1]s
+
+_%[1]s() {
+  if (( CURRENT > 2 )) && [[ ${words[2]} == completion ]]; then
+    _values shell %[2]s
+    return
+  fi
+
+  _arguments \
+%[3]s    '1: :{_alternative "commands:subcommand:(%[4]s)" "files:source:_files"}' \
+    '*:source:_files'
+}
+
+if [[ $funcstack[1] == _%[1]s ]]; then
+  _%[1]s "$@"
+else
+  compdef _%[1]s %[1]s
+fi
+`, appName, strings.Join(completionShells, " "), specs.String(), strings.Join(subcommands, " "))
+}
+
+// zshEscape escapes a description for a zsh _arguments spec
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", `'\''`, "[", `\[`, "]", `\]`, ":", `\:`).Replace(s)
+}
+
+// fishCompletion generates the fish completion script
+func fishCompletion(flags []completionFlag) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# fish completion for %s\n", appName))
+	b.WriteString(fmt.Sprintf("complete -c %s -n __fish_use_subcommand -a '%s'\n", appName, strings.Join(subcommands, " ")))
+	b.WriteString(fmt.Sprintf("complete -c %s -n '__fish_seen_subcommand_from completion' -x -a '%s'\n", appName, strings.Join(completionShells, " ")))
+
+	for _, f := range flags {
+		opt := "-l " + f.Name
+		if len(f.Name) == 1 {
+			opt = "-s " + f.Name
+		}
+
+		line := fmt.Sprintf("complete -c %s %s -d '%s'", appName, opt, strings.ReplaceAll(f.Usage, "'", `\'`))
+		switch {
+		case f.Values != nil:
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(f.Values, " "))
+		case f.IsFile:
+			line += " -r -F"
+		case !f.IsBool:
+			line += " -x"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	return b.String()
+}
+
+// powershellCompletion generates the PowerShell completion script
+func powershellCompletion(flags []completionFlag) string {
+	var options, values strings.Builder
+	for _, f := range flags {
+		options.WriteString(fmt.Sprintf("        '%s'\n", f.Option()))
+		if f.Values != nil {
+			values.WriteString(fmt.Sprintf("        '%s' = @('%s')\n", f.Option(), strings.Join(f.Values, "', '")))
+		}
+	}
+
+	return fmt.Sprintf(`# PowerShell completion for %[1]s
+Register-ArgumentCompleter -Native -CommandName %[1]s -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    $options = @(
+%[2]s    )
+    $values = @{
+%[3]s        'completion' = @('%[4]s')
+    }
+
+    $elements = $commandAst.CommandElements | ForEach-Object { $_.ToString() }
+    $previous = if ($wordToComplete) { $elements[-2] } else { $elements[-1] }
+
+    $candidates = if ($values.ContainsKey($previous)) {
+        $values[$previous]
+    } elseif ($wordToComplete -like '-*') {
+        $options
+    } elseif ($elements.Count -le 2) {
+        @('%[5]s')
+    } else {
+        @()
+    }
+
+    $candidates | Where-Object { $_ -like "$wordToComplete*" } | ForEach-Object {
+        [System.Management.Automation.CompletionResult]::new($_, $_, 'ParameterValue', $_)
+    }
+}
+`, appName, options.String(), values.String(), strings.Join(completionShells, "', '"), strings.Join(subcommands, "', '"))
+}
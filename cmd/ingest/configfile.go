@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// configFileNames are the options files read from the current directory
+// when --config is not given
+var configFileNames = []string{".ingest.yaml", ".ingest.yml"}
+
+// configFile holds the options of an .ingest.yaml file: top-level options
+// that apply to every run, and named profiles that add to or replace them.
+// Options are keyed by their long flag names, with list values joined by
+// commas like the comma-separated flag values.
+type configFile struct {
+	path     string
+	options  map[string]string
+	profiles map[string]map[string]string
+}
+
+// loadConfigFile reads the options file at path, or the first of
+// configFileNames that exists if path is empty. It returns nil without an
+// error if no default file exists.
+func loadConfigFile(path string) (*configFile, error) {
+	candidates := []string{path}
+	if path == "" {
+		candidates = configFileNames
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if errors.Is(err, os.ErrNotExist) && path == "" {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := parseConfigFile(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", candidate, err)
+		}
+		file.path = candidate
+		return file, nil
+	}
+	return nil, nil
+}
+
+// apply sets the flags not set on the command line or in the environment,
+// first from the named profile and then from the top-level options. It
+// returns the keys that match no option, prefixed with their profile.
+func (c *configFile) apply(flags *flag.FlagSet, profile string) (unknown []string, err error) {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	layers := []map[string]string{c.options}
+	prefixes := []string{""}
+	if profile != "" {
+		options, ok := c.profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile '%s' in %s (available: %s)", profile, c.path, strings.Join(c.profileNames(), ", "))
+		}
+		layers = []map[string]string{options, c.options}
+		prefixes = []string{"profiles." + profile + ".", ""}
+	}
+
+	for i, options := range layers {
+		keys := make([]string, 0, len(options))
+		for key := range options {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name := configFlagName(key)
+			if name == "" || flags.Lookup(name) == nil {
+				unknown = append(unknown, prefixes[i]+key)
+				continue
+			}
+			if set[name] {
+				continue
+			}
+
+			if err := flags.Set(name, options[key]); err != nil {
+				return nil, fmt.Errorf("%s: %s%s: invalid value '%s': %v", c.path, prefixes[i], key, options[key], err)
+			}
+			set[name] = true
+		}
+	}
+	return unknown, nil
+}
+
+// profileNames returns the names of the profiles in sorted order
+func (c *configFile) profileNames() []string {
+	names := make([]string, 0, len(c.profiles))
+	for name := range c.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configFlagName returns the flag set by an options file key, which is a
+// long flag name such as max-depth or its environment variable form. The
+// options file and its profile cannot be chosen from it, and the deprecated
+// profile key is not read as --pprof, so it is reported rather than taken
+// for a profile name.
+func configFlagName(key string) string {
+	if key == "config" || key == "config-profile" {
+		return ""
+	}
+	name := envFlagName(strings.ToUpper(strings.ReplaceAll(key, "-", "_")))
+	if _, ok := deprecatedFlags[name]; ok {
+		return ""
+	}
+	return name
+}
+
+// configLine is a significant line of an options file
+type configLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+// parseConfigFile parses the subset of YAML used by options files: a
+// mapping of options to scalars or lists in block or flow style, and a
+// "profiles" mapping of names to such mappings
+func parseConfigFile(data string) (*configFile, error) {
+	var lines []configLine
+	for i, raw := range strings.Split(data, "\n") {
+		text := stripConfigComment(strings.TrimRight(raw, " \t\r"))
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.TrimSpace(trimmed) == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot indent YAML", i+1)
+		}
+		lines = append(lines, configLine{num: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+
+	p := &configParser{lines: lines}
+	root, err := p.mapping(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+
+	file := &configFile{options: map[string]string{}, profiles: map[string]map[string]string{}}
+	for key, value := range root {
+		if key != "profiles" {
+			if file.options[key], err = configScalar(key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		profiles, ok := value.(map[string]any)
+		if !ok {
+			return nil, errors.New("profiles must map names to options")
+		}
+		for name, options := range profiles {
+			mapping, ok := options.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("profile '%s' must map options to values", name)
+			}
+			file.profiles[name] = map[string]string{}
+			for key, value := range mapping {
+				if file.profiles[name][key], err = configScalar(key, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return file, nil
+}
+
+// configScalar returns the flag value of an option, joining lists with
+// commas
+func configScalar(key string, value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, ","), nil
+	}
+	return "", fmt.Errorf("option '%s' must be a value or a list", key)
+}
+
+// configParser is a recursive descent parser over the lines of an options
+// file
+type configParser struct {
+	lines []configLine
+	pos   int
+}
+
+// mapping parses the "key: value" lines at the given indentation. Values
+// are strings, lists of strings, or nested mappings.
+func (p *configParser) mapping(indent int) (map[string]any, error) {
+	m := map[string]any{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent >= indent {
+		line := p.lines[p.pos]
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.num)
+		}
+		key, value, ok := strings.Cut(line.text, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.HasPrefix(key, "- ") {
+			return nil, fmt.Errorf("line %d: expected 'key: value'", line.num)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key '%s'", line.num, key)
+		}
+		value = strings.TrimSpace(value)
+		p.pos++
+
+		// A list may start at the key's own indentation, as YAML allows
+		var err error
+		next := p.pos < len(p.lines)
+		switch {
+		case value != "":
+			m[key], err = configValue(value)
+		case next && p.lines[p.pos].indent >= indent && isListItem(p.lines[p.pos].text):
+			m[key], err = p.list(p.lines[p.pos].indent)
+		case next && p.lines[p.pos].indent > indent:
+			if m[key], err = p.mapping(p.lines[p.pos].indent); err != nil {
+				return nil, err
+			}
+		default:
+			m[key] = ""
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line.num, err)
+		}
+	}
+	return m, nil
+}
+
+// list parses the "- item" lines at the given indentation
+func (p *configParser) list(indent int) ([]string, error) {
+	var items []string
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isListItem(p.lines[p.pos].text) {
+		item, err := configUnquote(strings.TrimSpace(strings.TrimPrefix(p.lines[p.pos].text, "-")))
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.pos++
+	}
+	return items, nil
+}
+
+// isListItem reports whether a line is a block list item
+func isListItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// configValue parses a scalar or a flow list such as [a, "b"]
+func configValue(value string) (any, error) {
+	if !strings.HasPrefix(value, "[") {
+		return configUnquote(value)
+	}
+	if !strings.HasSuffix(value, "]") {
+		return nil, errors.New("unterminated list")
+	}
+
+	items := []string{}
+	for _, item := range strings.Split(value[1:len(value)-1], ",") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		item, err := configUnquote(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// configUnquote returns a scalar without its single or double quotes
+func configUnquote(value string) (string, error) {
+	switch {
+	case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+		return strconv.Unquote(value)
+	case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	return value, nil
+}
+
+// stripConfigComment removes a "#" comment that starts the line or follows
+// whitespace outside of quoted values
+func stripConfigComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '\\' && quote == '"' {
+				i++
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" \t[,", line[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
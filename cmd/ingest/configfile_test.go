@@ -0,0 +1,97 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+const testConfigFile = `
+format: json
+max-depth: 3
+profile: cpu
+profiles:
+  ci:
+    max-depth: 8
+    output: ci.txt
+`
+
+func TestOptionPrecedence(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		profile string
+		want    map[string]string
+	}{
+		{"defaults", nil, nil, "", map[string]string{"o": "digest.txt"}},
+		{"options file", nil, nil, "", map[string]string{"format": "json", "max-depth": "3"}},
+		{"profile over top level", nil, nil, "ci", map[string]string{"format": "json", "max-depth": "8", "o": "ci.txt"}},
+		{"environment over options file", map[string]string{"INGEST_MAX_DEPTH": "5", "INGEST_OUTPUT": "env.txt"}, nil, "ci", map[string]string{"max-depth": "5", "o": "env.txt"}},
+		{"flags over environment", map[string]string{"INGEST_MAX_DEPTH": "5"}, []string{"--max-depth", "7", "-o", "flag.txt"}, "ci", map[string]string{"format": "json", "max-depth": "7", "o": "flag.txt"}},
+		{"deprecated alias from environment", map[string]string{"INGEST_PROFILE": "mem"}, nil, "", map[string]string{"profile": "mem"}},
+	}
+
+	file, err := parseConfigFile(testConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.path = ".ingest.yaml"
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+			flags := flag.NewFlagSet("ingest", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			flags.String("o", "digest.txt", "")
+			flags.String("format", "text", "")
+			flags.Int("max-depth", 20, "")
+			flags.String("pprof", "", "")
+			flags.String("profile", "", "")
+
+			if _, err := applyEnv(flags); err != nil {
+				t.Fatal(err)
+			}
+			if err := flags.Parse(test.args); err != nil {
+				t.Fatal(err)
+			}
+			unknown, err := file.apply(flags, test.profile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The deprecated profile key is not read as --pprof
+			if want := []string{"profile"}; !reflect.DeepEqual(unknown, want) {
+				t.Errorf("unknown options = %v, want %v", unknown, want)
+			}
+
+			for name, want := range test.want {
+				if got := flags.Lookup(name).Value.String(); got != want {
+					t.Errorf("--%s = %s, want %s", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvFlagName(t *testing.T) {
+	tests := []struct {
+		suffix string
+		name   string
+	}{
+		{"MAX_DEPTH", "max-depth"},
+		{"OUTPUT", "o"},
+		{"EXCLUDE", "e"},
+		{"MAX_FILE_SIZE", "s"},
+		{"PPROF_OUTPUT", "pprof-output"},
+		{"VERSION", ""},
+		{"SIZE", ""},
+	}
+	for _, test := range tests {
+		if got := envFlagName(test.suffix); got != test.name {
+			t.Errorf("envFlagName(%s) = %q, want %q", test.suffix, got, test.name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// envPrefix prefixes environment variables that set flag values
+const envPrefix = "INGEST_"
+
+// envFlagAliases maps environment variable names to the flags they set
+// where the flag name does not follow from the variable name
+var envFlagAliases = map[string]string{
+	"OUTPUT":        "o",
+	"INCLUDE":       "i",
+	"EXCLUDE":       "e",
+	"FILES":         "f",
+	"MAX_FILE_SIZE": "s",
+}
+
+// envIgnoredFlags are flags that cannot be set from the environment
+var envIgnoredFlags = map[string]bool{
+	"v": true, "vv": true, "h": true, "version": true, "help": true,
+	"output": true, "include": true, "exclude": true, "files": true, "size": true,
+}
+
+// applyEnv sets flags from INGEST_* environment variables. It runs before
+// the command line is parsed, so explicit flags take precedence. INGEST_FOO_BAR
+// sets --foo-bar. It returns the names of variables that match no flag.
+func applyEnv(flags *flag.FlagSet) (unknown []string, err error) {
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(key, envPrefix) {
+			continue
+		}
+
+		name := envFlagName(strings.TrimPrefix(key, envPrefix))
+		if name == "" || flags.Lookup(name) == nil {
+			unknown = append(unknown, key)
+			continue
+		}
+
+		if err := flags.Set(name, value); err != nil {
+			return nil, fmt.Errorf("%s: invalid value '%s': %v", key, value, err)
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// envFlagName returns the flag set by the environment variable with the
+// given suffix, or an empty string if it cannot be set from the environment
+func envFlagName(suffix string) string {
+	if name, ok := envFlagAliases[suffix]; ok {
+		return name
+	}
+
+	name := strings.ToLower(strings.ReplaceAll(suffix, "_", "-"))
+	if envIgnoredFlags[name] {
+		return ""
+	}
+	return name
+}
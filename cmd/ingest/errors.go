@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// Exit codes are part of the CLI contract so wrapping scripts can tell
+// failures apart
+const (
+	exitOK            = 0 // Digest written
+	exitFailure       = 1 // Invalid usage or unexpected failure
+	exitNoFiles       = 2 // No files matched the patterns
+	exitSourceMissing = 3 // Source does not exist or could not be fetched
+	exitWriteFailed   = 4 // Output could not be written
+	exitPartial       = 5 // Digest written, but some paths were skipped due to errors
+)
+
+// reporter logs errors and warnings, remembers whether any path was skipped,
+// and finishes the profile before exiting. Every record carries the error
+// kind, the path it relates to, and the exit code (0 for warnings).
+type reporter struct {
+	partial bool
+	skipped map[string]int // Paths skipped because of errors, by cause
+
+	stopProfile func() error // Finishes the --pprof output, if any
+}
+
+// fail reports a fatal error and exits with the given code
+func (r *reporter) fail(code int, kind, path, format string, args ...interface{}) {
+	slog.Error(fmt.Sprintf(format, args...), "kind", kind, "path", path, "exit_code", code)
+	r.exit(code)
+}
+
+// exit finishes the profile, if one is being recorded, and exits with code
+func (r *reporter) exit(code int) {
+	if r.stopProfile != nil {
+		stop := r.stopProfile
+		r.stopProfile = nil
+		if err := stop(); err != nil {
+			r.notice("profile_failed", "", "Failed to write profile: %v", err)
+		}
+	}
+	os.Exit(code)
+}
+
+// warn reports a path that was skipped; the run continues but ends with exitPartial
+func (r *reporter) warn(kind, path, format string, args ...interface{}) {
+	r.partial = true
+	slog.Warn(fmt.Sprintf(format, args...), "kind", kind, "path", path, "exit_code", 0)
+}
+
+// skip reports a path skipped because of an error and counts it by cause
+// for the summary printed at the end of the run
+func (r *reporter) skip(kind string, pe config.PathError, format string, args ...interface{}) {
+	r.warn(kind, pe.Path, format, args...)
+	if r.skipped == nil {
+		r.skipped = map[string]int{}
+	}
+	r.skipped[skipCause(pe.Err)]++
+}
+
+// summarizeSkipped logs how many paths were skipped and why, so a long run
+// ends with the outcome rather than the last of its warnings
+func (r *reporter) summarizeSkipped() {
+	total := 0
+	causes := make([]string, 0, len(r.skipped))
+	for cause, count := range r.skipped {
+		total += count
+		causes = append(causes, fmt.Sprintf("%d %s", count, cause))
+	}
+	if total == 0 {
+		return
+	}
+	sort.Strings(causes)
+
+	noun := "paths"
+	if total == 1 {
+		noun = "path"
+	}
+	r.notice("partial", "", "Digest is partial: %d %s skipped because of errors (%s); exit code %d",
+		total, noun, strings.Join(causes, ", "), exitPartial)
+}
+
+// skipCause names the cause of a path error for the summary
+func skipCause(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		return "permission denied"
+	case errors.Is(err, fs.ErrNotExist):
+		return "removed during the run"
+	case errors.Is(err, analyzer.ErrReservedName):
+		return "reserved device name"
+	default:
+		return "unreadable"
+	}
+}
+
+// notice reports a warning that does not affect the exit code
+func (r *reporter) notice(kind, path, format string, args ...interface{}) {
+	slog.Warn(fmt.Sprintf(format, args...), "kind", kind, "path", path, "exit_code", 0)
+}
+
+// exitCode returns the exit code for a run that wrote its output
+func (r *reporter) exitCode() int {
+	if r.partial {
+		return exitPartial
+	}
+	return exitOK
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// readFileList reads file paths from a file, or from stdin if path is "-".
+// Paths are newline-delimited, or NUL-delimited if null is set, as printed
+// by find -print0 or git ls-files -z. Empty entries are skipped.
+func readFileList(path string, null bool) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	files := []string{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	if null {
+		scanner.Split(scanNUL)
+	}
+	for scanner.Scan() {
+		entry := scanner.Text()
+		if !null {
+			entry = strings.TrimSuffix(entry, "\r")
+		}
+		if entry != "" {
+			files = append(files, entry)
+		}
+	}
+	return files, scanner.Err()
+}
+
+// scanNUL is a bufio.SplitFunc splitting input at NUL bytes
+func scanNUL(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// readPatch reads a diff from a file, or from stdin if path is "-"
+func readPatch(path string) (string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	return string(data), err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+)
+
+// Log formats for --log-format
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// logLevelNames are the level names of both formats; "warning" and "error"
+// match the levels of the JSON errors printed before logging used slog
+var logLevelNames = map[slog.Level]string{
+	slog.LevelDebug: "debug",
+	slog.LevelInfo:  "info",
+	slog.LevelWarn:  "warning",
+	slog.LevelError: "error",
+}
+
+// newLogger returns a logger writing records at or above level to w. Text
+// records read like "Warning: message"; JSON records are one object per line
+// with level, message, and attribute keys.
+func newLogger(w io.Writer, format string, level slog.Level) *slog.Logger {
+	if format == logFormatJSON {
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+			Level: level,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				switch {
+				case len(groups) > 0:
+				case a.Key == slog.LevelKey:
+					a.Value = slog.StringValue(logLevelNames[a.Value.Any().(slog.Level)])
+				case a.Key == slog.MessageKey:
+					a.Key = "message"
+				case a.Key == "path" && a.Value.String() == "":
+					return slog.Attr{}
+				}
+				return a
+			},
+		}))
+	}
+	return slog.New(&textHandler{w: w, level: level, mu: &sync.Mutex{}})
+}
+
+// textHandler writes records for people: the level as a prefix, then the
+// message. Attributes are appended only to info and debug records, since
+// errors and warnings already spell out what they concern.
+type textHandler struct {
+	w     io.Writer
+	level slog.Level
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	name := logLevelNames[r.Level]
+	var line strings.Builder
+	line.WriteString(strings.ToUpper(name[:1]) + name[1:] + ": " + r.Message)
+
+	if r.Level < slog.LevelWarn {
+		write := func(a slog.Attr) bool {
+			if a.Key != "" {
+				fmt.Fprintf(&line, " %s=%v", a.Key, a.Value)
+			}
+			return true
+		}
+		for _, a := range h.attrs {
+			write(a)
+		}
+		r.Attrs(write)
+	}
+	line.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(clone.attrs[:len(clone.attrs):len(clone.attrs)], attrs...)
+	return &clone
+}
+
+// WithGroup is not needed by the CLI; grouped attributes are written flat
+func (h *textHandler) WithGroup(string) slog.Handler {
+	return h
+}
@@ -3,12 +3,33 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/anonymize"
+	"github.com/agris/ingest-clone/pkg/codeowners"
+	"github.com/agris/ingest-clone/pkg/compress"
 	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/dockersource"
+	"github.com/agris/ingest-clone/pkg/embed"
 	"github.com/agris/ingest-clone/pkg/formatter"
+	"github.com/agris/ingest-clone/pkg/gitsource"
+	"github.com/agris/ingest-clone/pkg/golist"
+	"github.com/agris/ingest-clone/pkg/gosymbol"
+	"github.com/agris/ingest-clone/pkg/jsimports"
+	"github.com/agris/ingest-clone/pkg/objectstore"
+	"github.com/agris/ingest-clone/pkg/patch"
+	"github.com/agris/ingest-clone/pkg/sshsource"
+	"github.com/agris/ingest-clone/pkg/summarize"
 )
 
 const (
@@ -17,13 +38,128 @@ const (
 )
 
 func main() {
+	// Log warnings and errors as text until the flags choose otherwise
+	slog.SetDefault(newLogger(os.Stderr, logFormatText, slog.LevelWarn))
+
+	// Run subcommands
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		os.Exit(runSelftest())
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+
 	// Parse command line flags
 	outputFile := flag.String("o", config.DefaultOutputFile, "Output file")
 	includePatterns := flag.String("i", "", "Patterns to include (comma-separated)")
 	excludePatterns := flag.String("e", "", "Patterns to exclude (comma-separated)")
-	filesList := flag.String("f", "", "Specific files to analyze (comma-separated)")
+	order := flag.String("order", "", "Ordering rules placing matching files first (comma-separated)")
+	preset := flag.String("preset", "", "Apply the curated excludes and ordering of an ecosystem (go, node, python, rust; comma-separated)")
+	noReadmeFirst := flag.Bool("no-readme-first", false, "Keep README, ARCHITECTURE, and CONTRIBUTING docs in tree order")
+	gitHosts := flag.String("git-host", "", "Self-hosted git hosts as host=kind, kind github, gitlab, or bitbucket (comma-separated)")
+	subpath := flag.String("subpath", "", "Directory of a git URL source to fetch and analyze (sparse clone), or of a docker:// image")
+	goPackage := flag.String("go-package", "", "Analyze only the sources of a Go package of the source module, such as ./cmd/server")
+	withDeps := flag.Bool("with-deps", false, "Add the in-module packages imported by --go-package")
+	symbol := flag.String("symbol", "", "Include only the named Go declarations, such as analyzer.ProcessPath,Config.ShouldInclude")
+	owner := flag.String("owner", "", "Analyze only the files CODEOWNERS assigns to the given users or teams, such as @acme/backend (comma-separated)")
+	jsEntry := flag.String("js-entry", "", "Analyze only JS/TS entrypoints and the files they reach through relative imports (comma-separated)")
+	filesList := flag.String("f", "", "Specific files to analyze (comma-separated, - to read from stdin)")
+	filesFrom := flag.String("files-from", "", "Read file paths to analyze from a file, one per line (- for stdin)")
+	var nullSep bool
+	flag.BoolVar(&nullSep, "null", false, "File lists read with -f - or --files-from are NUL-delimited")
+	flag.BoolVar(&nullSep, "0", false, "File lists are NUL-delimited (alias for --null)")
+	format := flag.String("format", config.FormatText, "Output format (text, json, markdown, sqlite, jsonl, chunks, mermaid, dot; comma-separated for several)")
+	chunkTokens := flag.Int("chunk-tokens", config.DefaultChunkTokens, "Maximum estimated tokens of each chunk (chunks format)")
+	chunkOverlap := flag.Int("chunk-overlap", config.DefaultChunkOverlap, "Estimated tokens shared by consecutive chunks of a file (chunks format)")
+	embedProvider := flag.String("embed", "", "Compute chunk embeddings with a provider (openai, ollama; chunks and sqlite formats)")
+	embedModel := flag.String("embed-model", "", "Embedding model (default: the provider's default)")
+	summarizeWith := flag.String("summarize-with", "", "Replace file contents with summaries by an Ollama model URL, such as http://localhost:11434/llama3.2")
+	summarizePrompt := flag.String("summarize-prompt", "", "Instructions given to the model before each file (--summarize-with)")
+	full := flag.String("full", "", "Patterns of files whose full content is kept with --summary-rest or --summarize-with (comma-separated)")
+	summaryRest := flag.Bool("summary-rest", false, "Reduce files not matching --full to tree and statistics entries (or summaries with --summarize-with)")
+	backup := flag.Bool("backup", false, "Keep the previous output file as <output>.bak")
+	compressMethod := flag.String("compress", "", "Compress the output (gzip, zstd)")
+	templateFile := flag.String("template", "", "Go text/template file used to render the output")
+	prompt := flag.String("prompt", "", "Instructions placed after the digest, making the output a ready-to-send prompt")
+	promptFile := flag.String("prompt-file", "", "File whose contents are placed before the digest")
+	cost := flag.Bool("cost", false, "Add the estimated input cost for common models to the summary")
+	pricing := flag.String("pricing", "", "Pricing table with one 'MODEL USD-PER-MILLION-TOKENS' line per model (implies --cost)")
+	metricsCSV := flag.String("metrics-csv", "", "Also write per-file metrics (path, bytes, lines, tokens, language, modified) to a CSV file")
 	maxFileSize := flag.Int64("s", config.DefaultMaxFileSize, "Maximum file size to process in bytes")
-	showVersion := flag.Bool("v", false, "Show version information")
+	includeGenerated := flag.Bool("include-generated", false, "Include full contents of generated and minified files")
+	noDedupe := flag.Bool("no-dedupe", false, "Include every copy of duplicate files")
+	keepEmbedded := flag.Bool("keep-embedded", false, "Keep embedded base64 blobs in file contents")
+	outline := flag.Bool("outline", false, "Reduce Go, Python, Java, and JS/TS sources to declarations and doc comments")
+	docsOnly := flag.Bool("docs-only", false, "Reduce sources to their leading comment, doc comments, and docstrings")
+	stripComments := flag.Bool("strip-comments", false, "Remove comments from Go, JS/TS, Python, C-family, and shell sources")
+	keepDocComments := flag.Bool("keep-doc-comments", false, "Keep doc comments and docstrings with --strip-comments")
+	stripLicenseHeaders := flag.Bool("strip-license-headers", false, "Remove copyright and license header comments from source files")
+	normalizeEOL := flag.Bool("normalize-eol", false, "Convert CRLF and CR line endings to LF")
+	stripTrailing := flag.Bool("strip-trailing-whitespace", false, "Remove trailing spaces and tabs from every line")
+	collapseBlank := flag.Int("collapse-blank-lines", 0, "Maximum number of consecutive blank lines to keep (0 keeps all)")
+	hidden := flag.Bool("hidden", false, "Traverse dotfiles and dot-directories (default)")
+	noHidden := flag.Bool("no-hidden", false, "Skip all dotfiles and dot-directories")
+	ignoreCase := flag.Bool("ignore-case", false, "Match patterns regardless of case (default)")
+	caseSensitive := flag.Bool("case-sensitive", false, "Match patterns case-sensitively, except the default excludes")
+	treeStats := flag.Bool("tree-stats", false, "Annotate directories in the tree with file counts, sizes, and tokens")
+	treeMeta := flag.Bool("tree-metadata", false, "Show permissions, modification times, and symlink targets in the tree")
+	treeDepth := flag.Int("tree-depth", 0, "Maximum depth of the rendered tree (0 for unlimited)")
+	contentDepth := flag.Int("content-depth", 0, "Maximum depth of files whose contents are included (0 for unlimited)")
+	maxDepth := flag.Int("max-depth", config.DefaultDirDepth, "Maximum directory depth to traverse")
+	maxFiles := flag.Int("max-files", config.DefaultMaxFiles, "Maximum number of files to process")
+	maxTotalSize := flag.Int64("max-total-size", config.DefaultMaxTotalSize, "Maximum total size of processed files in bytes")
+	maxTokens := flag.Int("max-tokens", 0, "Maximum estimated tokens of the included file contents (0 for unlimited)")
+	noGit := flag.Bool("no-git", false, "Disable all git probing")
+	noGitIgnore := flag.Bool("no-gitignore", false, "Do not apply .gitignore, .git/info/exclude, or core.excludesFile rules")
+	oneFileSystem := flag.Bool("one-file-system", false, "Do not descend into directories on other file systems, such as mount points")
+	noTimestamp := flag.Bool("no-timestamp", false, "Omit the generation timestamp from the header")
+	timestampFrom := flag.String("timestamp-from", config.TimestampNow, "Source of the header timestamp (now, git)")
+	noDeps := flag.Bool("no-deps", false, "Omit the dependency summary section")
+	noTerraform := flag.Bool("no-terraform", false, "Omit the Terraform resources and modules section")
+	binaryMode := flag.String("binary", config.BinaryPlaceholder, "Binary file handling (placeholder, skip, hexdump, base64)")
+	imageMeta := flag.Bool("image-metadata", false, "Describe images by format, dimensions, and size")
+	maxBinarySize := flag.Int64("max-binary-size", config.DefaultMaxBinarySize, "Largest binary file embedded with --binary base64, in bytes")
+	lfs := flag.Bool("lfs", false, "Fetch Git LFS objects with git-lfs instead of noting their pointers")
+	tests := flag.String("tests", config.TestsInclude, "Test file handling (include, exclude, only)")
+	migrationsMode := flag.String("migrations", config.MigrationsAll, "Migration directories (all, latest, schema)")
+	latestMigrations := flag.Int("latest-migrations", config.DefaultLatestMigrations, "Number of migration versions kept by --migrations latest")
+	helmRender := flag.String("helm-render", "", "Replace the templates of these Helm chart directories with the manifests helm template renders (comma-separated, relative to the source)")
+	helmValues := flag.String("helm-values", "", "Values files used by --helm-render, relative to each chart unless absolute (comma-separated)")
+	sampleRows := flag.Int("sample-rows", 0, "Keep only the header and the first N rows of CSV and TSV files")
+	skeletonSize := flag.Int64("skeleton-size", config.DefaultSkeletonSize, "Replace JSON and YAML files larger than this with their skeleton, in bytes (0 to disable)")
+	logTail := flag.Int("log-tail", 0, "Include *.log files, keeping only their last N lines")
+	excludeLockfiles := flag.Bool("exclude-lockfiles", false, "Replace lockfile contents with placeholders")
+	blame := flag.Bool("blame", false, "Prefix every content line with the commit and author that last changed it, as git blame does")
+	authors := flag.Bool("authors", false, "Annotate each file with its CODEOWNERS owners and main git blame authors")
+	churn := flag.Bool("churn", false, "Add a section ranking the files changed by the most commits (requires git)")
+	churnSince := flag.String("churn-since", config.DefaultChurnSince, "Start of the history counted by --churn, such as '1 year ago' or 2025-01-01")
+	hotspots := flag.Bool("hotspots", false, "Add a section ranking the files with the highest cyclomatic complexity")
+	architecture := flag.Bool("architecture", false, "Add a section mapping the top-level directories and Go package imports")
+	todos := flag.Bool("todos", false, "Add a section listing TODO/FIXME/HACK/XXX comments")
+	goSymbols := flag.Bool("go-symbols", false, "Append an index of exported Go symbols with their defining and referencing files")
+	withIssues := flag.String("with-issues", "", "Append the threads of these GitHub or GitLab issues of the repository, such as 42,57 (comma-separated)")
+	strict := flag.Bool("strict", false, "Fail the run if any path could not be processed")
+	anonymizeFlag := flag.Bool("anonymize", false, "Replace the repository name, user names, internal hosts, and email addresses with placeholders")
+	toc := flag.Bool("toc", false, "Emit a table of contents with file offsets")
+	separator := flag.String("separator", config.DefaultSeparator, "Line drawn around file headers and between sources (empty for none)")
+	fileHeader := flag.String("file-header", "default", "File header style (default, markdown, plain) or template with {path} and {separator}")
+	watchMode := flag.Bool("watch", false, "Write the digest again whenever a file of the source directory changes, until interrupted")
+	pushURL := flag.String("push-url", "", "POST every digest written by --watch to this webhook URL")
+	var logFormat string
+	flag.StringVar(&logFormat, "log-format", logFormatText, "Format of log records on stderr (text, json)")
+	flag.StringVar(&logFormat, "error-format", logFormatText, "Format of log records on stderr (alias for --log-format)")
+	var verbose bool
+	flag.BoolVar(&verbose, "v", false, "Log progress on stderr")
+	flag.BoolVar(&verbose, "verbose", false, "Log progress on stderr (alias for -v)")
+	debug := flag.Bool("vv", false, "Log progress and every decision on stderr")
+	pprofKind := flag.String("pprof", "", "Record a profile of the run (cpu, mem, trace)")
+	pprofOutput := flag.String("pprof-output", "", "Profile file (default: cpu.pprof, mem.pprof, or trace.out)")
+	flag.StringVar(pprofKind, "profile", "", "Record a profile of the run (alias for --pprof, deprecated)")
+	flag.StringVar(pprofOutput, "profile-output", "", "Profile file (alias for --pprof-output, deprecated)")
+	configPath := flag.String("config", "", "Options file (default: .ingest.yaml in the current directory)")
+	configProfile := flag.String("config-profile", "", "Apply the named profile of the options file")
+	var showVersion bool
+	flag.BoolVar(&showVersion, "version", false, "Show version information")
 	showHelp := flag.Bool("h", false, "Show help")
 
 	// Create aliases for flags
@@ -32,13 +168,82 @@ func main() {
 	flag.String("exclude", "", "Patterns to exclude (alias for -e)")
 	flag.String("files", "", "Specific files to analyze (comma-separated) (alias for -f)")
 	flag.Int64("size", config.DefaultMaxFileSize, "Maximum file size to process in bytes (alias for -s)")
-	flag.Bool("version", false, "Show version information (alias for -v)")
 	flag.Bool("help", false, "Show help (alias for -h)")
 
-	flag.Parse()
+	// Completion scripts are generated from the flags defined above
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		os.Exit(runCompletion(flag.CommandLine, os.Args[2:]))
+	}
+
+	// Environment variables set flag values that explicit flags override
+	unknownEnv, envErr := applyEnv(flag.CommandLine)
+
+	// Report bad flags with the usage exit code rather than the flag
+	// package's default of 2, which means "no files matched"
+	// The stats, check-pattern, and patch subcommands take the same options
+	// as a digest run
+	cliArgs := os.Args[1:]
+	statsOnly := len(cliArgs) > 0 && cliArgs[0] == "stats"
+	checkOnly := len(cliArgs) > 0 && cliArgs[0] == "check-pattern"
+	patchOnly := len(cliArgs) > 0 && cliArgs[0] == "patch"
+	subcommand := ""
+	if statsOnly || checkOnly || patchOnly {
+		subcommand = cliArgs[0]
+		cliArgs = cliArgs[1:]
+	}
+
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(cliArgs); err != nil {
+		os.Exit(exitFailure)
+	}
+
+	// The options file sets what neither the flags nor the environment set,
+	// with the selected profile taking precedence over its top level
+	configFile, configErr := loadConfigFile(*configPath)
+	var unknownConfig []string
+	if configErr == nil && configFile != nil {
+		unknownConfig, configErr = configFile.apply(flag.CommandLine, *configProfile)
+	}
+
+	report := &reporter{}
+	if logFormat != logFormatText && logFormat != logFormatJSON {
+		report.fail(exitFailure, "usage", "", "Unknown log format '%s'", logFormat)
+	}
+	level := slog.LevelWarn
+	switch {
+	case *debug:
+		level = slog.LevelDebug
+	case verbose:
+		level = slog.LevelInfo
+	}
+	slog.SetDefault(newLogger(os.Stderr, logFormat, level))
+
+	if envErr != nil {
+		report.fail(exitFailure, "usage", "", "%v", envErr)
+	}
+	for _, key := range unknownEnv {
+		report.notice("unknown_env", "", "Ignoring %s, which matches no option", key)
+	}
+	if configErr != nil {
+		report.fail(exitFailure, "usage", *configPath, "Invalid options file: %v", configErr)
+	}
+	if configFile == nil && *configProfile != "" {
+		report.fail(exitFailure, "usage", "", "--config-profile requires an options file (.ingest.yaml or --config)")
+	}
+	for _, key := range unknownConfig {
+		report.notice("unknown_option", configFile.path, "Ignoring %s in %s, which matches no option", key, configFile.path)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if name, ok := deprecatedFlags[f.Name]; ok {
+			report.notice("deprecated_flag", "", "--%s is deprecated; use --%s (--config-profile selects options file profiles)", f.Name, name)
+		}
+	})
+	if configFile != nil {
+		slog.Info("Read options file", "path", configFile.path, "profile", *configProfile)
+	}
 
 	// Show version if requested
-	if *showVersion {
+	if showVersion {
 		fmt.Printf("%s version %s\n", appName, appVersion)
 		return
 	}
@@ -49,86 +254,634 @@ func main() {
 		return
 	}
 
+	// Profile the rest of the run, finishing the file on every exit path
+	if *pprofKind != "" {
+		stop, err := startProfile(*pprofKind, *pprofOutput)
+		if err != nil {
+			report.fail(exitFailure, "usage", "", "Invalid --pprof: %v", err)
+		}
+		report.stopProfile = stop
+	} else if *pprofOutput != "" {
+		report.fail(exitFailure, "usage", "", "--pprof-output requires --pprof")
+	}
+
 	// Create configuration
 	cfg := config.NewConfig()
 	cfg.MaxFileSize = *maxFileSize
+	cfg.MaxDirDepth = *maxDepth
+	cfg.MaxFiles = *maxFiles
+	cfg.MaxTotalSize = *maxTotalSize
+	cfg.MaxTokens = *maxTokens
 	cfg.OutputFile = *outputFile
+	cfg.Formats = config.ParsePatterns(*format)
+	if len(cfg.Formats) > 0 {
+		cfg.Format = cfg.Formats[0]
+	}
+	cfg.ChunkTokens = *chunkTokens
+	cfg.ChunkOverlap = *chunkOverlap
+	cfg.Embed = *embedProvider
+	cfg.EmbedModel = *embedModel
+	cfg.SummarizeWith = *summarizeWith
+	cfg.SummarizePrompt = *summarizePrompt
+	cfg.FullPatterns = config.ParsePatterns(*full)
+	cfg.SummaryRest = *summaryRest
+	cfg.Template = *templateFile
+	cfg.Compress = *compressMethod
+	cfg.Backup = *backup
+	cfg.IncludeGenerated = *includeGenerated
+	cfg.NoDedupe = *noDedupe
+	cfg.KeepEmbedded = *keepEmbedded
+	cfg.Outline = *outline
+	cfg.DocsOnly = *docsOnly
+	cfg.StripComments = *stripComments
+	cfg.KeepDocComments = *keepDocComments
+	cfg.StripLicenseHeaders = *stripLicenseHeaders
+	cfg.NormalizeEOL = *normalizeEOL
+	cfg.StripTrailingWhitespace = *stripTrailing
+	cfg.CollapseBlankLines = *collapseBlank
+	cfg.Hidden = !*noHidden
+	if *ignoreCase || *caseSensitive {
+		cfg.IgnoreCase = *ignoreCase
+	}
+	cfg.TreeDepth = *treeDepth
+	cfg.ContentDepth = *contentDepth
+	cfg.TreeMetadata = *treeMeta
+	cfg.TreeStats = *treeStats
+	cfg.NoGit = *noGit
+	cfg.NoGitIgnore = *noGitIgnore
+	cfg.OneFileSystem = *oneFileSystem
+	cfg.TimestampFrom = *timestampFrom
+	if *noTimestamp {
+		cfg.TimestampFrom = config.TimestampNone
+	}
+	cfg.NoDeps = *noDeps
+	cfg.NoTerraform = *noTerraform
+	cfg.BinaryMode = *binaryMode
+	cfg.MaxBinarySize = *maxBinarySize
+	cfg.ImageMetadata = *imageMeta
+	cfg.LFS = *lfs
+	cfg.ExcludeLockfiles = *excludeLockfiles
+	cfg.SampleRows = *sampleRows
+	cfg.SkeletonSize = *skeletonSize
+	cfg.LogTail = *logTail
+	cfg.Tests = *tests
+	cfg.Migrations = *migrationsMode
+	cfg.LatestMigrations = *latestMigrations
+	cfg.HelmCharts = config.ParsePatterns(*helmRender)
+	cfg.HelmValues = config.ParsePatterns(*helmValues)
+	cfg.Todos = *todos
+	cfg.Architecture = *architecture
+	cfg.Hotspots = *hotspots
+	cfg.Churn = *churn
+	cfg.Authors = *authors
+	cfg.Blame = *blame
+	cfg.ChurnSince = *churnSince
+	cfg.MetricsCSV = *metricsCSV
+	cfg.GoSymbols = *goSymbols
+	cfg.Strict = *strict
+	cfg.Anonymize = *anonymizeFlag
+	cfg.TOC = *toc
+	cfg.Separator = *separator
+
+	if len(cfg.Formats) == 0 {
+		report.fail(exitFailure, "usage", "", "--format requires a format")
+	}
+	for i, format := range cfg.Formats {
+		if !config.ValidFormat(format) {
+			report.fail(exitFailure, "usage", "", "Unknown output format '%s'", format)
+		}
+		if slices.Contains(cfg.Formats[:i], format) {
+			report.fail(exitFailure, "usage", "", "Output format '%s' is given twice", format)
+		}
+	}
+	if cfg.ChunkTokens < 1 || cfg.ChunkOverlap < 0 || cfg.ChunkOverlap >= cfg.ChunkTokens {
+		report.fail(exitFailure, "usage", "", "--chunk-tokens must be positive and larger than --chunk-overlap")
+	}
+	if cfg.Embed != "" && !embed.Valid(cfg.Embed) {
+		report.fail(exitFailure, "usage", "", "Unknown embedding provider '%s'", cfg.Embed)
+	}
+	if cfg.Embed != "" && !cfg.HasFormat(config.FormatChunks) && !cfg.HasFormat(config.FormatSQLite) {
+		report.fail(exitFailure, "usage", "", "--embed can only be used with the chunks and sqlite formats")
+	}
+	if cfg.EmbedModel != "" && cfg.Embed == "" {
+		report.fail(exitFailure, "usage", "", "--embed-model requires --embed")
+	}
+	var summarizer *summarize.Client
+	if cfg.SummarizeWith != "" {
+		var err error
+		if summarizer, err = summarize.New(cfg.SummarizeWith, cfg.SummarizePrompt); err != nil {
+			report.fail(exitFailure, "usage", "", "Invalid --summarize-with: %v", err)
+		}
+	} else if cfg.SummarizePrompt != "" {
+		report.fail(exitFailure, "usage", "", "--summarize-prompt requires --summarize-with")
+	}
+	if cfg.SummaryRest && len(cfg.FullPatterns) == 0 {
+		report.fail(exitFailure, "usage", "", "--summary-rest requires --full")
+	}
+	if len(cfg.FullPatterns) > 0 && !cfg.SummaryRest && cfg.SummarizeWith == "" {
+		report.fail(exitFailure, "usage", "", "--full requires --summary-rest or --summarize-with")
+	}
+
+	if cfg.TimestampFrom != config.TimestampNow && cfg.TimestampFrom != config.TimestampGit && cfg.TimestampFrom != config.TimestampNone {
+		report.fail(exitFailure, "usage", "", "Unknown timestamp source '%s'", cfg.TimestampFrom)
+	}
+
+	if !config.ValidBinaryMode(cfg.BinaryMode) {
+		report.fail(exitFailure, "usage", "", "Unknown binary mode '%s'", cfg.BinaryMode)
+	}
+	if !config.ValidTestsMode(cfg.Tests) {
+		report.fail(exitFailure, "usage", "", "Unknown tests mode '%s'", cfg.Tests)
+	}
+	if !config.ValidMigrationsMode(cfg.Migrations) {
+		report.fail(exitFailure, "usage", "", "Unknown migrations mode '%s'", cfg.Migrations)
+	}
+	if cfg.MaxTokens < 0 {
+		report.fail(exitFailure, "usage", "", "--max-tokens must not be negative")
+	}
+	if cfg.ContentDepth < 0 {
+		report.fail(exitFailure, "usage", "", "--content-depth must not be negative")
+	}
+	if cfg.LogTail < 0 {
+		report.fail(exitFailure, "usage", "", "--log-tail must not be negative")
+	}
+	if cfg.SampleRows < 0 {
+		report.fail(exitFailure, "usage", "", "--sample-rows must not be negative")
+	}
+	if cfg.LatestMigrations < 1 {
+		report.fail(exitFailure, "usage", "", "--latest-migrations must be at least 1")
+	}
+	if len(cfg.HelmValues) > 0 && len(cfg.HelmCharts) == 0 {
+		report.fail(exitFailure, "usage", "", "--helm-values requires --helm-render")
+	}
+	if len(cfg.HelmCharts) > 0 {
+		if _, err := exec.LookPath("helm"); err != nil {
+			report.fail(exitFailure, "usage", "", "--helm-render requires the helm command-line tool")
+		}
+		for _, chart := range cfg.HelmCharts {
+			if !filepath.IsLocal(filepath.FromSlash(chart)) {
+				report.fail(exitFailure, "usage", "", "Helm chart '%s' must be a directory relative to the source", chart)
+			}
+		}
+	}
+
+	if *keepDocComments && !*stripComments {
+		report.fail(exitFailure, "usage", "", "--keep-doc-comments requires --strip-comments")
+	}
+	if *docsOnly && (*outline || *stripComments) {
+		report.fail(exitFailure, "usage", "", "--docs-only cannot be combined with --outline or --strip-comments")
+	}
+	if *hidden && *noHidden {
+		report.fail(exitFailure, "usage", "", "--hidden and --no-hidden cannot be combined")
+	}
+	if *ignoreCase && *caseSensitive {
+		report.fail(exitFailure, "usage", "", "--ignore-case and --case-sensitive cannot be combined")
+	}
+
+	if header, err := config.ParseFileHeader(*fileHeader); err != nil {
+		report.fail(exitFailure, "usage", "", "Invalid --file-header: %v", err)
+	} else {
+		cfg.FileHeader = header
+	}
+
+	if cfg.Template != "" && !cfg.HasFormat(config.FormatText) {
+		report.fail(exitFailure, "usage", "", "--template can only be used with the text format")
+	}
+	if (*prompt != "" || *promptFile != "") && !cfg.HasFormat(config.FormatText) {
+		report.fail(exitFailure, "usage", "", "--prompt and --prompt-file can only be used with the text format")
+	}
+	cfg.Prompt = *prompt
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			report.fail(exitFailure, "prompt_file", *promptFile, "Failed to read prompt file: %v", err)
+		}
+		cfg.PromptPreamble = string(data)
+	}
+
+	// A pricing table replaces the default prices
+	if *cost {
+		cfg.Pricing = config.DefaultPricing
+	}
+	if *pricing != "" {
+		data, err := os.ReadFile(*pricing)
+		if err != nil {
+			report.fail(exitFailure, "pricing_file", *pricing, "Failed to read pricing table: %v", err)
+		}
+		if cfg.Pricing, err = config.ParsePricing(string(data)); err != nil {
+			report.fail(exitFailure, "usage", "", "Invalid pricing table %s: %v", *pricing, err)
+		}
+	}
+
+	if cfg.Blame && cfg.Anonymize {
+		report.fail(exitFailure, "usage", "", "--blame cannot be combined with --anonymize, since author names in the content cannot be anonymized")
+	}
+
+	if cfg.Compress != "" && !compress.Valid(cfg.Compress) {
+		report.fail(exitFailure, "usage", "", "Unknown compression '%s'", cfg.Compress)
+	}
+	if cfg.Compress != "" && cfg.HasFormat(config.FormatSQLite) {
+		report.fail(exitFailure, "usage", "", "--compress cannot be used with the sqlite format")
+	}
+
+	// Use the format's default extension unless an output file was given;
+	// several formats are written next to it with their own extensions
+	for _, format := range cfg.Formats {
+		path := cfg.OutputFile
+		if path == config.DefaultOutputFile || len(cfg.Formats) > 1 {
+			path = config.OutputFileFor(path, format)
+		}
+		if cfg.Compress != "" {
+			path = compress.OutputPath(path, cfg.Compress)
+		}
+		cfg.OutputFiles = append(cfg.OutputFiles, path)
+	}
+	cfg.OutputFile = cfg.OutputFiles[0]
 
 	// Parse include/exclude patterns
 	if *includePatterns != "" {
 		cfg.IncludePatterns = config.ParsePatterns(*includePatterns)
 	}
 
+	// Log files are excluded by default unless they are tailed
+	if cfg.LogTail > 0 {
+		cfg.ExcludePatterns = slices.DeleteFunc(cfg.ExcludePatterns, func(pattern string) bool { return pattern == "*.log" })
+	}
+
+	// Presets come before the user's patterns, which can override them
+	if err := cfg.ApplyPresets(config.ParsePatterns(*preset)); err != nil {
+		report.fail(exitFailure, "usage", "", "Invalid --preset: %v", err)
+	}
+
 	if *excludePatterns != "" {
 		cfg.ExcludePatterns = append(cfg.ExcludePatterns, config.ParsePatterns(*excludePatterns)...)
 	}
 
+	if *order != "" {
+		cfg.OrderPatterns = config.ParsePatterns(*order)
+	}
+	cfg.NoReadmeFirst = *noReadmeFirst
+
 	// Get source directory/file from args or use current directory as default
 	args := flag.Args()
+	var checkPath string
+	if checkOnly {
+		if len(args) == 0 {
+			report.fail(exitFailure, "usage", "", "check-pattern requires a path")
+		}
+		checkPath, args = args[0], args[1:]
+	}
+	var patchPath string
+	if patchOnly {
+		if len(args) == 0 {
+			report.fail(exitFailure, "usage", "", "patch requires a diff file, or - to read it from stdin")
+		}
+		patchPath, args = args[0], args[1:]
+	}
 	if len(args) > 0 {
 		cfg.Source = args[0]
 	}
 
+	hosts, err := gitsource.ParseHosts(config.ParsePatterns(*gitHosts))
+	if err != nil {
+		report.fail(exitFailure, "usage", "", "Invalid --git-host: %v", err)
+	}
+	cfg.GitHosts = hosts
+	cfg.Subpath = *subpath
+	if cfg.Subpath != "" && !gitsource.IsURL(cfg.Source, cfg.GitHosts) && !dockersource.IsURL(cfg.Source) {
+		report.fail(exitFailure, "usage", "", "--subpath requires a git URL or docker:// source")
+	}
+
+	// The charts of fetched sources are only checked when rendered
+	if len(cfg.HelmCharts) > 0 && remoteFetcher(cfg.Source, cfg) == nil && config.DirExists(cfg.Source) {
+		for _, chart := range cfg.HelmCharts {
+			if !config.FileExists(filepath.Join(cfg.Source, filepath.FromSlash(chart), "Chart.yaml")) {
+				report.fail(exitFailure, "usage", "", "'%s' is not a Helm chart directory of the source (no Chart.yaml)", chart)
+			}
+		}
+	}
+
+	if *pushURL != "" {
+		if !*watchMode {
+			report.fail(exitFailure, "usage", "", "--push-url requires --watch")
+		}
+		if u, err := url.Parse(*pushURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			report.fail(exitFailure, "usage", "", "Invalid --push-url '%s': expected an http or https URL", *pushURL)
+		}
+	}
+	if *watchMode && (subcommand != "" || *filesList != "" || *filesFrom != "" || remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source)) {
+		report.fail(exitFailure, "usage", "", "--watch requires a local directory as the source, and cannot be combined with a subcommand, -f, or --files-from")
+	}
+
+	// Review a pull request: its description and comments, its diff, and the
+	// head content of the files it changes
+	if gitsource.IsPullRequest(cfg.Source, cfg.GitHosts) {
+		if cfg.Subpath != "" {
+			report.fail(exitFailure, "usage", "", "--subpath cannot be combined with a pull request source")
+		}
+		if cfg.Anonymize {
+			report.fail(exitFailure, "usage", "", "--anonymize cannot be combined with a pull request source")
+		}
+		slog.Info("Fetching pull request", "source", cfg.Source)
+		pr, err := gitsource.PullRequest(cfg.Source, cfg.GitHosts)
+		if err != nil {
+			report.fail(exitSourceMissing, "fetch_failed", cfg.Source, "Failed to fetch pull request '%s': %v", cfg.Source, err)
+		}
+		cfg.PullRequest = pr
+		cfg.Patch = pr.Diff
+	}
+
+	// Append the issue threads discussing the requirements of the code
+	if *withIssues != "" {
+		if cfg.Anonymize {
+			report.fail(exitFailure, "usage", "", "--with-issues cannot be combined with --anonymize")
+		}
+		var numbers []int
+		for _, value := range config.ParsePatterns(*withIssues) {
+			number, err := strconv.Atoi(strings.TrimPrefix(value, "#"))
+			if err != nil || number <= 0 {
+				report.fail(exitFailure, "usage", "", "Invalid issue number '%s' in --with-issues", value)
+			}
+			numbers = append(numbers, number)
+		}
+
+		// Local sources find their repository through the origin remote
+		remote := cfg.Source
+		if !gitsource.IsURL(remote, cfg.GitHosts) {
+			if remoteFetcher(remote, cfg) != nil {
+				report.fail(exitFailure, "usage", "", "--with-issues requires a git URL or a local git work tree as the source")
+			}
+			_, origin, err := cfg.Git().Origin()
+			if err != nil || origin == "" {
+				report.fail(exitFailure, "usage", "", "--with-issues requires a git URL or a local git work tree with an origin remote as the source")
+			}
+			remote = origin
+		}
+		slog.Info("Fetching issues", "remote", remote, "issues", len(numbers))
+		found, err := gitsource.Issues(remote, cfg.GitHosts, numbers)
+		if err != nil {
+			report.fail(exitFailure, "issues_failed", *withIssues, "Failed to fetch issues: %v", err)
+		}
+		cfg.Issues = found
+	}
+
+	// Collect specific files from -f or --files-from
+	var files []string
+	if *filesList != "" && *filesFrom != "" {
+		report.fail(exitFailure, "usage", "", "-f and --files-from cannot be combined")
+	}
+	if nullSep && *filesList != "-" && *filesFrom == "" {
+		report.fail(exitFailure, "usage", "", "--null requires -f - or --files-from")
+	}
+	switch {
+	case *filesList == "-":
+		*filesFrom = "-"
+		fallthrough
+	case *filesFrom != "":
+		var err error
+		files, err = readFileList(*filesFrom, nullSep)
+		if err != nil {
+			report.fail(exitFailure, "file_list", *filesFrom, "Failed to read file list: %v", err)
+		}
+		if len(files) == 0 {
+			report.fail(exitNoFiles, "no_files", *filesFrom, "The file list is empty")
+		}
+	case *filesList != "":
+		files = config.ParsePatterns(*filesList)
+	}
+
+	// Entries such as main.go:100-250 keep only those lines of the file
+	if files != nil {
+		files, cfg.LineRanges, err = config.ParseFileRanges(files)
+		if err != nil {
+			report.fail(exitFailure, "usage", "", "Invalid file list: %v", err)
+		}
+	}
+
+	// Restrict traversal to the sources of a Go package or the files
+	// reachable from JS/TS entrypoints
+	if *withDeps && *goPackage == "" {
+		report.fail(exitFailure, "usage", "", "--with-deps requires --go-package")
+	}
+	if (*goPackage != "" || *jsEntry != "") && (files != nil || remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source)) {
+		report.fail(exitFailure, "usage", "", "--go-package and --js-entry require a local directory as the source")
+	}
+	var selected []string
+	if *goPackage != "" {
+		sources, err := golist.Files(cfg.Source, *goPackage, *withDeps)
+		if err != nil {
+			report.fail(exitFailure, "go_package", *goPackage, "Failed to resolve Go package '%s': %v", *goPackage, err)
+		}
+		selected = append(selected, sources...)
+	}
+	if *jsEntry != "" {
+		sources, err := jsimports.Files(cfg.Source, config.ParsePatterns(*jsEntry))
+		if err != nil {
+			report.fail(exitFailure, "js_entry", *jsEntry, "Failed to follow imports: %v", err)
+		}
+		selected = append(selected, sources...)
+	}
+
+	// Keep only the lines of the named declarations of the files defining them
+	if *symbol != "" {
+		if files != nil || remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source) {
+			report.fail(exitFailure, "usage", "", "--symbol requires a local directory as the source")
+		}
+		if selected != nil {
+			report.fail(exitFailure, "usage", "", "--symbol cannot be combined with --go-package or --js-entry")
+		}
+		ranges, missing, err := gosymbol.Find(cfg.Source, config.ParsePatterns(*symbol))
+		if err != nil {
+			report.fail(exitFailure, "symbol", *symbol, "Failed to find symbols: %v", err)
+		}
+		for _, name := range missing {
+			report.warn("symbol_missing", name, "Symbol '%s' not found", name)
+		}
+		if len(ranges) == 0 {
+			report.fail(exitNoFiles, "no_files", *symbol, "No symbols matched '%s'", *symbol)
+		}
+		cfg.LineRanges = ranges
+		for path := range ranges {
+			selected = append(selected, path)
+		}
+	}
+
+	// Include a diff with the current content of the files it touches
+	if patchOnly {
+		if files != nil || remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source) {
+			report.fail(exitFailure, "usage", "", "patch requires a local directory as the source")
+		}
+		diff, err := readPatch(patchPath)
+		if err != nil {
+			report.fail(exitFailure, "patch_file", patchPath, "Failed to read patch: %v", err)
+		}
+		touched := patch.Parse(diff)
+		if len(touched) == 0 {
+			report.fail(exitFailure, "usage", "", "No file changes found in patch '%s'", patchPath)
+		}
+		cfg.Patch = diff
+
+		found := 0
+		for _, file := range touched {
+			if file.IsDeleted {
+				continue
+			}
+			path := filepath.Join(cfg.Source, filepath.FromSlash(file.Path))
+			if !config.FileExists(path) {
+				report.warn("patch_missing", file.Path, "File '%s' changed by the patch does not exist in the source", file.Path)
+				continue
+			}
+			selected = append(selected, config.AbsPath(path))
+			found++
+		}
+		if found == 0 {
+			report.fail(exitNoFiles, "no_files", patchPath, "None of the files changed by the patch exist in the source")
+		}
+	}
+
+	// Keep only the files owned by the given users or teams, within any
+	// other selection
+	if *owner != "" {
+		if files != nil || remoteFetcher(cfg.Source, cfg) != nil || !config.DirExists(cfg.Source) {
+			report.fail(exitFailure, "usage", "", "--owner requires a local directory as the source")
+		}
+		dir := config.AbsPath(cfg.Source)
+		root := dir
+		if repoRoot, _, err := cfg.Git().Origin(); err == nil {
+			root = repoRoot
+		}
+		owned, err := codeowners.Files(root, dir, config.ParsePatterns(*owner))
+		if err != nil {
+			report.fail(exitFailure, "owner", *owner, "Failed to read code owners: %v", err)
+		}
+		if selected != nil {
+			owns := map[string]bool{}
+			for _, path := range owned {
+				owns[path] = true
+			}
+			owned = slices.DeleteFunc(selected, func(path string) bool { return !owns[path] })
+		}
+		if len(owned) == 0 {
+			report.fail(exitNoFiles, "no_files", *owner, "No files are owned by '%s'", *owner)
+		}
+		selected = owned
+	}
+	if selected != nil {
+		cfg.Select(selected)
+	}
+
+	// Explain the decision for one path instead of writing a digest
+	if checkOnly {
+		checkPattern(checkPath, cfg, report)
+	}
+
+	// Write the digest on every change of the source rather than once
+	if *watchMode {
+		watch(cfg, flag.CommandLine, flag.Args(), *pushURL, report)
+	}
+
 	// Process based on input type
 	var allNodes []*analyzer.FileSystemNode
+	var commit string        // HEAD of a fetched git source, resolved before cleanup
+	var commitTime time.Time // Committer date of that HEAD, for --timestamp-from=git
 
-	// If specific files are provided via -f flag, process them
-	if *filesList != "" {
-		files := config.ParsePatterns(*filesList)
+	// If specific files are provided, process them
+	if files != nil {
+		missing := 0
 		for _, file := range files {
 			// Verify that each file exists
 			if !config.FileExists(file) {
-				fmt.Fprintf(os.Stderr, "Error: File '%s' does not exist\n", file)
+				report.warn("source_missing", file, "File '%s' does not exist", file)
+				missing++
 				continue
 			}
 
 			// Process the file
-			node, err := analyzer.ProcessPath(file, cfg)
+			result, err := analyzer.ProcessPath(file, cfg)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Failed to process '%s': %v\n", file, err)
+				report.skip("process_failed", config.PathError{Path: file, Err: err}, "Failed to process '%s': %v", file, err)
 				continue
 			}
 
-			allNodes = append(allNodes, node)
+			allNodes = append(allNodes, result.Root)
+		}
+
+		if cfg.Strict && report.partial {
+			report.fail(exitFailure, "strict", "", "Some files could not be processed (--strict)")
 		}
 
 		if len(allNodes) == 0 {
-			fmt.Fprintf(os.Stderr, "Error: No valid files were found to process\n")
-			os.Exit(1)
+			code := exitNoFiles
+			if missing == len(files) {
+				code = exitSourceMissing
+			}
+			report.fail(code, "no_files", "", "No valid files were found to process")
 		}
 	} else {
-		// Process the source directory/file specified as positional argument
-		if !config.FileExists(cfg.Source) && !config.DirExists(cfg.Source) {
-			fmt.Fprintf(os.Stderr, "Error: Source '%s' does not exist\n", cfg.Source)
-			os.Exit(1)
+		// Process the source directory/file specified as positional argument,
+		// downloading bucket, git, ssh, and image sources to a temporary directory first
+		source, cleanup := cfg.Source, func() {}
+		fetch := remoteFetcher(cfg.Source, cfg)
+		if fetch != nil {
+			slog.Info("Fetching source", "source", cfg.Source)
+			dir, done, err := fetch(cfg.Source, cfg)
+			if err != nil {
+				report.fail(exitSourceMissing, "fetch_failed", cfg.Source, "Failed to fetch '%s': %v", cfg.Source, err)
+			}
+			source, cleanup = dir, done
+			// Git features read the clone, which is gone once analyzed
+			if gitsource.IsURL(cfg.Source, cfg.GitHosts) {
+				cfg.SetGitDir(dir)
+				commit, _ = cfg.Git().Head()
+				if cfg.TimestampFrom == config.TimestampGit {
+					commitTime, _ = cfg.Git().CommitTime()
+				}
+			}
+		} else if !config.FileExists(cfg.Source) && !config.DirExists(cfg.Source) {
+			report.fail(exitSourceMissing, "source_missing", cfg.Source, "Source '%s' does not exist", cfg.Source)
 		}
 
-		node, err := analyzer.ProcessPath(cfg.Source, cfg)
+		slog.Info("Analyzing source", "source", cfg.Source)
+		started := time.Now()
+		result, err := analyzer.ProcessPath(source, cfg)
+		cleanup()
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to process '%s': %v\n", cfg.Source, err)
-			os.Exit(1)
+			report.fail(exitFailure, "process_failed", cfg.Source, "Failed to process '%s': %v", cfg.Source, err)
+		}
+		node := result.Root
+		slog.Info("Analyzed source", "files", node.FileCount, "dirs", node.DirCount, "size", node.Size,
+			"excluded_files", node.Stats.ExcludedFiles, "pruned_dirs", node.Stats.PrunedDirs,
+			"duration", time.Since(started).Round(time.Millisecond).String())
+
+		// Report every path that was skipped because of an error
+		for _, pe := range result.Errors {
+			report.skip("path_skipped", pe, "Skipped '%s': %v", pe.Path, pe.Err)
+		}
+		if cfg.Strict && report.partial {
+			report.fail(exitFailure, "strict", cfg.Source, "Some paths could not be processed (--strict)")
+		}
+
+		if node.IsDir && node.FileCount == 0 {
+			report.fail(exitNoFiles, "no_files", cfg.Source, "No files matched in '%s'", cfg.Source)
 		}
 
 		allNodes = append(allNodes, node)
 	}
 
-	// Prepare output
-	output := ""
-
-	// Process each node and add to output
-	for i, node := range allNodes {
-		// Format the results
-		result := formatter.FormatResults(node, cfg)
+	// Print the extension report instead of writing a digest
+	if statsOnly {
+		fmt.Print(formatter.FormatExtensionStats(formatter.ExtensionStats(allNodes...), 0))
+		report.summarizeSkipped()
+		report.exit(report.exitCode())
+	}
 
-		// Add separator between multiple files
-		if i > 0 {
-			output += "\n" + config.Separator + "\n\n"
+	// Replace file contents with summaries for a map of the repository
+	if summarizer != nil {
+		slog.Info("Summarizing files", "model", summarizer.Model)
+		for _, node := range allNodes {
+			if err := analyzer.Summarize(node, cfg, summarizer.Model, summarizer.Summarize); err != nil {
+				report.fail(exitFailure, "summarize_failed", cfg.SummarizeWith, "Failed to summarize: %v", err)
+			}
 		}
-
-		// Add formatted content
-		output += result.Summary + "\n"
-		output += result.DirectoryStructure + "\n"
-		output += result.FileContents
 	}
 
 	// Write the output to a file
@@ -136,36 +889,333 @@ func main() {
 	if outputDir != "" && outputDir != "." {
 		err := os.MkdirAll(outputDir, 0755)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to create output directory: %v\n", err)
-			os.Exit(1)
+			report.fail(exitWriteFailed, "write_failed", outputDir, "Failed to create output directory: %v", err)
+		}
+	}
+
+	// Stamp every format with the tool version, timestamp, and source
+	header := formatter.NewHeader(appName, appVersion, cfg, files)
+	if commit != "" {
+		header.Commit = commit
+	}
+	if !commitTime.IsZero() {
+		header.GeneratedAt = commitTime.UTC()
+	}
+	header.Command = reproductionCommand(flag.CommandLine, subcommand, flag.Args())
+
+	// Anonymize after summarizing, so summaries are covered too
+	if cfg.Anonymize {
+		anonymizer := newAnonymizer(cfg, allNodes)
+		for _, node := range allNodes {
+			analyzer.Anonymize(node, anonymizer.String)
+		}
+		cfg.Patch = anonymizer.String(cfg.Patch)
+		header.Source = anonymizer.String(header.Source)
+		header.Command = anonymizer.String(header.Command)
+		header.Commit = ""
+	}
+
+	digest := formatter.NewDigest(allNodes, header, cfg)
+
+	// Embed the chunks before rendering, so a failing provider leaves any
+	// previous output untouched
+	if cfg.Embed != "" {
+		client, err := embed.New(cfg.Embed, cfg.EmbedModel)
+		if err != nil {
+			report.fail(exitFailure, "embed_failed", cfg.Embed, "Failed to compute embeddings: %v", err)
+		}
+		cfg.EmbedModel = client.Model
+
+		chunks := digest.Chunks()
+		texts := make([]string, len(chunks))
+		for i, chunk := range chunks {
+			texts[i] = chunk.Content
+		}
+		slog.Info("Computing embeddings", "provider", cfg.Embed, "model", client.Model, "chunks", len(texts))
+		vectors, err := client.Embed(texts)
+		if err != nil {
+			report.fail(exitFailure, "embed_failed", cfg.Embed, "Failed to compute embeddings: %v", err)
+		}
+		for i, chunk := range chunks {
+			chunk.Embedding = vectors[i]
+		}
+	}
+
+	// Render each format to a temporary file renamed into place, so an
+	// interrupted run never leaves a truncated digest behind
+	for i, format := range cfg.Formats {
+		path := cfg.OutputFiles[i]
+		err = writeAtomically(path, cfg.Backup, func(tmp string) error {
+			return writeFormat(digest, format, tmp, cfg)
+		})
+		if err != nil {
+			report.fail(exitWriteFailed, "write_failed", path, "Failed to write output file: %v", err)
+		}
+		slog.Info("Wrote output", "path", path, "format", format)
+	}
+	if cfg.MetricsCSV != "" {
+		err = writeAtomically(cfg.MetricsCSV, cfg.Backup, func(tmp string) error {
+			return writeOutput(tmp, "", digest.WriteMetricsCSV)
+		})
+		if err != nil {
+			report.fail(exitWriteFailed, "write_failed", cfg.MetricsCSV, "Failed to write metrics file: %v", err)
+		}
+		slog.Info("Wrote metrics", "path", cfg.MetricsCSV)
+	}
+
+	fmt.Printf("Analysis complete! Output written to: %s\n", strings.Join(cfg.OutputFiles, ", "))
+	report.summarizeSkipped()
+	report.exit(report.exitCode())
+}
+
+// writeFormat renders the digest in one output format to path; templates
+// and prompts apply to the text format
+func writeFormat(digest *formatter.Digest, format, path string, cfg *config.Config) error {
+	switch format {
+	case config.FormatJSON:
+		return writeOutput(path, cfg.Compress, digest.WriteJSON)
+	case config.FormatMarkdown:
+		return writeOutput(path, cfg.Compress, func(w io.Writer) error {
+			_, err := io.WriteString(w, digest.Markdown())
+			return err
+		})
+	case config.FormatSQLite:
+		return digest.WriteSQLite(path)
+	case config.FormatJSONL:
+		return writeOutput(path, cfg.Compress, digest.WriteJSONL)
+	case config.FormatChunks:
+		return writeOutput(path, cfg.Compress, digest.WriteChunks)
+	case config.FormatMermaid:
+		return writeOutput(path, cfg.Compress, digest.WriteMermaid)
+	case config.FormatDOT:
+		return writeOutput(path, cfg.Compress, digest.WriteDOT)
+	}
+
+	output := ""
+	if cfg.Template != "" {
+		var err error
+		if output, err = digest.Template(cfg.Template); err != nil {
+			return err
 		}
+		output = formatter.WrapPrompt(output, cfg)
+	} else {
+		output = digest.Text()
 	}
+	return writeOutput(path, cfg.Compress, func(w io.Writer) error {
+		_, err := io.WriteString(w, output)
+		return err
+	})
+}
 
-	err := os.WriteFile(cfg.OutputFile, []byte(output), 0644)
+// newAnonymizer returns the anonymizer for the analyzed source, naming the
+// repository after the git URL, the top-level directory of the work tree, or
+// the first analyzed node
+func newAnonymizer(cfg *config.Config, nodes []*analyzer.FileSystemNode) *anonymize.Anonymizer {
+	if gitsource.IsURL(cfg.Source, cfg.GitHosts) {
+		url := gitsource.Parse(cfg.Source, cfg.GitHosts).URL
+		return anonymize.New(gitsource.RepoName(url), url)
+	}
+	if root, origin, err := cfg.Git().Origin(); err == nil {
+		return anonymize.New(filepath.Base(root), origin)
+	}
+	return anonymize.New(nodes[0].Name, "")
+}
+
+// remoteFetcher returns the function that fetches a remote source into a
+// temporary directory, or nil if the source is local
+func remoteFetcher(source string, cfg *config.Config) func(string, *config.Config) (string, func(), error) {
+	switch {
+	case objectstore.IsURL(source):
+		return objectstore.Fetch
+	case dockersource.IsURL(source):
+		return dockersource.Fetch
+	case gitsource.IsPullRequest(source, cfg.GitHosts):
+		return gitsource.FetchPullRequest
+	case gitsource.IsURL(source, cfg.GitHosts):
+		return gitsource.Fetch
+	case sshsource.IsRemote(source):
+		return sshsource.Fetch
+	}
+	return nil
+}
+
+// writeOutput creates the output file and streams into it through write,
+// compressing with method unless it is empty
+func writeOutput(path, method string, write func(io.Writer) error) error {
+	file, err := os.Create(path)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error: Failed to write output file: %v\n", err)
-		os.Exit(1)
+		return err
 	}
 
-	fmt.Printf("Analysis complete! Output written to: %s\n", cfg.OutputFile)
+	var w io.Writer = file
+	var compressor io.WriteCloser
+	if method != "" {
+		compressor, err = compress.NewWriter(file, method)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w = compressor
+	}
+
+	if err := write(w); err != nil {
+		if compressor != nil {
+			compressor.Close()
+		}
+		file.Close()
+		return err
+	}
+
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	return file.Close()
 }
 
 // printUsage prints the usage information
 func printUsage() {
-	fmt.Printf("Usage: %s [options] [source]\n\n", appName)
+	fmt.Printf("Usage: %s [options] [source]\n", appName)
+	fmt.Printf("       %s selftest    Verify the installation on a synthetic tree\n", appName)
+	fmt.Printf("       %s stats [options] [source]  Print file counts, sizes, and token shares per extension\n", appName)
+	fmt.Printf("       %s check-pattern [options] path [source]  Explain whether a digest would include path\n", appName)
+	fmt.Printf("       %s patch [options] DIFF [source]  Digest a diff with the current content of the files it touches\n", appName)
+	fmt.Printf("       %s serve [--listen ADDR] [--dir DIR] [--token TOKEN]  Digest registered sources on a schedule and serve the latest digests\n", appName)
+	fmt.Printf("       %s completion bash|zsh|fish|powershell  Print a shell completion script\n\n", appName)
 	fmt.Println("Options:")
 	fmt.Println("  -o, --output FILE    Output file (default: digest.txt)")
 	fmt.Println("  -i, --include PATTERN Patterns to include (comma-separated)")
 	fmt.Println("  -e, --exclude PATTERN Patterns to exclude (comma-separated)")
-	fmt.Println("  -f, --files FILES    Specific files to analyze (comma-separated)")
+	fmt.Println("      --order RULES    Place files matching the rules first, in rule order (comma-separated)")
+	fmt.Println("      --preset NAMES   Apply curated excludes and ordering: go, node, python, rust (comma-separated)")
+	fmt.Println("      --no-readme-first Keep README, ARCHITECTURE, and CONTRIBUTING docs in tree order")
+	fmt.Println("      --git-host HOST=KIND Treat HOST as a github, gitlab, or bitbucket instance (comma-separated)")
+	fmt.Println("      --subpath DIR    Fetch and analyze only DIR of a git URL source (sparse, partial clone) or docker:// image")
+	fmt.Println("      --go-package PKG Analyze only the sources of Go package PKG of the source module")
+	fmt.Println("      --with-deps      Add the in-module packages imported by --go-package")
+	fmt.Println("      --symbol NAMES   Include only the named Go declarations (Name, pkg.Name, Type.Method), with")
+	fmt.Println("                       the package clause and imports of their files")
+	fmt.Println("      --owner OWNERS   Analyze only the files CODEOWNERS assigns to the users or teams, such as @acme/backend")
+	fmt.Println("      --js-entry FILES Analyze only JS/TS entrypoints and the files reachable through relative imports")
+	fmt.Println("  -f, --files FILES    Specific files to analyze (comma-separated, - to read from stdin),")
+	fmt.Println("                       optionally with line ranges such as main.go:100-250")
+	fmt.Println("      --files-from FILE Read file paths to analyze from FILE, one per line (- for stdin)")
+	fmt.Println("  -0, --null           File lists read from stdin or --files-from are NUL-delimited")
+	fmt.Println("      --format FORMAT  Output format: text, json, markdown, sqlite, jsonl, chunks, mermaid, dot (default: text; comma-separated for several)")
+	fmt.Println("      --chunk-tokens N Maximum estimated tokens of each chunk (default: 512)")
+	fmt.Println("      --chunk-overlap N Estimated tokens shared by consecutive chunks (default: 64)")
+	fmt.Println("      --embed PROVIDER Compute chunk embeddings with openai or ollama (chunks and sqlite formats)")
+	fmt.Println("      --embed-model MODEL Embedding model (default: text-embedding-3-small, nomic-embed-text)")
+	fmt.Println("      --summarize-with URL Replace file contents with summaries by an Ollama model (http://host:port/model)")
+	fmt.Println("      --summarize-prompt TEXT Instructions given to the model before each file")
+	fmt.Println("      --full PATTERNS  Keep the full content of matching files only (comma-separated)")
+	fmt.Println("      --summary-rest   List files not matching --full in the tree and statistics only")
+	fmt.Println("      --template FILE  Render the output with a Go text/template")
+	fmt.Println("      --prompt TEXT    Instructions placed after the digest, making the output a ready-to-send prompt")
+	fmt.Println("      --prompt-file FILE Place the contents of FILE before the digest")
+	fmt.Println("      --cost           Add the estimated input cost for common models to the summary")
+	fmt.Println("      --pricing FILE   Price the input with the 'MODEL USD-PER-MILLION-TOKENS' lines of FILE (implies --cost)")
+	fmt.Println("      --metrics-csv FILE Also write per-file path, bytes, lines, tokens, language, and modification time to FILE")
+	fmt.Println("      --backup         Keep the previous output file as <output>.bak")
+	fmt.Println("      --compress METHOD Compress the output with gzip (.gz) or zstd (.zst)")
 	fmt.Println("  -s, --size SIZE      Maximum file size to process in bytes (default: 10MB)")
-	fmt.Println("  -v, --version        Show version information")
+	fmt.Println("      --include-generated Include full contents of generated and minified files")
+	fmt.Println("      --no-dedupe      Include every copy of duplicate files")
+	fmt.Println("      --keep-embedded  Keep embedded base64 blobs (data URIs, notebook outputs)")
+	fmt.Println("      --outline        Reduce Go, Python, Java, and JS/TS sources to declarations and doc comments")
+	fmt.Println("      --docs-only      Reduce sources to their leading comment, doc comments, and docstrings")
+	fmt.Println("      --strip-comments Remove comments from Go, JS/TS, Python, C-family, and shell sources")
+	fmt.Println("      --keep-doc-comments Keep doc comments and docstrings with --strip-comments")
+	fmt.Println("      --strip-license-headers Remove copyright and license header comments matching the LICENSE file")
+	fmt.Println("      --normalize-eol  Convert CRLF and CR line endings to LF")
+	fmt.Println("      --strip-trailing-whitespace Remove trailing spaces and tabs from every line")
+	fmt.Println("      --collapse-blank-lines N Keep at most N consecutive blank lines")
+	fmt.Println("      --hidden         Traverse dotfiles and dot-directories (default)")
+	fmt.Println("      --no-hidden      Skip all dotfiles and dot-directories")
+	fmt.Println("      --ignore-case    Match patterns regardless of case (default)")
+	fmt.Println("      --case-sensitive Match patterns case-sensitively, except the default excludes")
+	fmt.Println("      --max-depth N    Maximum directory depth to traverse (default: 20)")
+	fmt.Println("      --max-files N    Maximum number of files to process (default: 10000)")
+	fmt.Println("      --max-total-size SIZE Maximum total size of processed files in bytes (default: 500MB)")
+	fmt.Println("      --max-tokens N   Maximum estimated tokens of the included file contents (0 for unlimited)")
+	fmt.Println("      --tree-depth N   Collapse the rendered tree below depth N (contents still included)")
+	fmt.Println("      --content-depth N Include contents of files up to depth N only (deeper files stay in the tree)")
+	fmt.Println("      --tree-stats     Annotate directories in the tree with file counts, sizes, and tokens")
+	fmt.Println("      --tree-metadata  Show permissions, modification times, and symlink targets in the tree")
+	fmt.Println("      --no-git         Disable all git probing")
+	fmt.Println("      --no-gitignore   Do not apply .gitignore, .git/info/exclude, or core.excludesFile rules")
+	fmt.Println("      --one-file-system Do not cross mount points into other file systems")
+	fmt.Println("      --no-timestamp   Omit the generation timestamp from the header")
+	fmt.Println("      --timestamp-from SOURCE Header timestamp source: now, git (default: now)")
+	fmt.Println("      --no-deps        Omit the dependency summary section")
+	fmt.Println("      --no-terraform   Omit the Terraform resources and modules section")
+	fmt.Println("      --binary MODE    Binary files: placeholder, skip, hexdump, base64 (default: placeholder)")
+	fmt.Println("      --image-metadata Describe images by format, dimensions, and size (kept even with --binary skip)")
+	fmt.Println("      --max-binary-size SIZE Largest binary file embedded with --binary base64 (default: 64KB)")
+	fmt.Println("      --lfs            Fetch Git LFS objects with git-lfs instead of noting their pointers")
+	fmt.Println("      --tests MODE     Test files: include, exclude, only (default: include)")
+	fmt.Println("      --migrations MODE Migration directories: all, latest, schema (default: all)")
+	fmt.Println("      --latest-migrations N Number of migration versions kept by --migrations latest (default: 5)")
+	fmt.Println("      --helm-render DIRS Replace the templates of these Helm charts with their rendered manifests")
+	fmt.Println("      --helm-values FILES Values files used by --helm-render, relative to each chart")
+	fmt.Println("      --sample-rows N  Keep only the header and the first N rows of CSV and TSV files")
+	fmt.Println("      --skeleton-size SIZE Replace larger JSON and YAML files with their keys, types, and array lengths (default: 1MB, 0 to disable)")
+	fmt.Println("      --log-tail N     Include *.log files, keeping only their last N lines")
+	fmt.Println("      --exclude-lockfiles Replace lockfile contents (go.sum, package-lock.json, ...) with placeholders")
+	fmt.Println("      --blame          Prefix every content line with the commit and author that last changed it")
+	fmt.Println("      --authors        Annotate each file with its CODEOWNERS owners and main git blame authors")
+	fmt.Println("      --churn          Add a section ranking the files changed by the most commits (requires git)")
+	fmt.Println("      --churn-since DATE Start of the history counted by --churn (default: 6 months ago)")
+	fmt.Println("      --hotspots       Add a section ranking the files with the highest cyclomatic complexity")
+	fmt.Println("      --architecture   Add a section mapping the top-level directories and Go package imports")
+	fmt.Println("      --todos          Add a section listing TODO/FIXME/HACK/XXX comments")
+	fmt.Println("      --go-symbols     Append an index of exported Go symbols with their defining and referencing files")
+	fmt.Println("      --with-issues NUMS Append the threads of these GitHub or GitLab issues, such as 42,57")
+	fmt.Println("      --strict         Fail the run if any path could not be processed")
+	fmt.Println("      --anonymize      Replace the repository name, user names, internal hosts, and emails with placeholders")
+	fmt.Println("      --toc            Emit a table of contents with file offsets")
+	fmt.Println("      --separator LINE Line drawn around file headers and between sources (empty for none)")
+	fmt.Println("      --file-header STYLE File header: default, markdown (### path), plain, or a template")
+	fmt.Println("                       with {path} and {separator} placeholders, for example \"## {path}\"")
+	fmt.Println("      --watch          Write the digest again whenever a file of the source changes")
+	fmt.Println("      --push-url URL   POST every digest written by --watch to a webhook")
+	fmt.Println("      --config FILE    Options file (default: .ingest.yaml in the current directory)")
+	fmt.Println("      --config-profile NAME Apply the named profile of the options file")
+	fmt.Println("      --log-format FORMAT Format of log records on stderr: text, json (default: text)")
+	fmt.Println("      --pprof KIND     Record a cpu or mem pprof profile, or an execution trace, of the run")
+	fmt.Println("      --pprof-output FILE Profile file (default: cpu.pprof, mem.pprof, or trace.out)")
+	fmt.Println("                       --profile and --profile-output are deprecated aliases; options")
+	fmt.Println("                       file profiles are selected with --config-profile")
+	fmt.Println("  -v, --verbose        Log progress on stderr")
+	fmt.Println("      -vv              Log progress and every decision on stderr")
+	fmt.Println("      --version        Show version information")
 	fmt.Println("  -h, --help           Show help")
+	fmt.Println("\nEnvironment:")
+	fmt.Println("  INGEST_<OPTION> sets --<option> unless given on the command line, for example")
+	fmt.Println("  INGEST_MAX_DEPTH=5, INGEST_NO_GIT=1; INGEST_OUTPUT, INGEST_INCLUDE, INGEST_EXCLUDE,")
+	fmt.Println("  INGEST_FILES, and INGEST_MAX_FILE_SIZE set -o, -i, -e, -f, and -s; they override")
+	fmt.Println("  the options file")
+	fmt.Println("\nExit codes:")
+	fmt.Println("  0 success, 1 usage or unexpected failure, 2 no files matched,")
+	fmt.Println("  3 source missing, 4 output write failure, 5 written with skipped errors")
 	fmt.Println("\nExamples:")
 	fmt.Println("  ingest                           # Analyze current directory")
 	fmt.Println("  ingest /path/to/directory        # Analyze specific directory")
 	fmt.Println("  ingest -o output.txt /path/to/dir # Specify output file")
 	fmt.Println("  ingest -i \"*.go,*.md\" /path/to/dir # Include specific patterns")
 	fmt.Println("  ingest -e \"vendor/,*.tmp\" /path/to/dir # Exclude specific patterns")
+	fmt.Println("  git diff --name-only main | ingest --files-from - # Analyze changed files")
+	fmt.Println("  ingest --subpath cmd/server https://github.com/org/repo # Digest one directory of a repository")
+	fmt.Println("  ingest --go-package ./cmd/server --with-deps . # Digest one binary and its in-module imports")
+	fmt.Println("  ingest https://github.com/org/repo/pull/1234 # Review a pull request with its diff and comments")
+	fmt.Println("  ingest s3://bucket/prefix        # Analyze objects in an S3 bucket (or gs://)")
+	fmt.Println("  ingest user@host:/srv/app         # Analyze a directory on a remote host over ssh")
+	fmt.Println("  ingest --subpath /app docker://org/app:1.2 # Audit the files shipped in a container image")
+	fmt.Println("  ingest --format sqlite /path/to/dir # Write a SQLite database (digest.db)")
+	fmt.Println("  ingest --compress zstd /path/to/dir # Write a compressed digest (digest.txt.zst)")
+	fmt.Println("  ingest --watch --push-url https://hooks.example.com/digest . # Push the digest on every change")
+	fmt.Println("  ingest --file-header markdown --separator '' . # Prompt-style ### path headers")
 	fmt.Println("  ingest -f \"file1.go,file2.go,README.md\" # Analyze specific files")
 }
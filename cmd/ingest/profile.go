@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// Profile kinds for --pprof
+const (
+	profileCPU   = "cpu"
+	profileMem   = "mem"
+	profileTrace = "trace"
+)
+
+// deprecatedFlags maps the former names of the profiling flags to their
+// current ones. They were renamed so --profile is not mistaken for
+// --config-profile, and remain accepted with a warning.
+var deprecatedFlags = map[string]string{
+	"profile":        "pprof",
+	"profile-output": "pprof-output",
+}
+
+// profileOutputs are the default profile files, named like the files of
+// go test -cpuprofile, -memprofile, and -trace
+var profileOutputs = map[string]string{
+	profileCPU:   "cpu.pprof",
+	profileMem:   "mem.pprof",
+	profileTrace: "trace.out",
+}
+
+// startProfile starts recording a profile of the given kind to path and
+// returns the function that stops it and finishes the file. Heap profiles
+// are taken when stopped, so they show what the run kept alive at the end
+// and, with -sample_index=alloc_space, everything it allocated.
+func startProfile(kind, path string) (func() error, error) {
+	if _, ok := profileOutputs[kind]; !ok {
+		return nil, fmt.Errorf("unknown profile kind '%s'", kind)
+	}
+	if path == "" {
+		path = profileOutputs[kind]
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case profileCPU:
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		}, nil
+	case profileTrace:
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return func() error {
+			trace.Stop()
+			return f.Close()
+		}, nil
+	default: // profileMem
+		return func() error {
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		}, nil
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/formatter"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// selftestTree is the synthetic source tree digested by the selftest
+// command, keyed by slash-separated relative path
+var selftestTree = map[string]string{
+	"README.md":                 "# Selftest\n\nA synthetic project used to verify the installation.\n",
+	"LICENSE":                   "Permission is hereby granted, free of charge, to any person obtaining a copy\nof this software.\n",
+	"go.mod":                    "module example.com/selftest\n\ngo 1.22\n",
+	"cmd/app/main.go":           "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n",
+	"pkg/util/util.go":          "package util\n\n// Add returns the sum of a and b\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+	"pkg/util/data/sample.txt":  "line one\nline two\n\nline four after a blank line\n",
+	"assets/logo.png":           "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+	"node_modules/dep/index.js": "module.exports = {}\n",
+}
+
+// selftestExcluded lists selftest paths expected to be excluded by default patterns
+var selftestExcluded = map[string]bool{"node_modules/dep/index.js": true}
+
+// selftestFileHeader matches a file header in a text digest
+var selftestFileHeader = regexp.MustCompile(`(?m)^` + config.DefaultSeparator + `\nFILE: (.+)\n` + config.DefaultSeparator + `\n`)
+
+// selftestCheck is a named step of the selftest
+type selftestCheck struct {
+	name string
+	run  func(s *selftestState) error
+}
+
+// selftestState carries results between selftest checks
+type selftestState struct {
+	dir    string
+	cfg    *config.Config
+	root   *analyzer.FileSystemNode
+	header *formatter.Header
+	digest *formatter.Digest
+	text   string
+}
+
+// runSelftest digests a synthetic tree, parses the outputs back, and
+// verifies them. It returns the process exit code.
+func runSelftest() int {
+	dir, err := os.MkdirTemp("", "ingest-selftest-")
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to create temporary directory: %v", err))
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	state := &selftestState{dir: dir}
+	checks := []selftestCheck{
+		{"write synthetic tree", selftestWriteTree},
+		{"analyze synthetic tree", selftestAnalyze},
+		{"text digest round trip", selftestText},
+		{"text digest token estimate", selftestTokens},
+		{"jsonl digest schema and round trip", selftestJSONL},
+	}
+
+	fmt.Printf("%s %s selftest (%s)\n", appName, appVersion, dir)
+	for _, check := range checks {
+		// Later checks depend on earlier ones, so stop at the first failure
+		if err := check.run(state); err != nil {
+			fmt.Printf("  FAIL %s: %v\n", check.name, err)
+			fmt.Println("selftest FAILED")
+			return 1
+		}
+		fmt.Printf("  ok   %s\n", check.name)
+	}
+
+	fmt.Println("selftest passed")
+	return 0
+}
+
+// selftestWriteTree writes the synthetic tree to the temporary directory
+func selftestWriteTree(s *selftestState) error {
+	for rel, content := range selftestTree {
+		path := filepath.Join(s.dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selftestAnalyze processes the synthetic tree and checks which files were found
+func selftestAnalyze(s *selftestState) error {
+	s.cfg = config.NewConfig()
+	s.cfg.Source = s.dir
+	s.cfg.NoGit = true
+	s.cfg.TimestampFrom = config.TimestampNone
+
+	result, err := analyzer.ProcessPath(s.dir, s.cfg)
+	if err != nil {
+		return err
+	}
+	if result.Partial() {
+		return fmt.Errorf("skipped %s: %v", result.Errors[0].Path, result.Errors[0].Err)
+	}
+	root := result.Root
+	s.root = root
+	s.header = formatter.NewHeader(appName, appVersion, s.cfg, nil)
+	s.digest = formatter.NewDigest([]*analyzer.FileSystemNode{root}, s.header, s.cfg)
+
+	got := []string{}
+	for _, file := range root.Files() {
+		got = append(got, file.RelPath(root))
+	}
+
+	want := []string{}
+	for rel := range selftestTree {
+		if !selftestExcluded[rel] {
+			want = append(want, rel)
+		}
+	}
+
+	sort.Strings(got)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		return fmt.Errorf("analyzed files %v, want %v", got, want)
+	}
+	return nil
+}
+
+// selftestText renders the text digest and checks every file parses back
+// to its original content
+func selftestText(s *selftestState) error {
+	s.text = s.digest.Text()
+
+	if !strings.HasPrefix(s.text, s.header.Text()) {
+		return fmt.Errorf("digest does not start with the header")
+	}
+
+	parsed := parseTextDigest(s.text)
+	if len(parsed) != len(s.root.Files()) {
+		return fmt.Errorf("parsed %d files, want %d", len(parsed), len(s.root.Files()))
+	}
+
+	rootName := filepath.Base(s.dir)
+	for path, content := range parsed {
+		rel, ok := selftestMatchPath(rootName, path)
+		if !ok {
+			return fmt.Errorf("unexpected file header %q", path)
+		}
+
+		want := selftestTree[rel]
+		if strings.HasSuffix(rel, ".png") {
+			want = "[Binary file]"
+		}
+		if content != want {
+			return fmt.Errorf("content of %s does not round trip", rel)
+		}
+	}
+	return nil
+}
+
+// selftestTokens checks the summary token estimate against the parsed contents
+func selftestTokens(s *selftestState) error {
+	chars := 0
+	for _, content := range parseTextDigest(s.text) {
+		chars += len(content)
+	}
+
+	want := fmt.Sprintf("Estimated tokens: %s\n", utils.FormatTokenCount(chars/4))
+	if !strings.Contains(s.text, want) {
+		return fmt.Errorf("summary does not contain %q", strings.TrimSpace(want))
+	}
+	return nil
+}
+
+// selftestJSONL renders the JSONL digest and validates every record
+func selftestJSONL(s *selftestState) error {
+	var buf bytes.Buffer
+	if err := s.digest.WriteJSONL(&buf); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+
+	records := 0
+	for line := 1; scanner.Scan(); line++ {
+		var record map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("line %d: %v", line, err)
+		}
+
+		required := []string{"type", "path", "size", "tokens", "content"}
+		if line == 1 {
+			required = []string{"type", "tool", "version", "source"}
+		}
+		for _, key := range required {
+			if _, ok := record[key]; !ok {
+				return fmt.Errorf("line %d: missing field %q", line, key)
+			}
+		}
+		if line == 1 {
+			if record["type"] != "header" || record["version"] != appVersion {
+				return fmt.Errorf("line 1: invalid header record")
+			}
+			continue
+		}
+
+		path, _ := record["path"].(string)
+		content, _ := record["content"].(string)
+		tokens, _ := record["tokens"].(float64)
+		if _, ok := selftestTree[path]; !ok || record["type"] != "file" {
+			return fmt.Errorf("line %d: unexpected record for %q", line, path)
+		}
+		if int(tokens) != utils.EstimateTokens(content) {
+			return fmt.Errorf("line %d: tokens %d, want %d", line, int(tokens), utils.EstimateTokens(content))
+		}
+		records++
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if records != len(s.root.Files()) {
+		return fmt.Errorf("got %d file records, want %d", records, len(s.root.Files()))
+	}
+	return nil
+}
+
+// parseTextDigest splits a text digest into file contents keyed by header path
+func parseTextDigest(digest string) map[string]string {
+	files := map[string]string{}
+	matches := selftestFileHeader.FindAllStringSubmatchIndex(digest, -1)
+
+	for i, match := range matches {
+		end := len(digest)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+
+		// Each file content is followed by a blank line
+		path := digest[match[2]:match[3]]
+		files[path] = strings.TrimSuffix(digest[match[1]:end], "\n\n")
+	}
+
+	return files
+}
+
+// selftestMatchPath maps a header path back to a synthetic tree path.
+// Headers show the parent directory name and file name only.
+func selftestMatchPath(rootName, header string) (string, bool) {
+	for rel := range selftestTree {
+		if strings.HasSuffix("/"+rootName+"/"+rel, "/"+header) {
+			return rel, true
+		}
+	}
+	return "", false
+}
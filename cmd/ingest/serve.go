@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/schedule"
+)
+
+// defaultServeListen is the address ingest serve listens on by default,
+// reachable from the local machine only
+const defaultServeListen = "127.0.0.1:8080"
+
+// serveSourcesFile stores the registered sources in the state directory
+const serveSourcesFile = "sources.json"
+
+// serveMaxBody limits the size of registration requests
+const serveMaxBody = 1 << 20
+
+// serveSourceName matches valid source names, which appear in URLs and
+// directory names
+var serveSourceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// serveTokenEnv names the environment variable holding the default --token,
+// which keeps the token out of the process list
+const serveTokenEnv = "INGEST_SERVE_TOKEN"
+
+// serveFlags are the flags registrations may pass to the digest runs, mapped
+// to whether they take a value. They only shape the digest of the source:
+// flags reading or writing other local files, reaching other hosts, or
+// placing the output, which the server sets itself, are not accepted.
+var serveFlags = map[string]bool{
+	// Selection
+	"i": true, "e": true, "order": true, "preset": true, "subpath": true, "go-package": true,
+	"symbol": true, "owner": true, "js-entry": true, "tests": true, "migrations": true,
+	"latest-migrations": true, "no-readme-first": false, "with-deps": false,
+	"hidden": false, "no-hidden": false, "ignore-case": false, "case-sensitive": false,
+	"no-git": false, "no-gitignore": false, "one-file-system": false, "strict": false,
+
+	// Limits
+	"s": true, "max-files": true, "max-total-size": true, "max-tokens": true, "max-depth": true,
+	"tree-depth": true, "content-depth": true, "max-binary-size": true,
+	"chunk-tokens": true, "chunk-overlap": true,
+
+	// Content
+	"full": true, "summary-rest": false, "binary": true, "sample-rows": true,
+	"skeleton-size": true, "log-tail": true, "collapse-blank-lines": true,
+	"include-generated": false, "no-dedupe": false, "keep-embedded": false,
+	"outline": false, "docs-only": false, "strip-comments": false, "keep-doc-comments": false,
+	"strip-license-headers": false, "normalize-eol": false, "strip-trailing-whitespace": false,
+	"image-metadata": false, "exclude-lockfiles": false, "anonymize": false,
+
+	// Sections and layout
+	"prompt": true, "churn-since": true, "timestamp-from": true, "separator": true, "file-header": true,
+	"cost": false, "tree-stats": false, "tree-metadata": false, "no-timestamp": false,
+	"no-deps": false, "no-terraform": false, "blame": false, "authors": false, "churn": false,
+	"hotspots": false, "architecture": false, "todos": false, "go-symbols": false, "toc": false,
+}
+
+// serveRegistration is a source registered with the server, as accepted by
+// PUT /sources/NAME and stored in the state directory
+type serveRegistration struct {
+	Source   string   `json:"source"`           // Path or URL to digest, as on the command line
+	Schedule string   `json:"schedule"`         // When to digest it again (see schedule.Parse)
+	Format   string   `json:"format,omitempty"` // Output format (default: text)
+	Args     []string `json:"args,omitempty"`   // Other flags of the digest runs, such as -e=vendor/ (see serveFlags)
+}
+
+// serveStatus describes a registered source and its latest digest
+type serveStatus struct {
+	Name string `json:"name"`
+	serveRegistration
+	URL       string     `json:"url"`                  // Path of the latest digest
+	ETag      string     `json:"etag,omitempty"`       // Entity tag of the latest digest
+	LastRun   *time.Time `json:"last_run,omitempty"`   // Start of the run that wrote the latest digest
+	NextRun   time.Time  `json:"next_run"`             // Start of the next scheduled run
+	Running   bool       `json:"running"`              // Whether a run is in progress
+	LastError string     `json:"last_error,omitempty"` // Failure of the last run, if it failed
+}
+
+// serveSource is a registered source and the state of its runs
+type serveSource struct {
+	name         string
+	registration serveRegistration
+	schedule     *schedule.Schedule
+	path         string             // Latest digest
+	cancel       context.CancelFunc // Stops the schedule and any run in progress
+
+	mu          sync.Mutex // Guards the fields below
+	etag        string     // Entity tag of the digest with the modification time and size below
+	etagModTime time.Time
+	etagSize    int64
+	lastRun     time.Time
+	nextRun     time.Time
+	running     bool
+	lastError   string
+}
+
+// server digests registered sources on their schedules and serves the
+// latest digest of each
+type server struct {
+	dir        string // State directory holding the sources file and digests
+	executable string // ingest executable running the digests
+
+	mu      sync.Mutex // Guards sources and the sources file
+	sources map[string]*serveSource
+}
+
+// runServe runs the serve subcommand, returning the process exit code only
+// if the server cannot start
+func runServe(args []string) int {
+	flags := flag.NewFlagSet(appName+" serve", flag.ContinueOnError)
+	listen := flags.String("listen", defaultServeListen, "Address to listen on")
+	dir := flags.String("dir", "", "State directory holding registrations and digests (default: the user cache directory)")
+	token := flags.String("token", os.Getenv(serveTokenEnv), "Token every request must send as 'Authorization: Bearer TOKEN' (default: $"+serveTokenEnv+")")
+	if err := flags.Parse(args); err != nil {
+		return exitFailure
+	}
+	if flags.NArg() > 0 {
+		slog.Error(fmt.Sprintf("Usage: %s serve [--listen ADDR] [--dir DIR] [--token TOKEN]", appName))
+		return exitFailure
+	}
+	slog.SetDefault(newLogger(os.Stderr, logFormatText, slog.LevelInfo))
+
+	// The digest runs need not know the token, and would warn about it
+	os.Unsetenv(serveTokenEnv)
+
+	// Anyone reaching the server can run digests, so beyond the local
+	// machine clients must authenticate
+	if *token == "" && !isLoopback(*listen) {
+		slog.Error(fmt.Sprintf("Listening on %s requires --token or $%s; without one, listen on the loopback interface", *listen, serveTokenEnv))
+		return exitFailure
+	}
+
+	if *dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			slog.Error(fmt.Sprintf("No state directory: %v; use --dir", err))
+			return exitFailure
+		}
+		*dir = filepath.Join(cache, appName, "serve")
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		slog.Error(fmt.Sprintf("Failed to locate the ingest executable: %v", err))
+		return exitFailure
+	}
+
+	s := &server{dir: *dir, executable: executable, sources: map[string]*serveSource{}}
+	if err := s.load(); err != nil {
+		slog.Error(fmt.Sprintf("Failed to load registered sources: %v", err))
+		return exitFailure
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sources", s.handleList)
+	mux.HandleFunc("GET /sources/{name}", s.handleStatus)
+	mux.HandleFunc("PUT /sources/{name}", s.handleRegister)
+	mux.HandleFunc("DELETE /sources/{name}", s.handleDelete)
+	mux.HandleFunc("GET /sources/{name}/digest", s.handleDigest)
+
+	fmt.Printf("Serving digests on http://%s/sources (state in %s)\n", *listen, *dir)
+	var handler http.Handler = mux
+	if *token != "" {
+		handler = requireToken(*token, mux)
+	}
+	httpServer := &http.Server{Addr: *listen, Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	if err := httpServer.ListenAndServe(); err != nil {
+		slog.Error(fmt.Sprintf("Failed to serve: %v", err))
+	}
+	return exitFailure
+}
+
+// isLoopback reports whether the listen address only accepts connections
+// from the local machine
+func isLoopback(listen string) bool {
+	host, _, err := net.SplitHostPort(listen)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// requireToken rejects requests that do not send token as a bearer token
+func requireToken(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// load registers the sources stored in the state directory. Sources whose
+// digest is missing are digested right away; the others keep their digest
+// until their next scheduled run.
+func (s *server) load() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, serveSourcesFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var registrations map[string]serveRegistration
+	if err := json.Unmarshal(data, &registrations); err != nil {
+		return fmt.Errorf("%s: %w", serveSourcesFile, err)
+	}
+	for name, registration := range registrations {
+		src, err := s.newSource(name, registration)
+		if err != nil {
+			return fmt.Errorf("%s: %w", serveSourcesFile, err)
+		}
+		s.sources[name] = src
+		s.start(src, src.etag == "")
+	}
+	return nil
+}
+
+// save stores the registered sources in the state directory; the caller
+// holds s.mu
+func (s *server) save() error {
+	registrations := map[string]serveRegistration{}
+	for name, src := range s.sources {
+		registrations[name] = src.registration
+	}
+	data, err := json.MarshalIndent(registrations, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return err
+	}
+	return writeAtomically(filepath.Join(s.dir, serveSourcesFile), false, func(tmp string) error {
+		return os.WriteFile(tmp, append(data, '\n'), 0644)
+	})
+}
+
+// newSource validates a registration and returns its source, picking up a
+// digest left by a previous registration of the same name and format
+func (s *server) newSource(name string, registration serveRegistration) (*serveSource, error) {
+	if !serveSourceName.MatchString(name) {
+		return nil, fmt.Errorf("invalid source name '%s': use letters, digits, '.', '_', and '-'", name)
+	}
+	if registration.Source == "" || strings.HasPrefix(registration.Source, "-") {
+		return nil, fmt.Errorf("invalid source '%s'", registration.Source)
+	}
+	parsed, err := schedule.Parse(registration.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if registration.Format == "" {
+		registration.Format = config.FormatText
+	}
+	if !config.ValidFormat(registration.Format) {
+		return nil, fmt.Errorf("unknown format '%s'", registration.Format)
+	}
+	for _, arg := range registration.Args {
+		if err := checkServeArg(arg); err != nil {
+			return nil, err
+		}
+	}
+
+	src := &serveSource{
+		name:         name,
+		registration: registration,
+		schedule:     parsed,
+		path:         filepath.Join(s.dir, name, config.OutputFileFor(config.DefaultOutputFile, registration.Format)),
+	}
+	if file, info, _, err := src.openDigest(); err == nil {
+		file.Close()
+		src.lastRun = info.ModTime()
+	}
+	return src, nil
+}
+
+// checkServeArg returns an error unless arg is one of serveFlags, given as
+// --flag or -flag, with its value after "=" if it takes one
+func checkServeArg(arg string) error {
+	if !strings.HasPrefix(arg, "-") {
+		return fmt.Errorf("argument '%s' is not a flag: the source is given by \"source\"", arg)
+	}
+	name, _, hasValue := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-"), "=")
+	takesValue, ok := serveFlags[name]
+	switch {
+	case !ok:
+		return fmt.Errorf("flag '%s' cannot be passed to the server", arg)
+	case takesValue && !hasValue:
+		return fmt.Errorf("flag '%s' needs a value, given as %s=VALUE", arg, arg)
+	}
+	return nil
+}
+
+// start runs the schedule of a source until it is unregistered, first
+// digesting it right away if now is set
+func (s *server) start(src *serveSource, now bool) {
+	ctx, cancel := context.WithCancel(context.Background())
+	src.cancel = cancel
+
+	next := time.Now()
+	if !now {
+		next = src.schedule.Next(next)
+	}
+	src.mu.Lock()
+	src.nextRun = next
+	src.mu.Unlock()
+
+	go func() {
+		for {
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.run(ctx, src)
+			}
+
+			next = src.schedule.Next(time.Now())
+			src.mu.Lock()
+			src.nextRun = next
+			src.mu.Unlock()
+		}
+	}()
+}
+
+// run digests a source by running ingest with its flags. The digest is
+// replaced atomically, so readers get either the previous or the new one,
+// and a failed run leaves the previous one in place.
+func (s *server) run(ctx context.Context, src *serveSource) {
+	started := time.Now()
+	src.mu.Lock()
+	src.running = true
+	src.mu.Unlock()
+	slog.Info("Digesting source", "name", src.name, "source", src.registration.Source)
+
+	args := []string{"--watch=false", "--push-url=", "--format=" + src.registration.Format, "-o=" + src.path}
+	args = append(append(args, src.registration.Args...), src.registration.Source)
+
+	err := os.MkdirAll(filepath.Dir(src.path), 0755)
+	if err == nil {
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, s.executable, args...)
+		cmd.Stdout = io.Discard
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == exitPartial {
+			err = nil
+		} else if err != nil && stderr.Len() > 0 {
+			err = errors.New(strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	// Unregistered while running
+	if ctx.Err() != nil {
+		return
+	}
+
+	src.mu.Lock()
+	src.running = false
+	if err != nil {
+		src.lastError = err.Error()
+		src.mu.Unlock()
+		slog.Warn(fmt.Sprintf("Failed to digest '%s': %v", src.name, err))
+		return
+	}
+	src.lastRun, src.lastError = started, ""
+	src.mu.Unlock()
+
+	// Hash the new digest now rather than on the first request
+	if file, _, etag, err := src.openDigest(); err == nil {
+		file.Close()
+		slog.Info("Digested source", "name", src.name, "etag", etag, "duration", time.Since(started).Round(time.Millisecond).String())
+	}
+}
+
+// openDigest opens the latest digest of a source, returning it with its
+// file information and entity tag: its quoted SHA-256. The tag is computed
+// from the opened file, so it always matches the content served, and only
+// when the digest changed since it was last computed.
+func (src *serveSource) openDigest() (*os.File, fs.FileInfo, string, error) {
+	file, err := os.Open(src.path)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, "", err
+	}
+
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	if src.etag == "" || !info.ModTime().Equal(src.etagModTime) || info.Size() != src.etagSize {
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			file.Close()
+			return nil, nil, "", err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			file.Close()
+			return nil, nil, "", err
+		}
+		src.etag = `"` + hex.EncodeToString(hash.Sum(nil)) + `"`
+		src.etagModTime, src.etagSize = info.ModTime(), info.Size()
+	}
+	return file, info, src.etag, nil
+}
+
+// status returns the status of a source
+func (src *serveSource) status() serveStatus {
+	src.mu.Lock()
+	defer src.mu.Unlock()
+	status := serveStatus{
+		Name:              src.name,
+		serveRegistration: src.registration,
+		URL:               "/sources/" + src.name + "/digest",
+		ETag:              src.etag,
+		NextRun:           src.nextRun,
+		Running:           src.running,
+		LastError:         src.lastError,
+	}
+	if !src.lastRun.IsZero() {
+		lastRun := src.lastRun
+		status.LastRun = &lastRun
+	}
+	return status
+}
+
+// lookup returns the source named in the request path, or nil
+func (s *server) lookup(r *http.Request) *serveSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sources[r.PathValue("name")]
+}
+
+// handleList responds with the status of every source, sorted by name
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	statuses := make([]serveStatus, 0, len(s.sources))
+	for _, src := range s.sources {
+		statuses = append(statuses, src.status())
+	}
+	s.mu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+// handleStatus responds with the status of a source
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	src := s.lookup(r)
+	if src == nil {
+		writeError(w, http.StatusNotFound, "no source named '%s'", r.PathValue("name"))
+		return
+	}
+	writeJSON(w, http.StatusOK, src.status())
+}
+
+// handleRegister registers a source, or replaces the registration of the
+// same name, and digests it right away. The previous digest is served until
+// then.
+func (s *server) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var registration serveRegistration
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&registration); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid registration: %v", err)
+		return
+	}
+
+	name := r.PathValue("name")
+	src, err := s.newSource(name, registration)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	s.mu.Lock()
+	previous := s.sources[name]
+	if previous != nil {
+		previous.cancel()
+		if previous.path != src.path {
+			os.Remove(previous.path)
+		}
+	}
+	s.sources[name] = src
+	err = s.save()
+	s.start(src, true)
+	s.mu.Unlock()
+	if err != nil {
+		slog.Warn(fmt.Sprintf("Failed to save registered sources: %v", err))
+	}
+
+	status := http.StatusCreated
+	if previous != nil {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, src.status())
+}
+
+// handleDelete unregisters a source and removes its digest
+func (s *server) handleDelete(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	s.mu.Lock()
+	src := s.sources[name]
+	if src != nil {
+		src.cancel()
+		delete(s.sources, name)
+		if err := s.save(); err != nil {
+			slog.Warn(fmt.Sprintf("Failed to save registered sources: %v", err))
+		}
+	}
+	s.mu.Unlock()
+
+	if src == nil {
+		writeError(w, http.StatusNotFound, "no source named '%s'", name)
+		return
+	}
+	src.mu.Lock()
+	os.RemoveAll(filepath.Join(s.dir, name))
+	src.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDigest serves the latest digest of a source with its ETag, answering
+// If-None-Match with 304 Not Modified when the digest has not changed
+func (s *server) handleDigest(w http.ResponseWriter, r *http.Request) {
+	src := s.lookup(r)
+	if src == nil {
+		writeError(w, http.StatusNotFound, "no source named '%s'", r.PathValue("name"))
+		return
+	}
+
+	file, info, etag, err := src.openDigest()
+	if errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "no digest of '%s' has been written yet", src.name)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "%v", err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", formatContentTypes[src.registration.Format])
+	http.ServeContent(w, r, "", info.ModTime(), file)
+}
+
+// writeJSON responds with value as indented JSON
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
+
+// writeError responds with a JSON error message
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// watchInterval is how often --watch checks the source for changes
+const watchInterval = time.Second
+
+// watchFlags are the flags controlling watch mode. The digest runs it starts
+// turn them off explicitly, overriding the environment and options file.
+var watchFlags = map[string]bool{"watch": true, "push-url": true}
+
+// formatContentTypes maps each output format to the Content-Type it is
+// pushed and served with
+var formatContentTypes = map[string]string{
+	config.FormatText:     "text/plain; charset=utf-8",
+	config.FormatJSON:     "application/json",
+	config.FormatMarkdown: "text/markdown; charset=utf-8",
+	config.FormatSQLite:   "application/vnd.sqlite3",
+	config.FormatJSONL:    "application/x-ndjson",
+	config.FormatChunks:   "application/x-ndjson",
+	config.FormatMermaid:  "text/vnd.mermaid; charset=utf-8",
+	config.FormatDOT:      "text/vnd.graphviz; charset=utf-8",
+}
+
+// pushClient posts regenerated digests to the --push-url webhook
+var pushClient = &http.Client{Timeout: time.Minute}
+
+// watch writes the digest, then writes it again whenever a file below the
+// source directory changes, until interrupted. Each digest is written by
+// running ingest with the same flags and arguments, so a failing run is
+// reported and the next change retried. With pushURL, every output file of
+// a run that wrote them is posted to it.
+func watch(cfg *config.Config, flags *flag.FlagSet, args []string, pushURL string, report *reporter) {
+	executable, err := os.Executable()
+	if err != nil {
+		report.fail(exitFailure, "watch_failed", "", "Failed to locate the ingest executable: %v", err)
+	}
+	runArgs := watchRunArgs(flags, args)
+
+	// Files written by the digest runs must not trigger another run
+	written := func(path string) bool {
+		return cfg.IsDigestOutput(path) || cfg.MetricsCSV != "" && isWrittenFile(path, cfg.MetricsCSV)
+	}
+
+	last := ""
+	for {
+		current, err := treeFingerprint(cfg.Source, cfg, written)
+		if err != nil {
+			report.notice("watch_failed", cfg.Source, "Failed to scan '%s': %v", cfg.Source, err)
+		} else if current != last {
+			// Wait for the tree to settle, so a burst of saves is one run
+			for last != "" {
+				time.Sleep(watchInterval)
+				settled, err := treeFingerprint(cfg.Source, cfg, written)
+				if err != nil || settled == current {
+					break
+				}
+				current = settled
+			}
+			last = current
+
+			if regenerate(executable, runArgs, report) && pushURL != "" {
+				for i, path := range cfg.OutputFiles {
+					if err := push(pushURL, path, cfg.Formats[i], cfg); err != nil {
+						report.notice("push_failed", path, "Failed to push '%s' to %s: %v", path, pushURL, err)
+					} else {
+						slog.Info("Pushed digest", "url", pushURL, "path", path)
+					}
+				}
+			}
+			fmt.Printf("Watching %s for changes (Ctrl+C to stop)\n", cfg.Source)
+		}
+		time.Sleep(watchInterval)
+	}
+}
+
+// watchRunArgs returns the arguments of the digest runs started by watch:
+// every flag set on the command line, in the environment, or by an options
+// file, except the watch flags, and the positional arguments
+func watchRunArgs(flags *flag.FlagSet, args []string) []string {
+	runArgs := []string{"--watch=false", "--push-url="}
+	flags.Visit(func(f *flag.Flag) {
+		if !watchFlags[f.Name] {
+			runArgs = append(runArgs, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return append(runArgs, args...)
+}
+
+// regenerate runs ingest to write the digest, reporting whether the output
+// files were written: on success, or with some paths skipped
+func regenerate(executable string, args []string, report *reporter) bool {
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == exitPartial:
+		return true
+	}
+	report.notice("watch_failed", "", "Failed to write the digest (%v); waiting for the next change", err)
+	return false
+}
+
+// isWrittenFile reports whether path is the file written by
+// writeAtomically at output, its backup, or its temporary file
+func isWrittenFile(path, output string) bool {
+	path, output = config.AbsPath(path), config.AbsPath(output)
+	if path == output || path == output+".bak" {
+		return true
+	}
+	return filepath.Dir(path) == filepath.Dir(output) &&
+		strings.HasPrefix(filepath.Base(path), "."+filepath.Base(output)+".tmp-")
+}
+
+// treeFingerprint hashes the path, size, and modification time of every
+// file below root that the digest would read: files passing the
+// include/exclude patterns and git ignore rules, leaving out those for which
+// written is true
+func treeFingerprint(root string, cfg *config.Config, written func(string) bool) (string, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+
+	// Ask git for the ignored paths again, since files may have been created
+	cfg.SetRoot(root)
+	cfg.ReloadGitIgnored()
+	hash := sha256.New()
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed while walking are picked up by the next scan
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" || cfg.ShouldExclude(path) || cfg.IsGitIgnored(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if written(path) || !cfg.ShouldIncludeTree(root, path) || cfg.IsGitIgnored(path) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(hash, "%s\x00%d\x00%d\n", path, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// push posts an output file to the webhook at url, with its format and the
+// source in headers; compressed files are sent with their Content-Encoding
+func push(url, path, format string, cfg *config.Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, file)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", formatContentTypes[format])
+	if cfg.Compress != "" {
+		req.Header.Set("Content-Encoding", cfg.Compress)
+	}
+	req.Header.Set("User-Agent", appName+"/"+appVersion)
+	req.Header.Set("X-Ingest-Source", cfg.Source)
+	req.Header.Set("X-Ingest-Format", format)
+
+	resp, err := pushClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
@@ -1,12 +1,21 @@
 package analyzer
 
 import (
+	"errors"
+	"fmt"
 	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/deps"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/terraform"
+	"github.com/agris/ingest-clone/pkg/transform"
+	"github.com/agris/ingest-clone/pkg/utils"
 )
 
 // FileSystemNode represents a node in the file system tree
@@ -20,25 +29,103 @@ type FileSystemNode struct {
 	Children  []*FileSystemNode // Child nodes (if it's a directory)
 	FileCount int               // Number of files in this directory and subdirectories
 	DirCount  int               // Number of directories in this directory and subdirectories
+
+	DuplicateOf string             // Path of an identical file whose content is included instead
+	SHA256      string             // Hex SHA-256 of the raw file contents (files only)
+	ModTime     time.Time          // Last modification time
+	Mode        fs.FileMode        // File mode and permission bits
+	LinkTarget  string             // Target of the symbolic link (symlinks only)
+	Language    string             // Language detected from the name or shebang (files only)
+	MIME        string             // MIME type detected from the contents (files only)
+	IsBinary    bool               // Whether the file was detected as binary
+	IsBlank     bool               // Whether the file is empty or holds only whitespace
+	Lines       []config.LineRange // Lines kept of the file (nil for the whole file)
+	Entrypoint  string             // How the file starts execution, such as "func main" (empty if it does not)
+	Commits     int                // Commits changing the file since cfg.ChurnSince (with cfg.Churn)
+	Authors     int                // Distinct authors of those commits (with cfg.Churn)
+	Owners      []string           // Owners assigned by CODEOWNERS (with cfg.Authors)
+	TopAuthors  []Author           // Main authors by git blame, most lines first (with cfg.Authors)
+	Stats       *config.Stats      // Processing statistics (root node only)
+
+	hasText  bool   // Whether Content holds the file's text rather than a placeholder
+	device   uint64 // Device of a directory with --one-file-system, 0 if unknown
+	redacted int    // Secrets replaced in a Terraform state file
 }
 
 // NewFileSystemNode creates a new FileSystemNode
 func NewFileSystemNode(path string, info fs.FileInfo, depth int) *FileSystemNode {
+	var linkTarget string
+	if info.Mode()&fs.ModeSymlink != 0 {
+		linkTarget, _ = os.Readlink(pathutil.Long(path))
+	}
+
+	// Directory sizes are the sum of their files, not the directory entry
+	size := info.Size()
+	if info.IsDir() {
+		size = 0
+	}
+
 	return &FileSystemNode{
 		Name:      info.Name(),
 		Path:      path,
 		IsDir:     info.IsDir(),
-		Size:      info.Size(),
+		Size:      size,
 		Depth:     depth,
 		Children:  []*FileSystemNode{},
 		FileCount: 0,
 		DirCount:  0,
+
+		ModTime:    info.ModTime(),
+		Mode:       info.Mode(),
+		LinkTarget: linkTarget,
+	}
+}
+
+// Files returns all file nodes below the node in tree order
+func (n *FileSystemNode) Files() []*FileSystemNode {
+	if !n.IsDir {
+		return []*FileSystemNode{n}
+	}
+
+	files := []*FileSystemNode{}
+	for _, child := range n.Children {
+		files = append(files, child.Files()...)
 	}
+	return files
 }
 
-// ProcessPath analyzes a file or directory and returns a FileSystemNode
-func ProcessPath(path string, cfg *config.Config) (*FileSystemNode, error) {
-	info, err := os.Stat(path)
+// RelPath returns the slash-separated path of the node relative to root. A
+// root that is itself a file is represented by its name.
+func (n *FileSystemNode) RelPath(root *FileSystemNode) string {
+	if n == root {
+		return n.Name
+	}
+
+	rel, err := filepath.Rel(root.Path, n.Path)
+	if err != nil {
+		return n.Name
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Result is the outcome of analyzing a path: the tree, and every path below
+// it that could not be processed, such as unreadable files and directories
+// whose listing was denied. The tree is complete except for those paths.
+type Result struct {
+	Root   *FileSystemNode    // Root of the analyzed tree
+	Errors []config.PathError // Paths skipped because of errors, in traversal order
+}
+
+// Partial reports whether any path was skipped because of an error
+func (r *Result) Partial() bool {
+	return len(r.Errors) > 0
+}
+
+// ProcessPath analyzes a file or directory. Errors below the path are
+// collected in the result rather than stopping the analysis; an error is
+// returned only when the path itself cannot be read.
+func ProcessPath(path string, cfg *config.Config) (*Result, error) {
+	info, err := os.Stat(pathutil.Long(path))
 	if err != nil {
 		return nil, err
 	}
@@ -49,31 +136,78 @@ func ProcessPath(path string, cfg *config.Config) (*FileSystemNode, error) {
 		return nil, err
 	}
 
+	// Anchored patterns are relative to the analyzed path
+	cfg.SetRoot(absPath)
+
 	// Create root node
 	root := NewFileSystemNode(absPath, info, 0)
+	if cfg.OneFileSystem {
+		root.device, _ = pathutil.Device(absPath, info)
+	}
 
 	// Create stats object to track file processing stats
 	stats := &config.Stats{}
+	root.Stats = stats
 
 	// Process the node
 	if info.IsDir() {
 		err = processDirectory(root, cfg, stats)
 	} else {
 		err = processFile(root, cfg)
+		stats.RedactedSecrets += root.redacted
 	}
 
-	return root, err
+	// Drop license boilerplate while every file still has its content
+	if cfg.StripLicenseHeaders {
+		stripLicenseHeaders(root, stats)
+	}
+
+	// Empty files such as __init__.py and .gitkeep are listed without content
+	markBlankFiles(root, stats)
+	markPackageBins(root)
+	if cfg.Churn {
+		markChurn(root, cfg)
+	}
+	if cfg.Authors {
+		markOwners(root, cfg)
+	}
+
+	// Keep full content only for the critical paths; model summaries of the
+	// rest are added later by Summarize
+	if cfg.SummaryRest && cfg.SummarizeWith == "" {
+		summarizeRest(root, cfg, stats)
+	}
+	if info.IsDir() && !cfg.NoDedupe {
+		deduplicate(root)
+	}
+
+	return &Result{Root: root, Errors: stats.Errors}, err
+}
+
+// markBlankFiles marks the text files below root that are empty or hold
+// only whitespace, counting them for the summary
+func markBlankFiles(root *FileSystemNode, stats *config.Stats) {
+	for _, file := range root.Files() {
+		if file.hasText && strings.TrimSpace(file.Content) == "" {
+			file.IsBlank = true
+			stats.OmittedBlank++
+		}
+	}
 }
 
+// ErrReservedName is recorded for entries named like a device
+var ErrReservedName = errors.New("reserved device name")
+
 // processDirectory processes a directory and its contents
 func processDirectory(node *FileSystemNode, cfg *config.Config, stats *config.Stats) error {
-	// Check if max depth is reached
+	// Check if max depth is reached, counting what is cut off
 	if node.Depth >= cfg.MaxDirDepth {
+		countBelowDepth(node.Path, cfg, stats)
 		return nil
 	}
 
 	// Read directory entries
-	entries, err := os.ReadDir(node.Path)
+	entries, err := os.ReadDir(pathutil.Long(node.Path))
 	if err != nil {
 		return err
 	}
@@ -82,23 +216,58 @@ func processDirectory(node *FileSystemNode, cfg *config.Config, stats *config.St
 	for _, entry := range entries {
 		entryPath := filepath.Join(node.Path, entry.Name())
 
-		// Check if we should include this path
-		if !cfg.ShouldInclude(entryPath) || cfg.ShouldExclude(entryPath) {
+		// Check if we should include this path; excluded directories are
+		// pruned without listing them
+		if reason := filterReason(entryPath, entry.IsDir(), cfg); reason != "" {
+			slog.Debug("Skipping path", "path", entryPath, "reason", reason)
+			if entry.IsDir() {
+				stats.PrunedDirs++
+			} else {
+				stats.ExcludedFiles++
+			}
+			continue
+		}
+
+		// Never ingest the output or an earlier digest into the new one
+		if !entry.IsDir() && cfg.IsDigestOutput(entryPath) {
+			slog.Debug("Skipping path", "path", entryPath, "reason", "digest")
+			stats.OmittedDigests++
+			continue
+		}
+
+		// Reading a reserved device name such as CON on Windows would read
+		// from the device instead of a file
+		if pathutil.IsReservedName(entry.Name()) {
+			stats.Errors = append(stats.Errors, config.PathError{Path: entryPath, Err: ErrReservedName})
 			continue
 		}
 
 		info, err := entry.Info()
 		if err != nil {
-			continue // Skip entries that can't be accessed
+			// Skip entries that can't be accessed
+			stats.Errors = append(stats.Errors, config.PathError{Path: entryPath, Err: err})
+			continue
 		}
 
 		child := NewFileSystemNode(entryPath, info, node.Depth+1)
 
 		if entry.IsDir() {
+			// Stay on the source's file system, so a stray network mount
+			// cannot stall the run
+			if cfg.OneFileSystem {
+				if device, ok := pathutil.Device(entryPath, info); ok && node.device != 0 && device != node.device {
+					slog.Debug("Skipping path", "path", entryPath, "reason", "mount")
+					stats.OmittedMounts++
+					continue
+				}
+				child.device = node.device
+			}
+
 			// Process subdirectory
 			err = processDirectory(child, cfg, stats)
 			if err != nil {
-				// Log error but continue processing
+				// Record error but continue processing
+				stats.Errors = append(stats.Errors, config.PathError{Path: entryPath, Err: err})
 				continue
 			}
 			node.DirCount += child.DirCount + 1
@@ -107,22 +276,50 @@ func processDirectory(node *FileSystemNode, cfg *config.Config, stats *config.St
 		} else {
 			// Process file
 			if stats.TotalFiles >= cfg.MaxFiles {
+				slog.Debug("Skipping path", "path", entryPath, "reason", "max-files")
+				stats.OmittedByMaxFiles++
 				continue // Skip if max files limit reached
 			}
 
 			if stats.TotalSize+info.Size() > cfg.MaxTotalSize {
+				slog.Debug("Skipping path", "path", entryPath, "reason", "max-total-size")
+				stats.OmittedByTotalSize++
 				continue // Skip if max total size limit reached
 			}
 
 			if info.Size() > cfg.MaxFileSize {
+				slog.Debug("Skipping path", "path", entryPath, "reason", "max-size")
+				stats.OmittedByFileSize++
 				continue // Skip if file size exceeds limit
 			}
 
 			err = processFile(child, cfg)
 			if err != nil {
-				// Log error but continue processing
+				// Record error but continue processing
+				stats.Errors = append(stats.Errors, config.PathError{Path: entryPath, Err: err})
+				continue
+			}
+
+			// Leave binary files out entirely when requested
+			if omitBinary(child, cfg) {
+				slog.Debug("Skipping path", "path", entryPath, "reason", "binary")
+				stats.OmittedBinary++
 				continue
 			}
+			// Files whose contents would exceed the token budget are left
+			// out, so smaller files later in the walk may still fit
+			tokens := utils.EstimateTokens(child.Content)
+			if cfg.MaxTokens > 0 && stats.TotalTokens+tokens > cfg.MaxTokens {
+				slog.Debug("Skipping path", "path", entryPath, "reason", "max-tokens")
+				stats.OmittedByTokens++
+				continue
+			}
+			stats.TotalTokens += tokens
+			if beyondContentDepth(child, cfg) {
+				stats.OmittedContent++
+			}
+			stats.RedactedSecrets += child.redacted
+			slog.Debug("Processed file", "path", entryPath, "language", child.Language, "size", child.Size)
 
 			node.FileCount++
 			node.Size += child.Size
@@ -134,78 +331,225 @@ func processDirectory(node *FileSystemNode, cfg *config.Config, stats *config.St
 		node.Children = append(node.Children, child)
 	}
 
+	// Shorten migration directories when requested
+	summarizeMigrations(node, cfg, stats)
+
+	// Show the manifests of Helm charts rather than their templates
+	renderHelmChart(node, cfg, stats)
+
 	// Sort children for consistent output
 	sortChildren(node)
 
 	return nil
 }
 
+// countBelowDepth counts the files and directories below a directory at the
+// maximum depth that pass the include/exclude patterns
+func countBelowDepth(path string, cfg *config.Config, stats *config.Stats) {
+	entries, err := os.ReadDir(pathutil.Long(path))
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		entryPath := filepath.Join(path, entry.Name())
+		if filterReason(entryPath, entry.IsDir(), cfg) != "" {
+			continue
+		}
+
+		if entry.IsDir() {
+			stats.OmittedDirsByDepth++
+			countBelowDepth(entryPath, cfg, stats)
+		} else {
+			stats.OmittedByDepth++
+		}
+	}
+}
+
+// filterReason returns why the selection, patterns, git ignore rules, or
+// test handling leave out an entry ("patterns" or "tests"), or "" if they
+// keep it. The cheap pattern checks run first, and the exclude patterns
+// are evaluated once even without include patterns.
+func filterReason(path string, isDir bool, cfg *config.Config) string {
+	if _, included := cfg.IncludedBy(path); !cfg.Selected(path) || !included || cfg.ShouldExclude(path) || cfg.IsGitIgnored(path) {
+		return "patterns"
+	}
+	if (!isDir || cfg.Tests == config.TestsExclude) && !cfg.SelectsTests(utils.IsTestFile(path)) {
+		return "tests"
+	}
+	return ""
+}
+
+// beyondContentDepth reports whether a file lies below --content-depth, so
+// that only its name and size are included
+func beyondContentDepth(node *FileSystemNode, cfg *config.Config) bool {
+	return cfg.ContentDepth > 0 && node.Depth > cfg.ContentDepth
+}
+
 // processFile reads and processes a file
 func processFile(node *FileSystemNode, cfg *config.Config) error {
+	// Hash the raw contents so the digest can be verified against the tree.
+	// This also surfaces unreadable files instead of mistaking them for
+	// binary files.
+	sum, err := checksumFile(node.Path)
+	if err != nil {
+		node.Content = "[Error reading file]"
+		return err
+	}
+	node.SHA256 = sum
+	node.Language = utils.DetectLanguage(node.Name)
+
+	// Git LFS pointers stand in for objects stored outside the repository;
+	// fetched objects are read from a temporary file in place of the pointer
+	dataPath := node.Path
+	if pointer, ok := readLFSPointer(node); ok {
+		if !cfg.LFS {
+			node.Content = fmt.Sprintf("[LFS object: %s, not fetched]", utils.FormatSize(pointer.Size))
+			return nil
+		}
+
+		dataPath, err = fetchLFSObject(node.Path)
+		if err != nil {
+			node.Content = fmt.Sprintf("[LFS object: %s, fetch failed]", utils.FormatSize(pointer.Size))
+			return err
+		}
+		defer os.Remove(dataPath)
+		node.Size = pointer.Size
+	}
+
+	node.MIME, node.IsBinary = sniffFile(dataPath)
+
 	// Skip if file is too large
 	if node.Size > cfg.MaxFileSize {
 		node.Content = "[File too large]"
 		return nil
 	}
 
-	// Check if file is binary
-	if isBinaryFile(node.Path) {
-		node.Content = "[Binary file]"
+	// List files below the content depth without their contents
+	if beyondContentDepth(node, cfg) {
+		node.Content = fmt.Sprintf("[Content omitted (--content-depth %d): %s]", cfg.ContentDepth, utils.FormatSize(node.Size))
 		return nil
 	}
 
+	// Leave out generated lockfiles when requested
+	if cfg.ExcludeLockfiles && deps.IsLockfile(node.Name) {
+		node.Content = fmt.Sprintf("[Lockfile, %s omitted]", utils.FormatSize(node.Size))
+		return nil
+	}
+
+	// Check if file is binary
+	if node.IsBinary {
+		node.Content, err = binaryContent(node, dataPath, cfg)
+		if err != nil {
+			node.Content = "[Error reading file]"
+		}
+		return err
+	}
+
 	// Read file content
-	content, err := os.ReadFile(node.Path)
+	content, err := os.ReadFile(pathutil.Long(dataPath))
 	if err != nil {
 		node.Content = "[Error reading file]"
 		return err
 	}
 
+	// Terraform state holds the secrets of the infrastructure in plain text;
+	// the raw content is replaced too, so blame cannot show them
+	if terraform.IsState(node.Name) {
+		var redacted string
+		redacted, node.redacted = terraform.RedactState(string(content))
+		content = []byte(redacted)
+	}
+
 	node.Content = string(content)
-	return nil
-}
+	node.Language = utils.DetectContentLanguage(node.Name, node.Content)
+	node.Entrypoint = detectEntrypoint(node)
+
+	// Keep only the lines selected with entries such as -f main.go:100-250,
+	// numbered as in the file on disk
+	if ranges := cfg.LinesOf(node.Path); ranges != nil {
+		node.Content = selectLines(node.Content, ranges)
+		node.Lines = ranges
+	}
+	selected := node.Content
+
+	// Replace embedded base64 blobs that only waste tokens, before their
+	// long lines make the file look minified
+	if !cfg.KeepEmbedded {
+		node.Content = transform.StripEmbeddedBase64(node.Path, node.Content)
+	}
+
+	// Sample data files rather than including whole datasets
+	if cfg.SampleRows > 0 {
+		node.Content = transform.SampleRows(node.Name, node.Content, cfg.SampleRows)
+	}
+	if cfg.LogTail > 0 {
+		node.Content = transform.TailLines(node.Name, node.Content, cfg.LogTail)
+	}
+	if cfg.SkeletonSize > 0 && int64(len(node.Content)) > cfg.SkeletonSize {
+		if skeleton, ok := transform.Skeleton(node.Name, node.Content); ok {
+			node.Content = skeleton
+		}
+	}
 
-// isBinaryFile checks if a file is likely binary
-func isBinaryFile(path string) bool {
-	// Get file extension
-	ext := strings.ToLower(filepath.Ext(path))
+	// Summarize generated and minified files, which waste token budgets
+	if !cfg.IncludeGenerated {
+		if reason := detectGenerated(node.Name, node.Content); reason != "" {
+			node.Content = fmt.Sprintf("[Generated file (%s), %s omitted]", reason, utils.FormatSize(node.Size))
+			return nil
+		}
+	}
 
-	// List of common binary file extensions
-	binaryExts := map[string]bool{
-		".exe": true, ".dll": true, ".so": true, ".dylib": true,
-		".obj": true, ".o": true, ".a": true, ".lib": true,
-		".bin": true, ".dat": true, ".db": true, ".sqlite": true,
-		".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
-		".pdf": true, ".doc": true, ".docx": true, ".xls": true,
-		".xlsx": true, ".ppt": true, ".pptx": true, ".zip": true,
-		".tar": true, ".gz": true, ".rar": true, ".7z": true,
+	// Keep only the documentation when the API matters more than the code
+	if cfg.DocsOnly {
+		node.Content = transform.ExtractDocs(node.Language, node.Content)
 	}
 
-	if binaryExts[ext] {
-		return true
+	// Reduce source files to their declarations when only the shape matters
+	if cfg.Outline {
+		if outline, ok := transform.Outline(node.Language, node.Content); ok {
+			node.Content = outline
+		}
 	}
 
-	// Check for null bytes in the first 512 bytes
-	file, err := os.Open(path)
-	if err != nil {
-		return true // If we can't read the file, assume it's binary
+	// Drop comments when only the logic matters
+	if cfg.StripComments {
+		node.Content = transform.StripComments(node.Language, node.Content, cfg.KeepDocComments)
 	}
-	defer file.Close()
 
-	buf := make([]byte, 512)
-	n, err := file.Read(buf)
-	if err != nil {
-		return true
+	// Minimize whitespace that costs tokens without carrying meaning
+	if cfg.NormalizeEOL {
+		node.Content = transform.NormalizeEOL(node.Content)
+	}
+	if cfg.StripTrailingWhitespace {
+		node.Content = transform.StripTrailingWhitespace(node.Content)
+	}
+	if cfg.CollapseBlankLines > 0 {
+		node.Content = transform.CollapseBlankLines(node.Content, cfg.CollapseBlankLines)
 	}
 
-	// Look for null bytes, which indicate a binary file
-	for i := 0; i < n; i++ {
-		if buf[i] == 0 {
-			return true
+	// Blame needs the lines as they are on disk, so files changed by the
+	// transformations above are left as they are
+	if cfg.Blame && node.Content == selected {
+		if blamed, ok := blameContent(node, string(content), cfg); ok {
+			node.Content = blamed
 		}
 	}
 
-	return false
+	node.hasText = true
+	return nil
+}
+
+// sniffFile detects the MIME type of a file from its leading bytes and
+// reports whether it is binary. Unreadable files are treated as binary.
+func sniffFile(path string) (string, bool) {
+	head, err := readHead(path, sniffBytes)
+	if err != nil {
+		return "application/octet-stream", true
+	}
+
+	mediaType := detectMIME(filepath.Base(path), head)
+	return mediaType, !isTextMIME(mediaType)
 }
 
 // sortChildren sorts the children of a node
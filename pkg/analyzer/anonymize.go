@@ -0,0 +1,47 @@
+package analyzer
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// Anonymize rewrites the names, paths, link targets, and contents of every
+// node below root, and the paths of the errors recorded for it, with
+// anonymize. Checksums of the raw contents are kept.
+func Anonymize(root *FileSystemNode, anonymize func(string) string) {
+	var walk func(node *FileSystemNode)
+	walk = func(node *FileSystemNode) {
+		node.Name = anonymize(node.Name)
+		node.Path = anonymize(node.Path)
+		node.LinkTarget = anonymize(node.LinkTarget)
+		node.DuplicateOf = anonymize(node.DuplicateOf)
+		node.Entrypoint = anonymize(node.Entrypoint)
+		node.Content = anonymize(node.Content)
+		for i, owner := range node.Owners {
+			node.Owners[i] = anonymize(owner)
+		}
+		// Author names cannot be recognized, so they are replaced by the
+		// placeholders of their emails
+		for i, author := range node.TopAuthors {
+			node.TopAuthors[i] = Author{Name: anonymize(author.Email), Email: anonymize(author.Email), Share: author.Share}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	if root.Stats == nil {
+		return
+	}
+	for i, pe := range root.Stats.Errors {
+		var pathErr *fs.PathError
+		if errors.As(pe.Err, &pathErr) {
+			pe.Err = &fs.PathError{Op: pathErr.Op, Path: anonymize(pathErr.Path), Err: pathErr.Err}
+		} else {
+			pe.Err = errors.New(anonymize(pe.Err.Error()))
+		}
+		pe.Path = anonymize(pe.Path)
+		root.Stats.Errors[i] = pe
+	}
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// writeSyntheticTree writes dirs directories of files Go sources each, with
+// lines lines per file, below dir
+func writeSyntheticTree(b *testing.B, dir string, dirs, files, lines int) {
+	b.Helper()
+
+	var body strings.Builder
+	body.WriteString("package synthetic\n\n")
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&body, "// Func%d returns its argument plus %d\nfunc Func%d(x int) int { return x + %d }\n", i, i, i, i)
+	}
+
+	for d := 0; d < dirs; d++ {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%03d", d))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			// Vary the content so deduplication does not collapse the tree
+			content := fmt.Sprintf("// File %d of package %d\n%s", f, d, body.String())
+			if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%03d.go", f)), []byte(content), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// benchmarkProcessPath measures traversal and processing of a synthetic tree
+// with the options set by configure
+func benchmarkProcessPath(b *testing.B, dirs, files, lines int, configure func(*config.Config)) {
+	dir := b.TempDir()
+	writeSyntheticTree(b, dir, dirs, files, lines)
+
+	cfg := config.NewConfig()
+	cfg.Source = dir
+	cfg.NoGit = true
+	if configure != nil {
+		configure(cfg)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		result, err := ProcessPath(dir, cfg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if result.Root.FileCount != dirs*files {
+			b.Fatalf("processed %d files, want %d", result.Root.FileCount, dirs*files)
+		}
+	}
+}
+
+func BenchmarkProcessPathSmallFiles(b *testing.B) {
+	benchmarkProcessPath(b, 20, 50, 10, nil)
+}
+
+func BenchmarkProcessPathLargeFiles(b *testing.B) {
+	benchmarkProcessPath(b, 4, 10, 2000, nil)
+}
+
+func BenchmarkProcessPathStripComments(b *testing.B) {
+	benchmarkProcessPath(b, 4, 10, 2000, func(cfg *config.Config) {
+		cfg.StripComments = true
+	})
+}
+
+func BenchmarkProcessPathOutline(b *testing.B) {
+	benchmarkProcessPath(b, 4, 10, 2000, func(cfg *config.Config) {
+		cfg.Outline = true
+	})
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// hexdumpBytes is the number of leading bytes shown in hexdump mode
+const hexdumpBytes = 256
+
+// base64LineWidth is the line width of embedded base64 content
+const base64LineWidth = 76
+
+// binaryContent returns the digest content of a binary file, read from
+// path, under the configured policy. Files too large to embed fall back to
+// the placeholder.
+func binaryContent(node *FileSystemNode, path string, cfg *config.Config) (string, error) {
+	if describesImage(node, cfg) {
+		return imageMetadata(node, path), nil
+	}
+
+	switch cfg.BinaryMode {
+	case config.BinaryHexdump:
+		head, err := readHead(path, hexdumpBytes)
+		if err != nil {
+			return "", err
+		}
+		label := fmt.Sprintf("[Binary file, %s]", utils.FormatSize(node.Size))
+		if node.Size > int64(len(head)) {
+			label = fmt.Sprintf("[Binary file, %s, first %d bytes]", utils.FormatSize(node.Size), len(head))
+		}
+		return label + "\n" + strings.TrimSuffix(hex.Dump(head), "\n"), nil
+
+	case config.BinaryBase64:
+		if node.Size > cfg.MaxBinarySize {
+			break
+		}
+		data, err := os.ReadFile(pathutil.Long(path))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("[Binary file, %s, base64]\n%s", utils.FormatSize(node.Size), wrapLines(base64.StdEncoding.EncodeToString(data), base64LineWidth)), nil
+	}
+
+	return "[Binary file]", nil
+}
+
+// omitBinary reports whether the skip policy leaves a file out of the
+// digest. Images described by --image-metadata are kept.
+func omitBinary(node *FileSystemNode, cfg *config.Config) bool {
+	return node.IsBinary && cfg.BinaryMode == config.BinarySkip && !describesImage(node, cfg)
+}
+
+// readHead reads up to n leading bytes of a file
+func readHead(path string, n int) ([]byte, error) {
+	file, err := os.Open(pathutil.Long(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := make([]byte, n)
+	read, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+// wrapLines breaks s into lines of at most width characters
+func wrapLines(s string, width int) string {
+	var builder strings.Builder
+	for len(s) > width {
+		builder.WriteString(s[:width])
+		builder.WriteString("\n")
+		s = s[width:]
+	}
+	builder.WriteString(s)
+	return builder.String()
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// maxBlameAuthor limits the width of author names in blame prefixes
+const maxBlameAuthor = 20
+
+// blameContent prefixes every line of raw, the content of node on disk,
+// with the abbreviated commit and the author that last changed it, as git
+// blame does, and keeps the lines selected for node. It reports false when
+// git cannot blame the file, such as outside a work tree or for untracked
+// files.
+func blameContent(node *FileSystemNode, raw string, cfg *config.Config) (string, bool) {
+	blame, err := cfg.Git().Blame(node.Path)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.SplitAfter(raw, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(blame) != len(lines) {
+		// The file changed since it was read
+		return "", false
+	}
+
+	width := 0
+	for _, line := range blame {
+		width = max(width, min(utf8.RuneCountInString(line.Author), maxBlameAuthor))
+	}
+
+	var builder strings.Builder
+	for i, line := range lines {
+		author := blame[i].Author
+		if utf8.RuneCountInString(author) > maxBlameAuthor {
+			author = string([]rune(author)[:maxBlameAuthor-1]) + "…"
+		}
+		// Pad by characters, since names are often not ASCII
+		author += strings.Repeat(" ", width-utf8.RuneCountInString(author))
+		commit := blame[i].Commit
+		if len(commit) > 8 {
+			commit = commit[:8]
+		}
+		prefix := fmt.Sprintf("%s %s |", commit, author)
+		if strings.TrimRight(line, "\r\n") != "" {
+			prefix += " "
+		}
+		builder.WriteString(prefix + line)
+	}
+
+	blamed := builder.String()
+	if node.Lines != nil {
+		blamed = selectLines(blamed, node.Lines)
+	}
+	return blamed, true
+}
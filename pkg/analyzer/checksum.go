@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/pathutil"
+)
+
+// checksumFile returns the hex SHA-256 of a file's raw contents
+func checksumFile(path string) (string, error) {
+	file, err := os.Open(pathutil.Long(path))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// ManifestSHA256 returns the SHA-256 of the checksum manifest of all files
+// below the given roots: one "<sha256>  <path>" line per file, sorted by
+// path, as printed by sha256sum. It identifies the exact tree state the
+// digest was generated from, independent of output options.
+func ManifestSHA256(roots ...*FileSystemNode) string {
+	var lines []string
+	for _, root := range roots {
+		for _, file := range root.Files() {
+			if file.SHA256 != "" {
+				lines = append(lines, fmt.Sprintf("%s  %s\n", file.SHA256, file.RelPath(root)))
+			}
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		return lines[i][sha256.Size*2+2:] < lines[j][sha256.Size*2+2:]
+	})
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "")))
+	return hex.EncodeToString(sum[:])
+}
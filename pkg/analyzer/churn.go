@@ -0,0 +1,28 @@
+package analyzer
+
+import (
+	"path/filepath"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// markChurn records on the files below root how many commits changed them
+// since cfg.ChurnSince and how many authors those commits had. History is
+// read relative to the root, so the paths match before any anonymization.
+// Without git the files keep no churn and the summary notes why.
+func markChurn(root *FileSystemNode, cfg *config.Config) {
+	dir := root.Path
+	if !root.IsDir {
+		dir = filepath.Dir(root.Path)
+	}
+	churn, err := cfg.Git().Churn(dir, cfg.ChurnSince)
+	if err != nil {
+		return
+	}
+
+	for _, file := range root.Files() {
+		if c, ok := churn[file.RelPath(root)]; ok {
+			file.Commits, file.Authors = c.Commits, c.Authors
+		}
+	}
+}
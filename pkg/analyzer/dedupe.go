@@ -0,0 +1,35 @@
+package analyzer
+
+import "fmt"
+
+// minDedupeSize is the smallest content worth replacing with a reference;
+// anything shorter costs less than the reference itself
+const minDedupeSize = 64
+
+// deduplicate replaces the content of files identical to an earlier file in
+// tree order with a reference to that file. Files are compared by the
+// SHA-256 of their raw contents on disk, so transformations that make
+// different files read alike, such as --outline or stripped license headers,
+// never pair them; a copy rendered differently from the original, by --full
+// for instance, keeps its own content.
+func deduplicate(root *FileSystemNode) {
+	seen := map[string]*FileSystemNode{}
+
+	for _, file := range root.Files() {
+		if file.SHA256 == "" || len(file.Content) < minDedupeSize {
+			continue
+		}
+
+		original, ok := seen[file.SHA256]
+		if !ok {
+			seen[file.SHA256] = file
+			continue
+		}
+		if file.Content != original.Content {
+			continue
+		}
+
+		file.DuplicateOf = original.RelPath(root)
+		file.Content = fmt.Sprintf("[identical to %s]", file.DuplicateOf)
+	}
+}
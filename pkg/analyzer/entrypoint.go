@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// entrypointPattern recognizes where execution starts in a language
+type entrypointPattern struct {
+	kind    string         // Shown in the tree and summary, such as "func main"
+	pattern *regexp.Regexp // Matches the entry point in the content
+	require *regexp.Regexp // Must also match, if set
+}
+
+// entrypointPatterns maps languages to their entry points
+var entrypointPatterns = map[string]entrypointPattern{
+	"go":     {"func main", regexp.MustCompile(`(?m)^func main\(\)`), regexp.MustCompile(`(?m)^package main\b`)},
+	"python": {"__main__", regexp.MustCompile(`(?m)^if\s+__name__\s*==\s*['"]__main__['"]\s*:`), nil},
+	"rust":   {"fn main", regexp.MustCompile(`(?m)^(?:pub\s+)?(?:async\s+)?fn main\s*\(`), nil},
+	"java":   {"static void main", regexp.MustCompile(`\bpublic\s+static\s+void\s+main\s*\(`), nil},
+	"c":      {"main()", regexp.MustCompile(`(?m)^\s*int\s+main\s*\(`), nil},
+	"cpp":    {"main()", regexp.MustCompile(`(?m)^\s*(?:auto|int)\s+main\s*\(`), nil},
+}
+
+// detectEntrypoint returns how a source file starts execution, or an empty
+// string if it does not. Tests are never entry points.
+func detectEntrypoint(node *FileSystemNode) string {
+	if utils.IsTestFile(node.Path) {
+		return ""
+	}
+	if node.Language == "python" && node.Name == "__main__.py" {
+		return "__main__.py"
+	}
+
+	p, ok := entrypointPatterns[node.Language]
+	if !ok || !p.pattern.MatchString(node.Content) || (p.require != nil && !p.require.MatchString(node.Content)) {
+		return ""
+	}
+	return p.kind
+}
+
+// markPackageBins marks the scripts named by the bin field of every
+// package.json below root as entry points
+func markPackageBins(root *FileSystemNode) {
+	byPath := map[string]*FileSystemNode{}
+	var manifests []*FileSystemNode
+	for _, file := range root.Files() {
+		byPath[file.Path] = file
+		if file.Name == "package.json" && file.hasText {
+			manifests = append(manifests, file)
+		}
+	}
+
+	for _, manifest := range manifests {
+		var pkg struct {
+			Name string          `json:"name"`
+			Bin  json.RawMessage `json:"bin"`
+		}
+		if json.Unmarshal([]byte(manifest.Content), &pkg) != nil || len(pkg.Bin) == 0 {
+			continue
+		}
+
+		// "bin" is a path for a command named after the package, or a map
+		// of command names to paths
+		bins := map[string]string{}
+		var single string
+		if json.Unmarshal(pkg.Bin, &single) == nil {
+			bins[pkg.Name[strings.LastIndex(pkg.Name, "/")+1:]] = single
+		} else if json.Unmarshal(pkg.Bin, &bins) != nil {
+			continue
+		}
+
+		commands := make([]string, 0, len(bins))
+		for command := range bins {
+			commands = append(commands, command)
+		}
+		sort.Strings(commands)
+		for _, command := range commands {
+			target := filepath.Join(filepath.Dir(manifest.Path), filepath.FromSlash(bins[command]))
+			if file := byPath[target]; file != nil && file.Entrypoint == "" {
+				file.Entrypoint = "bin " + command
+			}
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// Decision explains whether analyzing a source would include a path
+type Decision struct {
+	Included bool   // Whether the path would be included
+	Path     string // Path the decision is about: the checked path or a directory above it
+	Reason   string // Pattern or limit that decides, such as "exclude pattern '*.log'"
+}
+
+// Explain reports whether ProcessPath on root would include path, and which
+// pattern or limit decides. It applies the checks of the traversal in the
+// same order to path and to each directory above it, so an excluded parent
+// is reported as such. Limits that depend on what the traversal collected
+// before the path, --max-files and the running total size, are not
+// considered.
+func Explain(root, path string, cfg *config.Config) (Decision, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return Decision{}, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// Anchored patterns are relative to the analyzed path
+	cfg.SetRoot(absRoot)
+
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return Decision{}, fmt.Errorf("outside the source '%s'", root)
+	}
+	if rel == "." {
+		return Decision{Included: true, Path: absPath}, nil
+	}
+
+	// Mount points are found by a change of device, as in processDirectory
+	var device uint64
+	if info, err := os.Stat(pathutil.Long(absRoot)); err == nil && cfg.OneFileSystem {
+		device, _ = pathutil.Device(absRoot, info)
+	}
+
+	// Walk down from the root like processDirectory
+	current := absRoot
+	parts := strings.Split(rel, string(filepath.Separator))
+	for depth, part := range parts {
+		current = filepath.Join(current, part)
+		if depth >= cfg.MaxDirDepth {
+			return Decision{Path: current, Reason: fmt.Sprintf("--max-depth %d", cfg.MaxDirDepth)}, nil
+		}
+
+		isDir := depth < len(parts)-1 || config.DirExists(current)
+		if reason := excludedReason(current, isDir, cfg); reason != "" {
+			return Decision{Path: current, Reason: reason}, nil
+		}
+		if info, err := os.Lstat(pathutil.Long(current)); err == nil && isDir && device != 0 {
+			if d, ok := pathutil.Device(current, info); ok && d != device {
+				return Decision{Path: current, Reason: "--one-file-system (mount point)"}, nil
+			}
+		}
+	}
+
+	// The limits applied to files while reading them
+	info, err := os.Stat(pathutil.Long(absPath))
+	if err == nil && !info.IsDir() {
+		if info.Size() > cfg.MaxFileSize {
+			return Decision{Path: absPath, Reason: fmt.Sprintf("-s %d (file is %s)", cfg.MaxFileSize, utils.FormatSize(info.Size()))}, nil
+		}
+		if info.Size() > cfg.MaxTotalSize {
+			return Decision{Path: absPath, Reason: fmt.Sprintf("--max-total-size %d (file is %s)", cfg.MaxTotalSize, utils.FormatSize(info.Size()))}, nil
+		}
+
+		node := NewFileSystemNode(absPath, info, len(parts))
+		node.MIME, node.IsBinary = sniffFile(absPath)
+		if omitBinary(node, cfg) {
+			return Decision{Path: absPath, Reason: "--binary skip"}, nil
+		}
+	}
+
+	return Decision{Included: true, Path: absPath, Reason: includedReason(absPath, cfg)}, nil
+}
+
+// excludedReason names the check for which processDirectory would skip an
+// entry, or returns "" if it would be kept
+func excludedReason(path string, isDir bool, cfg *config.Config) string {
+	if !cfg.Selected(path) {
+		return "--go-package or --js-entry selection"
+	}
+	if _, included := cfg.IncludedBy(path); !included {
+		return fmt.Sprintf("include patterns (%s)", strings.Join(cfg.IncludePatterns, ","))
+	}
+	if cfg.SkipsHidden(path) {
+		return "--no-hidden"
+	}
+	if pattern, excluded := cfg.ExcludedBy(path); excluded {
+		return "exclude pattern " + describePattern(pattern)
+	}
+	if cfg.IsGitIgnored(path) {
+		return "git ignore rules"
+	}
+	if (!isDir || cfg.Tests == config.TestsExclude) && !cfg.SelectsTests(utils.IsTestFile(path)) {
+		if cfg.Tests == config.TestsOnly {
+			return "--tests only"
+		}
+		return "--tests exclude"
+	}
+	if !isDir && cfg.IsDigestOutput(path) {
+		return "digest detection (output file or earlier digest)"
+	}
+	if pathutil.IsReservedName(filepath.Base(path)) {
+		return "reserved device name check"
+	}
+	return ""
+}
+
+// includedReason names the pattern that keeps an included path, or "" if
+// no pattern applies
+func includedReason(path string, cfg *config.Config) string {
+	if pattern, _ := cfg.ExcludedBy(path); pattern != "" {
+		return "exclude pattern " + describePattern(pattern)
+	}
+	if pattern, _ := cfg.IncludedBy(path); pattern != "" {
+		return fmt.Sprintf("include pattern '%s'", pattern)
+	}
+	return ""
+}
+
+// describePattern quotes an exclude pattern, marking the default ones
+func describePattern(pattern string) string {
+	if config.IsDefaultExclude(pattern) {
+		return fmt.Sprintf("'%s' (default)", pattern)
+	}
+	return fmt.Sprintf("'%s'", pattern)
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Thresholds for detecting minified files by their line lengths
+const (
+	minifiedMinSize       = 2048
+	minifiedAvgLineLength = 500
+)
+
+// generatedNamePatterns match file names produced by code generators and minifiers
+var generatedNamePatterns = []string{
+	"*.min.js", "*.min.css", "*.min.mjs",
+	"*_pb.go", "*.pb.go", "*.pb.gw.go", "*_pb2.py", "*_pb2_grpc.py", "*.pb.cc", "*.pb.h",
+	"*_generated.go", "*.gen.go", "*.generated.ts",
+	"*.designer.cs", "*.g.dart", "*.freezed.dart",
+}
+
+// generatedHeaderPattern matches the Go convention for generated files
+// (https://go.dev/s/generatedcode) and the widespread @generated marker
+var generatedHeaderPattern = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$|@generated\b`)
+
+// generatedHeaderLines is how many leading lines are searched for generator markers
+const generatedHeaderLines = 10
+
+// detectGenerated returns why a file looks generated or minified, or an
+// empty string if it looks hand-written
+func detectGenerated(name, content string) string {
+	for _, pattern := range generatedNamePatterns {
+		if matched, _ := filepath.Match(pattern, strings.ToLower(name)); matched {
+			return "matches " + pattern
+		}
+	}
+
+	// Only the top of the file carries generator markers
+	head := content
+	for i, n := 0, 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			if n++; n == generatedHeaderLines {
+				head = content[:i]
+				break
+			}
+		}
+	}
+	if generatedHeaderPattern.MatchString(head) {
+		return "generated code marker"
+	}
+
+	// Minified files pack everything into a few very long lines
+	if len(content) >= minifiedMinSize {
+		lines := strings.Count(content, "\n") + 1
+		if len(content)/lines > minifiedAvgLineLength {
+			return "minified"
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// renderedManifestsName names the file replacing the templates of a chart
+// with the manifests rendered from them
+const renderedManifestsName = "manifests.rendered.yaml"
+
+// helmRelease is the release name charts are rendered with
+const helmRelease = "release"
+
+// renderHelmChart replaces the templates directory of a chart selected with
+// --helm-render, once its files are processed, with the manifests that
+// helm template renders from the chart and the selected values files, so
+// the digest shows what is deployed. A chart that fails to render keeps its
+// templates, and the failure is recorded.
+func renderHelmChart(node *FileSystemNode, cfg *config.Config, stats *config.Stats) {
+	if !cfg.IsHelmChart(node.Path) {
+		return
+	}
+
+	var chart, templates *FileSystemNode
+	for _, child := range node.Children {
+		switch {
+		case !child.IsDir && child.Name == "Chart.yaml":
+			chart = child
+		case child.IsDir && child.Name == "templates":
+			templates = child
+		}
+	}
+	if chart == nil {
+		stats.Errors = append(stats.Errors, config.PathError{Path: node.Path, Err: fmt.Errorf("not a Helm chart: no Chart.yaml")})
+		return
+	}
+
+	manifests, err := helmTemplate(node.Path, cfg.HelmValues)
+	if err != nil {
+		stats.Errors = append(stats.Errors, config.PathError{Path: node.Path, Err: err})
+		return
+	}
+
+	// Drop the templates, which the manifests stand for
+	rendered := 0
+	if templates != nil {
+		rendered = templates.FileCount
+		children := node.Children[:0]
+		for _, child := range node.Children {
+			if child != templates {
+				children = append(children, child)
+			}
+		}
+		node.Children = children
+		node.FileCount -= templates.FileCount
+		node.DirCount -= templates.DirCount + 1
+		node.Size -= templates.Size
+		stats.TotalFiles -= templates.FileCount
+		stats.TotalSize -= templates.Size
+		stats.HelmTemplates += templates.FileCount
+	}
+
+	noun := "templates"
+	if rendered == 1 {
+		noun = "template"
+	}
+	content := fmt.Sprintf("# Manifests rendered by helm template from %d %s", rendered, noun)
+	if len(cfg.HelmValues) > 0 {
+		content += " with " + strings.Join(cfg.HelmValues, ", ")
+	}
+	content += "\n" + manifests
+
+	sum := sha256.Sum256([]byte(content))
+	file := &FileSystemNode{
+		Name:     renderedManifestsName,
+		Path:     filepath.Join(node.Path, renderedManifestsName),
+		Size:     int64(len(content)),
+		Depth:    node.Depth + 1,
+		Content:  content,
+		SHA256:   hex.EncodeToString(sum[:]),
+		Language: "yaml",
+		MIME:     "text/plain; charset=utf-8",
+		ModTime:  chart.ModTime,
+		Mode:     chart.Mode,
+		hasText:  true,
+	}
+	node.Children = append(node.Children, file)
+	node.FileCount++
+	node.Size += file.Size
+	stats.TotalFiles++
+	stats.TotalSize += file.Size
+}
+
+// helmTemplate renders a chart with helm template, including its custom
+// resource definitions. Relative values files are resolved against the
+// chart directory.
+func helmTemplate(dir string, values []string) (string, error) {
+	args := []string{"template", helmRelease, dir, "--include-crds"}
+	for _, file := range values {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, filepath.FromSlash(file))
+		}
+		args = append(args, "--values", file)
+	}
+
+	var stdout, stderr bytes.Buffer
+	slog.Debug("Running helm", "args", strings.Join(args, " "))
+	cmd := exec.Command("helm", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("helm template: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
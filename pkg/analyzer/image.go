@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF for image.DecodeConfig
+	_ "image/jpeg" // Register JPEG for image.DecodeConfig
+	_ "image/png"  // Register PNG for image.DecodeConfig
+	"os"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// imageHeaderBytes is the number of leading bytes read to find the
+// dimensions of formats the standard library cannot decode
+const imageHeaderBytes = 64
+
+// describesImage reports whether a binary file is an image described by its
+// metadata instead of the binary policy
+func describesImage(node *FileSystemNode, cfg *config.Config) bool {
+	return cfg.ImageMetadata && node.IsBinary && strings.HasPrefix(node.MIME, "image/")
+}
+
+// imageMetadata returns a one-line descriptor of an image read from path
+// with its format, dimensions when they can be read, and size
+func imageMetadata(node *FileSystemNode, path string) string {
+	format := strings.ToUpper(strings.TrimPrefix(node.MIME, "image/"))
+	format = strings.TrimPrefix(format, "X-")
+
+	width, height, ok := imageDimensions(path)
+	if !ok {
+		return fmt.Sprintf("[Image: %s, %s]", format, utils.FormatSize(node.Size))
+	}
+	return fmt.Sprintf("[Image: %s, %dx%d, %s]", format, width, height, utils.FormatSize(node.Size))
+}
+
+// imageDimensions reads the width and height of a PNG, JPEG, GIF, BMP, or
+// WebP image
+func imageDimensions(path string) (int, int, bool) {
+	file, err := os.Open(pathutil.Long(path))
+	if err != nil {
+		return 0, 0, false
+	}
+	defer file.Close()
+
+	if cfg, _, err := image.DecodeConfig(file); err == nil {
+		return cfg.Width, cfg.Height, true
+	}
+
+	head, err := readHead(path, imageHeaderBytes)
+	if err != nil {
+		return 0, 0, false
+	}
+	return headerDimensions(head)
+}
+
+// headerDimensions parses the dimensions of BMP and WebP images from their
+// headers
+func headerDimensions(head []byte) (int, int, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte("BM")) && len(head) >= 26:
+		width := int32(binary.LittleEndian.Uint32(head[18:22]))
+		height := int32(binary.LittleEndian.Uint32(head[22:26]))
+		if height < 0 {
+			height = -height // Top-down bitmaps have a negative height
+		}
+		return int(width), int(height), true
+
+	case bytes.HasPrefix(head, []byte("RIFF")) && len(head) >= 30 && string(head[8:12]) == "WEBP":
+		chunk := head[12:16]
+		switch string(chunk) {
+		case "VP8 ":
+			return int(binary.LittleEndian.Uint16(head[26:28]) & 0x3fff), int(binary.LittleEndian.Uint16(head[28:30]) & 0x3fff), true
+		case "VP8L":
+			bits := binary.LittleEndian.Uint32(head[21:25])
+			return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+		case "VP8X":
+			width := int(head[24]) | int(head[25])<<8 | int(head[26])<<16
+			height := int(head[27]) | int(head[28])<<8 | int(head[29])<<16
+			return width + 1, height + 1, true
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/pathutil"
+)
+
+// lfsPointerMaxSize bounds the size of files checked for Git LFS pointers;
+// pointers are about 130 bytes, well under the 1024 bytes the spec allows
+const lfsPointerMaxSize = 1024
+
+// lfsVersionLine starts every Git LFS pointer file
+const lfsVersionLine = "version https://git-lfs.github.com/spec/v1\n"
+
+// lfsPointer describes a Git LFS pointer file
+type lfsPointer struct {
+	OID  string // Object ID, such as sha256:4d7a...
+	Size int64  // Size of the object in bytes
+}
+
+// readLFSPointer reports whether a file is a Git LFS pointer and parses it
+func readLFSPointer(node *FileSystemNode) (lfsPointer, bool) {
+	if node.Size > lfsPointerMaxSize {
+		return lfsPointer{}, false
+	}
+	head, err := readHead(node.Path, lfsPointerMaxSize)
+	if err != nil || !bytes.HasPrefix(head, []byte(lfsVersionLine)) {
+		return lfsPointer{}, false
+	}
+
+	var pointer lfsPointer
+	for _, line := range strings.Split(string(head), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "oid":
+			pointer.OID = value
+		case "size":
+			pointer.Size, _ = strconv.ParseInt(value, 10, 64)
+		}
+	}
+	return pointer, pointer.OID != ""
+}
+
+// fetchLFSObject smudges the LFS pointer at path into a temporary file
+// with git-lfs, downloading the object from the repository's LFS server if
+// it is not cached, and returns the temporary file's path
+func fetchLFSObject(path string) (string, error) {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return "", fmt.Errorf("--lfs requires git-lfs: %w", err)
+	}
+
+	pointer, err := os.Open(pathutil.Long(path))
+	if err != nil {
+		return "", err
+	}
+	defer pointer.Close()
+
+	out, err := os.CreateTemp("", "ingest-lfs-")
+	if err != nil {
+		return "", err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "lfs", "smudge", "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	cmd.Stdin = pointer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("git lfs smudge: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return out.Name(), nil
+}
@@ -0,0 +1,54 @@
+package analyzer
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/license"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/transform"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// stripLicenseHeaders removes the license header comments of the text files
+// below root, comparing them with the LICENSE file at the top of root
+func stripLicenseHeaders(root *FileSystemNode, stats *config.Stats) {
+	words := transform.LicenseWords(topLevelLicense(root))
+	for _, file := range root.Files() {
+		if !file.hasText || license.IsLicenseFile(file.Name) {
+			continue
+		}
+
+		content, ok := transform.StripLicenseHeader(file.Language, file.Content, words)
+		if !ok {
+			continue
+		}
+		slog.Debug("Stripped license header", "path", file.RelPath(root))
+		stats.LicenseHeaders++
+		stats.LicenseTokens += utils.EstimateTokens(file.Content) - utils.EstimateTokens(content)
+		file.Content = content
+	}
+}
+
+// topLevelLicense returns the text of the license file directly in root,
+// read from disk so that it counts even when excluded, or an empty string
+func topLevelLicense(root *FileSystemNode) string {
+	if !root.IsDir {
+		return ""
+	}
+	entries, err := os.ReadDir(pathutil.Long(root.Path))
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !license.IsLicenseFile(entry.Name()) || license.IsNoticeFile(entry.Name()) {
+			continue
+		}
+		if data, err := os.ReadFile(pathutil.Long(filepath.Join(root.Path, entry.Name()))); err == nil {
+			return string(data)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// selectLines keeps the given ranges of lines of content, in order, with a
+// marker such as "[... lines 81-119 ...]" where lines between them are left
+// out. Ranges past the end of the content are cut short.
+func selectLines(content string, ranges []config.LineRange) string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var builder strings.Builder
+	next := 1
+	for i, r := range ranges {
+		end := r.End
+		if end == 0 || end > len(lines) {
+			end = len(lines)
+		}
+		if r.Start > end {
+			continue
+		}
+		if i > 0 && r.Start > next {
+			builder.WriteString(gapMarker(lines[next-1:r.Start-1], next))
+		}
+		builder.WriteString(strings.Join(lines[r.Start-1:end], ""))
+		next = end + 1
+	}
+	return builder.String()
+}
+
+// gapMarker returns the marker for the lines left out between two ranges,
+// starting at line first, or the lines themselves if they are all blank
+func gapMarker(gap []string, first int) string {
+	if strings.TrimSpace(strings.Join(gap, "")) == "" {
+		return strings.Join(gap, "")
+	}
+	if len(gap) == 1 {
+		return fmt.Sprintf("[... line %d ...]\n", first)
+	}
+	return fmt.Sprintf("[... lines %d-%d ...]\n", first, first+len(gap)-1)
+}
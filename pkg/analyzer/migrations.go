@@ -0,0 +1,112 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/migrations"
+)
+
+// inferredSchemaName names the file replacing SQL migrations with their schema
+const inferredSchemaName = "schema.inferred.sql"
+
+// summarizeMigrations shortens a migration directory once its files are
+// processed: the latest policy keeps the newest migrations, and the schema
+// policy replaces SQL migrations with the schema they build. Migrations that
+// are not SQL (Django, Rails) fall back to the latest policy.
+func summarizeMigrations(node *FileSystemNode, cfg *config.Config, stats *config.Stats) {
+	if cfg.Migrations == config.MigrationsAll {
+		return
+	}
+
+	files := map[string]*FileSystemNode{}
+	var names []string
+	for _, child := range node.Children {
+		if !child.IsDir {
+			files[child.Name] = child
+			names = append(names, child.Name)
+		}
+	}
+
+	kind, ordered := migrations.Detect(node.Name, names)
+	if kind == "" {
+		return
+	}
+	isMigration := make(map[string]bool, len(ordered))
+	for _, file := range ordered {
+		isMigration[file.Name] = true
+	}
+
+	keep := migrations.Latest(ordered, cfg.LatestMigrations)
+	var schema *FileSystemNode
+	if cfg.Migrations == config.MigrationsSchema && migrations.IsSQL(kind) {
+		keep = map[string]bool{}
+		schema = inferSchema(node, kind, ordered, files)
+	}
+
+	// Drop the migrations that are not kept
+	children := node.Children[:0]
+	for _, child := range node.Children {
+		if !child.IsDir && isMigration[child.Name] && !keep[child.Name] {
+			node.FileCount--
+			node.Size -= child.Size
+			stats.TotalFiles--
+			stats.TotalSize -= child.Size
+			stats.OmittedMigrations++
+			continue
+		}
+		children = append(children, child)
+	}
+	node.Children = children
+
+	if schema != nil {
+		node.Children = append(node.Children, schema)
+		node.FileCount++
+		node.Size += schema.Size
+		stats.TotalFiles++
+		stats.TotalSize += schema.Size
+	}
+}
+
+// inferSchema builds a file holding the schema that the up migrations of a
+// SQL migration directory create
+func inferSchema(node *FileSystemNode, kind string, ordered []migrations.File, files map[string]*FileSystemNode) *FileSystemNode {
+	var scripts []string
+	var latest *FileSystemNode
+	for _, file := range ordered {
+		if file.Down {
+			continue
+		}
+		child := files[file.Name]
+		scripts = append(scripts, child.Content)
+		if !file.Repeatable {
+			latest = child
+		}
+	}
+
+	content := fmt.Sprintf("-- Schema inferred from %d %s migrations", len(scripts), kind)
+	if latest != nil {
+		content += fmt.Sprintf(" (latest: %s)", latest.Name)
+	}
+	content += "\n\n" + migrations.InferSchema(scripts)
+
+	sum := sha256.Sum256([]byte(content))
+	schema := &FileSystemNode{
+		Name:     inferredSchemaName,
+		Path:     filepath.Join(node.Path, inferredSchemaName),
+		Size:     int64(len(content)),
+		Depth:    node.Depth + 1,
+		Content:  content,
+		SHA256:   hex.EncodeToString(sum[:]),
+		Language: "sql",
+		MIME:     "text/plain; charset=utf-8",
+		hasText:  true,
+	}
+	if latest != nil {
+		schema.ModTime, schema.Mode = latest.ModTime, latest.Mode
+	}
+	return schema
+}
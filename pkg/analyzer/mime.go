@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffBytes is the number of leading bytes inspected for MIME detection
+const sniffBytes = 512
+
+// magicNumber maps a signature at the start of a file to its MIME type
+type magicNumber struct {
+	prefix []byte
+	mime   string
+}
+
+// magicNumbers lists signatures that net/http's sniffer does not recognize
+// or reports only as application/octet-stream
+var magicNumbers = []magicNumber{
+	{[]byte("SQLite format 3\x00"), "application/vnd.sqlite3"},
+	{[]byte("\x00asm"), "application/wasm"},
+	{[]byte("\x7fELF"), "application/x-elf"},
+	{[]byte("\xfe\xed\xfa\xce"), "application/x-mach-binary"},
+	{[]byte("\xfe\xed\xfa\xcf"), "application/x-mach-binary"},
+	{[]byte("\xce\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xcf\xfa\xed\xfe"), "application/x-mach-binary"},
+	{[]byte("\xca\xfe\xba\xbe"), "application/java-vm"},
+	{[]byte("!<arch>\n"), "application/x-archive"},
+	{[]byte("\x28\xb5\x2f\xfd"), "application/zstd"},
+	{[]byte("BZh"), "application/x-bzip2"},
+	{[]byte("\xfd7zXZ\x00"), "application/x-xz"},
+	{[]byte("PAR1"), "application/vnd.apache.parquet"},
+	{[]byte("\x89HDF\r\n\x1a\n"), "application/x-hdf5"},
+}
+
+// protobufExts are extensions of serialized protocol buffers, such as
+// compiled descriptor sets, which carry no signature
+var protobufExts = map[string]bool{".pb": true, ".binpb": true, ".desc": true, ".protoset": true}
+
+// textMIMETypes are non-text/* MIME types whose content is text
+var textMIMETypes = map[string]bool{
+	"application/json":       true,
+	"application/xml":        true,
+	"application/javascript": true,
+	"image/svg+xml":          true,
+}
+
+// detectMIME returns the MIME type of a file from its name and leading
+// bytes. Content wins over the extension, so extensionless scripts and
+// text .dat files are recognized as text.
+func detectMIME(name string, head []byte) string {
+	for _, magic := range magicNumbers {
+		if bytes.HasPrefix(head, magic.prefix) {
+			return magic.mime
+		}
+	}
+
+	// SVG documents are XML text even when a long prolog or comment keeps
+	// the <svg> element out of the sniffed bytes
+	if strings.EqualFold(filepath.Ext(name), ".svg") && !bytes.Contains(head, []byte{0}) {
+		return "image/svg+xml"
+	}
+
+	// Scripts are text even when they hold control characters, as long as
+	// the shebang marks them as such and no NUL byte follows
+	if bytes.HasPrefix(head, []byte("#!")) && !bytes.Contains(head, []byte{0}) {
+		return "text/plain"
+	}
+
+	detected := http.DetectContentType(head)
+	mediaType, _, err := mime.ParseMediaType(detected)
+	if err != nil {
+		mediaType = detected
+	}
+
+	switch {
+	case mediaType == "application/octet-stream" && protobufExts[strings.ToLower(filepath.Ext(name))]:
+		return "application/x-protobuf"
+	case isTextMIME(mediaType) && isSVG(head):
+		return "image/svg+xml"
+	}
+	return mediaType
+}
+
+// isSVG reports whether text content is an SVG document
+func isSVG(head []byte) bool {
+	trimmed := bytes.TrimSpace(head)
+	if !bytes.HasPrefix(trimmed, []byte("<?xml")) && !bytes.HasPrefix(trimmed, []byte("<svg")) &&
+		!bytes.HasPrefix(trimmed, []byte("<!DOCTYPE svg")) && !bytes.HasPrefix(trimmed, []byte("<!--")) {
+		return false
+	}
+	return bytes.Contains(head, []byte("<svg"))
+}
+
+// isTextMIME reports whether files of the MIME type hold text
+func isTextMIME(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") || textMIMETypes[mediaType]
+}
@@ -0,0 +1,92 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/agris/ingest-clone/pkg/codeowners"
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// Author is a main author of a file by git blame
+type Author struct {
+	Name  string // Author name
+	Email string // Author email
+	Share int    // Percentage of the committed lines last changed by the author
+}
+
+// Limits of the authors listed for a file: the authors of at least
+// minAuthorShare percent of its lines, at most maxAuthors of them, and
+// always the first
+const (
+	maxAuthors     = 3
+	minAuthorShare = 10
+)
+
+// markOwners records on the files below root their owners from the
+// CODEOWNERS file of the repository and their main authors by git blame.
+// Without git, owners come from a CODEOWNERS file at the root and authors
+// are left out.
+func markOwners(root *FileSystemNode, cfg *config.Config) {
+	base := root.Path
+	if !root.IsDir {
+		base = filepath.Dir(root.Path)
+	}
+	if repoRoot, _, err := cfg.Git().Origin(); err == nil {
+		base = repoRoot
+	}
+
+	_, rules, _ := codeowners.Load(base)
+	blame := cfg.Git().Available()
+	for _, file := range root.Files() {
+		if rel, err := filepath.Rel(base, file.Path); err == nil && len(rules) > 0 {
+			file.Owners = codeowners.Owners(rules, filepath.ToSlash(rel))
+		}
+		if blame && !file.IsBinary {
+			file.TopAuthors = topAuthors(file, cfg)
+		}
+	}
+}
+
+// topAuthors returns the main authors of the committed lines of a file
+func topAuthors(file *FileSystemNode, cfg *config.Config) []Author {
+	lines, err := cfg.Git().Blame(file.Path)
+	if err != nil {
+		return nil
+	}
+
+	counts := map[string]int{}
+	names := map[string]string{}
+	total := 0
+	for _, line := range lines {
+		if !line.Committed() {
+			continue
+		}
+		counts[line.Email]++
+		if names[line.Email] == "" {
+			names[line.Email] = line.Author
+		}
+		total++
+	}
+
+	emails := make([]string, 0, len(counts))
+	for email := range counts {
+		emails = append(emails, email)
+	}
+	sort.Slice(emails, func(i, j int) bool {
+		if counts[emails[i]] != counts[emails[j]] {
+			return counts[emails[i]] > counts[emails[j]]
+		}
+		return emails[i] < emails[j]
+	})
+
+	var authors []Author
+	for i, email := range emails {
+		share := counts[email] * 100 / total
+		if i >= maxAuthors || i > 0 && share < minAuthorShare {
+			break
+		}
+		authors = append(authors, Author{Name: names[email], Email: email, Share: share})
+	}
+	return authors
+}
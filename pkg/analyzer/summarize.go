@@ -0,0 +1,60 @@
+package analyzer
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// Summarize replaces the content of every text file below root with the
+// summary returned by summarize, which receives the file's path relative to
+// root. Files matching the --full patterns, placeholders, binary files, and
+// duplicates are left as they are, and the first error stops the pass.
+func Summarize(root *FileSystemNode, cfg *config.Config, model string, summarize func(path, content string) (string, error)) error {
+	for _, file := range root.Files() {
+		if !file.hasText || file.DuplicateOf != "" || strings.TrimSpace(file.Content) == "" {
+			continue
+		}
+
+		path := file.RelPath(root)
+		if len(cfg.FullPatterns) > 0 && cfg.IsFull(path) {
+			continue
+		}
+		slog.Debug("Summarizing file", "path", path)
+		summary, err := summarize(path, file.Content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		file.Content = fmt.Sprintf("[Summary of %d lines by %s]\n%s\n", countLines(file.Content), model, summary)
+		if root.Stats != nil {
+			root.Stats.SummarizedFiles++
+		}
+	}
+	return nil
+}
+
+// summarizeRest reduces the text files below root that match no --full
+// pattern to placeholders, so they appear only in the tree and statistics
+func summarizeRest(root *FileSystemNode, cfg *config.Config, stats *config.Stats) {
+	for _, file := range root.Files() {
+		if !file.hasText || cfg.IsFull(file.RelPath(root)) {
+			continue
+		}
+
+		file.Content = fmt.Sprintf("[Content omitted (--summary-rest): %d lines, %s]", countLines(file.Content), utils.FormatSize(file.Size))
+		file.hasText = false
+		stats.SummaryOnlyFiles++
+	}
+}
+
+// countLines counts the lines of content, including a last unterminated one
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
@@ -0,0 +1,134 @@
+// Package anonymize replaces identifying names in digests with stable
+// placeholders, so the structure of proprietary code can be shared without
+// revealing who wrote it or where it runs.
+package anonymize
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Placeholders of the repository and its owner
+const (
+	ProjectPlaceholder = "project"
+	OwnerPlaceholder   = "org"
+)
+
+// homeDir matches a home directory with its user name: /home/NAME,
+// /Users/NAME, or C:\Users\NAME
+var homeDir = regexp.MustCompile(`(/home/|/Users/|\\Users\\)([^/\\\s"'` + "`" + `:;,)]+)`)
+
+// sharedHomes are directories below /Users and C:\Users that belong to no one
+var sharedHomes = map[string]bool{"Shared": true, "Public": true, "Default": true, "All Users": true}
+
+// internalHost matches host names under domains that only resolve inside
+// private networks
+var internalHost = regexp.MustCompile(`(?i)\b(?:[a-z0-9](?:[a-z0-9-]*[a-z0-9])?\.)+(?:internal|local|localdomain|corp|lan|intranet|home\.arpa)\b`)
+
+// email matches an email address
+var email = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`)
+
+// Anonymizer replaces identifying names with placeholders. The same name
+// always gets the same placeholder, numbered in the order names are first
+// seen, so a digest stays consistent and identical runs give identical
+// output.
+type Anonymizer struct {
+	names   *regexp.Regexp    // Repository and owner names, nil if none
+	literal map[string]string // Placeholders of the repository and owner names
+
+	users  map[string]string
+	hosts  map[string]string
+	emails map[string]string
+}
+
+// New returns an Anonymizer for a repository named repo whose remote URL,
+// if not empty, also names its owner, as in github.com/OWNER/REPO
+func New(repo, remote string) *Anonymizer {
+	a := &Anonymizer{
+		literal: map[string]string{},
+		users:   map[string]string{},
+		hosts:   map[string]string{},
+		emails:  map[string]string{},
+	}
+
+	if owner, name := remoteOwner(remote); owner != "" {
+		a.literal[owner] = OwnerPlaceholder
+		if repo == "" {
+			repo = name
+		}
+	}
+	if repo != "" {
+		a.literal[repo] = ProjectPlaceholder
+	}
+
+	// Short names such as "go" would replace ordinary words
+	var alternatives []string
+	for name := range a.literal {
+		if len(name) < 3 {
+			delete(a.literal, name)
+			continue
+		}
+		alternatives = append(alternatives, regexp.QuoteMeta(name))
+	}
+	if len(alternatives) > 0 {
+		// Longer names first, so a repository named after its owner with a
+		// suffix is replaced whole
+		sort.Slice(alternatives, func(i, j int) bool { return len(alternatives[i]) > len(alternatives[j]) })
+		a.names = regexp.MustCompile(`\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	}
+
+	return a
+}
+
+// String returns s with email addresses, user names in home directories,
+// internal host names, and the repository and owner names replaced
+func (a *Anonymizer) String(s string) string {
+	s = email.ReplaceAllStringFunc(s, func(match string) string {
+		// SSH remotes such as git@github.com:owner/repo are not addresses
+		if strings.HasPrefix(match, "git@") {
+			return match
+		}
+		return placeholder(a.emails, strings.ToLower(match), "person%d@example.com")
+	})
+	s = homeDir.ReplaceAllStringFunc(s, func(match string) string {
+		parts := homeDir.FindStringSubmatch(match)
+		if sharedHomes[parts[2]] {
+			return match
+		}
+		return parts[1] + placeholder(a.users, parts[2], "user%d")
+	})
+	s = internalHost.ReplaceAllStringFunc(s, func(match string) string {
+		return placeholder(a.hosts, strings.ToLower(match), "host%d.internal")
+	})
+	if a.names != nil {
+		s = a.names.ReplaceAllStringFunc(s, func(match string) string { return a.literal[match] })
+	}
+	return s
+}
+
+// placeholder returns the placeholder of value, numbering a new one with
+// format if value was not seen before
+func placeholder(seen map[string]string, value, format string) string {
+	if p, ok := seen[value]; ok {
+		return p
+	}
+	p := fmt.Sprintf(format, len(seen)+1)
+	seen[value] = p
+	return p
+}
+
+// remoteOwner returns the owner and repository name of a remote URL such as
+// https://github.com/OWNER/REPO.git or git@github.com:OWNER/REPO
+func remoteOwner(remote string) (owner, repo string) {
+	remote = strings.TrimSuffix(strings.TrimRight(remote, "/"), ".git")
+	if _, rest, ok := strings.Cut(remote, "://"); ok {
+		remote = rest
+	}
+	parts := strings.FieldsFunc(remote, func(r rune) bool { return r == '/' || r == ':' })
+	if len(parts) < 3 {
+		return "", ""
+	}
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
@@ -0,0 +1,148 @@
+// Package codeowners reads CODEOWNERS files, which assign the users and
+// teams responsible for the paths of a repository.
+package codeowners
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// Locations are where CODEOWNERS files are looked up, relative to the
+// repository root, in the order GitHub uses them
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule assigns owners to the paths matching a pattern. A rule without
+// owners leaves its paths unowned.
+type Rule struct {
+	Pattern string
+	Owners  []string
+}
+
+// Load reads the first CODEOWNERS file found below the repository root dir.
+// It returns the path of the file, or an empty path and no rules if there
+// is none.
+func Load(dir string) (string, []Rule, error) {
+	for _, location := range Locations {
+		path := filepath.Join(dir, filepath.FromSlash(location))
+		content, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return path, Parse(string(content)), nil
+	}
+	return "", nil, nil
+}
+
+// Parse parses the rules of a CODEOWNERS file in order, skipping blank
+// lines and comments
+func Parse(content string) []Rule {
+	var rules []Rule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, Rule{Pattern: strings.ReplaceAll(fields[0], `\#`, "#"), Owners: fields[1:]})
+	}
+	return rules
+}
+
+// Owners returns the owners of the slash-separated path rel, relative to the
+// repository root: those of the last rule matching it
+func Owners(rules []Rule, rel string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if Match(rules[i].Pattern, rel) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// Match reports whether a CODEOWNERS pattern matches the slash-separated
+// file path rel. As in .gitignore, a pattern starting with or containing a
+// slash is relative to the repository root, and one without matches at any
+// depth; a pattern matching a directory matches everything below it, except
+// that a trailing "/*" matches only the files directly inside.
+func Match(pattern, rel string) bool {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	if !anchored {
+		pattern = "**/" + pattern
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return config.MatchPathPattern(pattern, rel)
+	}
+	if !dirOnly && config.MatchPathPattern(pattern, rel) {
+		return true
+	}
+	return config.MatchPathPattern(pattern+"/*/**", rel)
+}
+
+// Files returns the absolute paths of the files below dir owned by any of
+// owners according to the CODEOWNERS file of the repository at root. Owners
+// are compared case-insensitively, and a name without "@" stands for the
+// user or team handle, so "acme/backend" matches "@acme/backend". The .git
+// directory is skipped.
+func Files(root, dir string, owners []string) ([]string, error) {
+	path, rules, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no CODEOWNERS file in %s", strings.Join(Locations, ", "))
+	}
+
+	wanted := map[string]bool{}
+	for _, owner := range owners {
+		if !strings.Contains(owner, "@") {
+			owner = "@" + owner
+		}
+		wanted[strings.ToLower(owner)] = true
+	}
+
+	var files []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		for _, owner := range Owners(rules, filepath.ToSlash(rel)) {
+			if wanted[strings.ToLower(owner)] {
+				abs, err := filepath.Abs(path)
+				if err != nil {
+					return err
+				}
+				files = append(files, abs)
+				break
+			}
+		}
+		return nil
+	})
+	return files, err
+}
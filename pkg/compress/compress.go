@@ -0,0 +1,177 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Supported compression methods
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// extensions maps compression methods to the suffix appended to output files
+var extensions = map[string]string{
+	Gzip: ".gz",
+	Zstd: ".zst",
+}
+
+// Magic numbers identifying compressed streams
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Valid reports whether method is a supported compression method
+func Valid(method string) bool {
+	_, ok := extensions[method]
+	return ok
+}
+
+// OutputPath returns path with the method's extension appended unless it
+// already ends with it
+func OutputPath(path, method string) string {
+	ext := extensions[method]
+	if strings.HasSuffix(path, ext) {
+		return path
+	}
+	return path + ext
+}
+
+// NewWriter returns a writer compressing into w. Closing it flushes all
+// compressed data but does not close w. zstd compression requires the zstd
+// command-line tool.
+func NewWriter(w io.Writer, method string) (io.WriteCloser, error) {
+	switch method {
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return newZstdWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression %q (use gzip or zstd)", method)
+}
+
+// zstdWriter pipes data through the zstd command-line tool
+type zstdWriter struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr bytes.Buffer
+}
+
+// newZstdWriter starts zstd writing its compressed output to w
+func newZstdWriter(w io.Writer) (*zstdWriter, error) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		return nil, fmt.Errorf("zstd compression requires the zstd command-line tool: %w", err)
+	}
+
+	zw := &zstdWriter{cmd: exec.Command("zstd", "-q", "-c")}
+	zw.cmd.Stdout = w
+	zw.cmd.Stderr = &zw.stderr
+
+	stdin, err := zw.cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	zw.stdin = stdin
+
+	if err := zw.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return zw, nil
+}
+
+// Write sends p to zstd
+func (zw *zstdWriter) Write(p []byte) (int, error) {
+	return zw.stdin.Write(p)
+}
+
+// Close ends the input and waits for zstd to finish
+func (zw *zstdWriter) Close() error {
+	if err := zw.stdin.Close(); err != nil {
+		return err
+	}
+	if err := zw.cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd: %v: %s", err, strings.TrimSpace(zw.stderr.String()))
+	}
+	return nil
+}
+
+// Open opens a digest for reading, transparently decompressing gzip and
+// zstd files detected by their magic numbers
+func Open(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	buffered := bufio.NewReader(file)
+	magic, _ := buffered.Peek(len(zstdMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: gz, closers: []io.Closer{gz, file}}, nil
+
+	case bytes.HasPrefix(magic, zstdMagic):
+		cmd := exec.Command("zstd", "-q", "-d", "-c")
+		cmd.Stdin = buffered
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("reading zstd digests requires the zstd command-line tool: %w", err)
+		}
+		return &readCloser{Reader: stdout, closers: []io.Closer{waitCloser{cmd, stdout}, file}}, nil
+	}
+
+	return &readCloser{Reader: buffered, closers: []io.Closer{file}}, nil
+}
+
+// readCloser reads from a decompressor and closes every underlying layer
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes all layers, returning the first error
+func (rc *readCloser) Close() error {
+	var first error
+	for _, c := range rc.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// waitCloser stops reading from a decompression command and waits for it
+type waitCloser struct {
+	cmd    *exec.Cmd
+	stdout io.Closer
+}
+
+// Close closes the output pipe, so an unfinished command exits, and waits
+// for the command. An early close is not an error.
+func (wc waitCloser) Close() error {
+	wc.stdout.Close()
+	if err := wc.cmd.Wait(); err != nil {
+		if _, ok := err.(*exec.ExitError); !ok {
+			return err
+		}
+	}
+	return nil
+}
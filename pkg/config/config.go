@@ -4,26 +4,151 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/agris/ingest-clone/pkg/gitutil"
+	"github.com/agris/ingest-clone/pkg/issues"
+	"github.com/agris/ingest-clone/pkg/pathutil"
+	"github.com/agris/ingest-clone/pkg/pullrequest"
 )
 
 // Constants for default values
 const (
-	DefaultMaxFileSize  = 10 * 1024 * 1024 // 10 MB
-	DefaultOutputFile   = "digest.txt"
-	DefaultDirDepth     = 20
-	DefaultMaxFiles     = 10000
-	DefaultMaxTotalSize = 500 * 1024 * 1024 // 500 MB
-	Separator           = "================================================"
+	DefaultMaxFileSize      = 10 * 1024 * 1024 // 10 MB
+	DefaultOutputFile       = "digest.txt"
+	DefaultDirDepth         = 20
+	DefaultMaxFiles         = 10000
+	DefaultMaxTotalSize     = 500 * 1024 * 1024 // 500 MB
+	DefaultMaxBinarySize    = 64 * 1024         // 64 KB
+	DefaultLatestMigrations = 5
+	DefaultSkeletonSize     = 1024 * 1024 // 1 MB
+	DefaultChunkTokens      = 512
+	DefaultChunkOverlap     = 64
+	DefaultChurnSince       = "6 months ago"
+	DefaultSeparator        = "================================================"
+	DefaultFileHeader       = "{separator}\nFILE: {path}\n{separator}"
+)
+
+// Output formats
+const (
+	FormatText     = "text"
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatSQLite   = "sqlite"
+	FormatJSONL    = "jsonl"
+	FormatChunks   = "chunks"
+	FormatMermaid  = "mermaid"
+	FormatDOT      = "dot"
+)
+
+// Timestamp sources for the output header
+const (
+	TimestampNow  = "now"
+	TimestampGit  = "git"
+	TimestampNone = "none"
+)
+
+// Policies for binary files
+const (
+	BinaryPlaceholder = "placeholder" // Replace the content with a placeholder
+	BinarySkip        = "skip"        // Leave the file out of the digest
+	BinaryHexdump     = "hexdump"     // Show a hexdump of the first bytes
+	BinaryBase64      = "base64"      // Embed small files as base64
+)
+
+// Handling of test files
+const (
+	TestsInclude = "include" // Include tests in their own section after the other files
+	TestsExclude = "exclude" // Leave test files out
+	TestsOnly    = "only"    // Include only test files
+)
+
+// Handling of database migration directories
+const (
+	MigrationsAll    = "all"    // Include every migration
+	MigrationsLatest = "latest" // Include only the newest migrations
+	MigrationsSchema = "schema" // Replace SQL migrations with the schema they build
 )
 
+// formatExtensions maps each output format to the extension of its default output file
+var formatExtensions = map[string]string{
+	FormatText:     ".txt",
+	FormatJSON:     ".json",
+	FormatMarkdown: ".md",
+	FormatSQLite:   ".db",
+	FormatJSONL:    ".jsonl",
+	FormatChunks:   ".chunks.jsonl",
+	FormatMermaid:  ".mmd",
+	FormatDOT:      ".dot",
+}
+
 // Config holds the application configuration
 type Config struct {
 	// Source directory or file to analyze
 	Source string
 
+	// Directory within a git URL source to fetch and analyze
+	Subpath string
+
+	// Self-hosted git hosts mapped to their kind (github, gitlab, or bitbucket)
+	GitHosts map[string]string
+
 	// Output file path
 	OutputFile string
 
+	// Output format (text, json, markdown, sqlite, jsonl, chunks, mermaid, or dot)
+	Format string
+
+	// Output formats written from one traversal and the file of each, in
+	// the same order; Format and OutputFile are the first of them
+	Formats     []string
+	OutputFiles []string
+
+	// Maximum estimated tokens of each chunk (chunks format)
+	ChunkTokens int
+
+	// Estimated tokens shared by consecutive chunks of a file (chunks format)
+	ChunkOverlap int
+
+	// Embedding provider for chunks (openai or ollama), empty to skip
+	// embeddings (chunks and sqlite formats)
+	Embed string
+
+	// Embedding model, empty for the provider's default
+	EmbedModel string
+
+	// Ollama model URL, such as http://localhost:11434/llama3.2, whose
+	// summaries replace file contents; empty to keep contents
+	SummarizeWith string
+
+	// Instructions given to the model before each file, empty for the default
+	SummarizePrompt string
+
+	// Patterns of files whose full content is kept when the rest is reduced
+	// by SummaryRest or SummarizeWith
+	FullPatterns []string
+
+	// Reduce files not matching FullPatterns to tree and statistics entries,
+	// or to summaries with SummarizeWith
+	SummaryRest bool
+
+	// Source of the header timestamp (now, git, or none)
+	TimestampFrom string
+
+	// Go text/template file used to render the output (text format)
+	Template string
+
+	// Text placed before a text digest, read from --prompt-file
+	PromptPreamble string
+
+	// Instructions placed after a text digest
+	Prompt string
+
+	// Input token prices listed in the summary (nil for no cost estimate)
+	Pricing []Price
+
+	// CSV file receiving per-file metrics, besides the output formats
+	MetricsCSV string
+
 	// Maximum file size to process in bytes
 	MaxFileSize int64
 
@@ -33,14 +158,208 @@ type Config struct {
 	// Patterns to exclude (comma-separated)
 	ExcludePatterns []string
 
+	// Include full contents of generated and minified files
+	IncludeGenerated bool
+
+	// Include every copy of duplicate files instead of referencing the first
+	NoDedupe bool
+
+	// Keep embedded base64 blobs (data URIs, notebook outputs) in file contents
+	KeepEmbedded bool
+
+	// Traverse dotfiles and dot-directories not covered by the exclude patterns
+	Hidden bool
+
+	// Match patterns regardless of case (the default, so *.jpg also
+	// excludes IMG.JPG). The default exclude patterns always ignore case.
+	IgnoreCase bool
+
+	// Keep the previous output file as <output>.bak
+	Backup bool
+
+	// Compression applied to the output file ("gzip", "zstd", or empty)
+	Compress string
+
+	// Ordering rules placing matching files first in the file contents
+	OrderPatterns []string
+
+	// Keep top-level README, ARCHITECTURE, and CONTRIBUTING docs in tree order
+	NoReadmeFirst bool
+
+	// Replace Go, Python, Java, and JS/TS sources with their declarations
+	Outline bool
+
+	// Replace sources with their leading comment, doc comments, and docstrings
+	DocsOnly bool
+
+	// Remove comments from source files in supported languages
+	StripComments bool
+
+	// Keep doc comments and docstrings when stripping comments
+	KeepDocComments bool
+
+	// Remove license header comments from source files
+	StripLicenseHeaders bool
+
+	// Lines kept of the files given with ranges such as main.go:100-250,
+	// keyed by absolute path
+	LineRanges map[string][]LineRange
+
+	// Convert CRLF and CR line endings to LF
+	NormalizeEOL bool
+
+	// Remove trailing spaces and tabs from every line
+	StripTrailingWhitespace bool
+
+	// Maximum number of consecutive blank lines to keep (0 keeps all)
+	CollapseBlankLines int
+
 	// Maximum directory depth to traverse
 	MaxDirDepth int
 
+	// Annotate directories in the tree with file counts, sizes, and tokens
+	TreeStats bool
+
+	// Show permissions, modification times, and symlink targets in the tree
+	TreeMetadata bool
+
+	// Maximum depth of the rendered tree (0 for unlimited)
+	TreeDepth int
+
+	// Maximum depth of files whose contents are included (0 for
+	// unlimited); deeper files are listed in the tree without content
+	ContentDepth int
+
 	// Maximum number of files to process
 	MaxFiles int
 
 	// Maximum total size in bytes
 	MaxTotalSize int64
+
+	// Maximum estimated tokens of the included file contents (0 for
+	// unlimited)
+	MaxTokens int
+
+	// Disable all git probing
+	NoGit bool
+
+	// Do not apply git ignore rules
+	NoGitIgnore bool
+
+	// Stay on the file system of the source, skipping mount points
+	OneFileSystem bool
+
+	// Omit the dependency summary section
+	NoDeps bool
+
+	// Omit the Terraform resources and modules section
+	NoTerraform bool
+
+	// Handling of binary files (placeholder, skip, hexdump, or base64)
+	BinaryMode string
+
+	// Describe images by format, dimensions, and size regardless of BinaryMode
+	ImageMetadata bool
+
+	// Largest binary file embedded in base64 mode, in bytes
+	MaxBinarySize int64
+
+	// Fetch Git LFS objects with git-lfs instead of noting their pointers
+	LFS bool
+
+	// Replace lockfile contents (go.sum, package-lock.json, ...) with placeholders
+	ExcludeLockfiles bool
+
+	// Keep only the header and this many rows of CSV and TSV files (0 keeps all rows)
+	SampleRows int
+
+	// Replace JSON and YAML files larger than this with their skeleton (0 never does)
+	SkeletonSize int64
+
+	// Include log files, keeping only this many of their last lines (0 excludes them)
+	LogTail int
+
+	// Whether test files are included, excluded, or the only files included
+	Tests string
+
+	// How migration directories are summarized (all, latest, schema)
+	Migrations string
+
+	// Number of migration versions kept by the latest policy
+	LatestMigrations int
+
+	// Helm chart directories, relative to the source, whose templates are
+	// replaced by the manifests helm renders from them
+	HelmCharts []string
+
+	// Values files passed to helm when rendering charts, relative to each
+	// chart unless absolute
+	HelmValues []string
+
+	// Add a section listing TODO/FIXME/HACK/XXX comments
+	Todos bool
+
+	// Add a section mapping the top-level directories and Go package imports
+	Architecture bool
+
+	// Add a section ranking the files with the highest cyclomatic complexity
+	Hotspots bool
+
+	// Add a section ranking the files changed by the most commits
+	Churn bool
+
+	// Start of the commit history counted for churn, in any format git accepts
+	ChurnSince string
+
+	// Annotate files with their CODEOWNERS owners and main git blame authors
+	Authors bool
+
+	// Prefix every content line with the commit and author that last changed it
+	Blame bool
+
+	// Unified diff included before the contents of the files it touches
+	Patch string
+
+	// Pull request whose description and comments precede its diff
+	PullRequest *pullrequest.PullRequest
+
+	// Append an index of exported Go symbols with their defining and referencing files
+	GoSymbols bool
+
+	// Issue threads appended after the contents
+	Issues []*issues.Issue
+
+	// Fail the run if any path could not be processed
+	Strict bool
+
+	// Replace the repository name, user names, internal hosts, and email
+	// addresses with placeholders
+	Anonymize bool
+
+	// Emit a table of contents with file offsets (text format)
+	TOC bool
+
+	// Line drawn around file headers and between sources (text format)
+	Separator string
+
+	// File header template with {path} and {separator} placeholders (text format)
+	FileHeader string
+
+	// Lazily opened git work tree for the source
+	git *gitutil.Repo
+
+	// Paths excluded by git ignore rules, loaded on first use
+	gitIgnored map[string]bool
+
+	// Absolute paths of the output files, resolved on first use
+	outputAbs []string
+
+	// Absolute paths traversal is restricted to, nil for no restriction
+	selection map[string]bool
+
+	// Absolute path of the traversal root, which anchored patterns are
+	// relative to
+	root string
 }
 
 // Stats tracks statistics during file processing
@@ -49,20 +368,84 @@ type Stats struct {
 	TotalSize  int64
 	FileCount  int
 	DirCount   int
+	Errors     []PathError // Paths skipped because of errors
+
+	// Files and directories cut off by limits
+	OmittedByDepth     int // Files below the maximum directory depth
+	OmittedDirsByDepth int // Directories below the maximum directory depth
+	OmittedByMaxFiles  int // Files skipped after reaching the maximum file count
+	OmittedByTotalSize int // Files that would exceed the maximum total size
+	OmittedByFileSize  int // Files larger than the maximum file size
+	OmittedByTokens    int // Files that would exceed the token budget
+	TotalTokens        int // Estimated tokens of the included file contents
+
+	// Paths left out by the patterns, git ignore rules, selection, or test
+	// handling; pruned directories are not read at all
+	PrunedDirs    int // Directories skipped with everything below them
+	ExcludedFiles int // Files skipped
+
+	OmittedDigests int // Previous digests left out of the traversal
+	OmittedBinary  int // Binary files left out by the skip policy
+	OmittedMounts  int // Mount points not crossed with --one-file-system
+
+	OmittedMigrations int // Older migrations left out or folded into an inferred schema
+	HelmTemplates     int // Helm templates replaced by the manifests rendered from them
+	RedactedSecrets   int // Sensitive values replaced in Terraform state files
+	SummarizedFiles   int // Files replaced with a model's summary
+	SummaryOnlyFiles  int // Files listed without content by --summary-rest
+	LicenseHeaders    int // Files whose license header was removed
+	LicenseTokens     int // Estimated tokens of the removed license headers
+	OmittedContent    int // Files below --content-depth listed without content
+	OmittedBlank      int // Empty or whitespace-only files listed without content
+}
+
+// PathError records a path that could not be processed
+type PathError struct {
+	Path string // Full path that failed
+	Err  error  // Underlying error
 }
 
 // NewConfig creates a new Config with default values
 func NewConfig() *Config {
 	return &Config{
-		Source:          ".",
-		OutputFile:      DefaultOutputFile,
-		MaxFileSize:     DefaultMaxFileSize,
-		IncludePatterns: []string{},
-		ExcludePatterns: getDefaultExcludePatterns(),
-		MaxDirDepth:     DefaultDirDepth,
-		MaxFiles:        DefaultMaxFiles,
-		MaxTotalSize:    DefaultMaxTotalSize,
+		Source:           ".",
+		OutputFile:       DefaultOutputFile,
+		Format:           FormatText,
+		TimestampFrom:    TimestampNow,
+		MaxFileSize:      DefaultMaxFileSize,
+		IncludePatterns:  []string{},
+		ExcludePatterns:  getDefaultExcludePatterns(),
+		Hidden:           true,
+		IgnoreCase:       true,
+		MaxDirDepth:      DefaultDirDepth,
+		MaxFiles:         DefaultMaxFiles,
+		MaxTotalSize:     DefaultMaxTotalSize,
+		BinaryMode:       BinaryPlaceholder,
+		Tests:            TestsInclude,
+		Migrations:       MigrationsAll,
+		LatestMigrations: DefaultLatestMigrations,
+		SkeletonSize:     DefaultSkeletonSize,
+		ChunkTokens:      DefaultChunkTokens,
+		ChunkOverlap:     DefaultChunkOverlap,
+		ChurnSince:       DefaultChurnSince,
+		MaxBinarySize:    DefaultMaxBinarySize,
+		Separator:        DefaultSeparator,
+		FileHeader:       DefaultFileHeader,
+	}
+}
+
+// Git returns the git work tree for the source, opening it on first use
+func (c *Config) Git() *gitutil.Repo {
+	if c.git == nil {
+		c.git = gitutil.Open(c.Source, c.NoGit)
 	}
+	return c.git
+}
+
+// SetGitDir opens the git work tree of a fetched source at dir, which Git
+// returns instead of probing the source itself
+func (c *Config) SetGitDir(dir string) {
+	c.git = gitutil.Open(dir, c.NoGit)
 }
 
 // ShouldInclude determines if the given path should be included based on patterns
@@ -72,39 +455,317 @@ func (c *Config) ShouldInclude(path string) bool {
 		return !c.ShouldExclude(path)
 	}
 
-	// Check if the path matches any include pattern
-	for _, pattern := range c.IncludePatterns {
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
-			return true
-		}
+	_, included := c.IncludedBy(path)
+	return included
+}
 
-		// Check for directory patterns like "dir/"
-		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, strings.TrimSuffix(pattern, "/")) {
-			return true
+// IncludedBy returns the first include pattern matching path, entering the
+// directories leading to an anchored one. Without include patterns every
+// path is included and the pattern is empty.
+func (c *Config) IncludedBy(path string) (pattern string, included bool) {
+	if len(c.IncludePatterns) == 0 {
+		return "", true
+	}
+
+	for _, pattern := range c.IncludePatterns {
+		if c.matchPattern(pattern, path) || c.leadsToAnchored(pattern, path) && DirExists(path) {
+			return pattern, true
 		}
 	}
 
 	// If include patterns are specified but none matched, exclude the path
-	return false
+	return "", false
 }
 
 // ShouldExclude determines if the given path should be excluded based on patterns
 func (c *Config) ShouldExclude(path string) bool {
-	// Check if the path matches any exclude pattern
+	if c.SkipsHidden(path) {
+		return true
+	}
+	_, excluded := c.ExcludedBy(path)
+	return excluded
+}
+
+// SkipsHidden reports whether path is a dotfile or dot-directory skipped
+// because hidden files are disabled
+func (c *Config) SkipsHidden(path string) bool {
+	if c.Hidden {
+		return false
+	}
+	base := filepath.Base(path)
+	return strings.HasPrefix(base, ".") && base != "." && base != ".."
+}
+
+// ExcludedBy returns whether the exclude patterns exclude path and the
+// pattern that decides it, which is empty when no pattern applies. A
+// negation that keeps the path, or an excluded directory holding
+// re-included paths, is returned with excluded false.
+func (c *Config) ExcludedBy(path string) (pattern string, excluded bool) {
+	// The last matching pattern decides; paths no pattern matches follow the
+	// nearest decided directory above them, which negations may have kept
+	pattern, excluded, decided := c.excludeDecision(path)
+	if !decided && c.hasNegations() {
+		for dir := filepath.Dir(path); c.belowRoot(dir); dir = filepath.Dir(dir) {
+			if pattern, excluded, decided = c.excludeDecision(dir); decided {
+				break
+			}
+		}
+	}
+
+	// Enter an excluded directory that holds re-included paths
+	if excluded {
+		if negation := c.negatedBelow(path); negation != "" {
+			return negation, false
+		}
+	}
+	return pattern, excluded
+}
+
+// IsDefaultExclude reports whether pattern is one of the default exclude
+// patterns
+func IsDefaultExclude(pattern string) bool {
+	return defaultExcludes[pattern]
+}
+
+// excludeDecision returns the last exclude pattern matching path and
+// whether it excludes the path; decided is false when no pattern matches
+func (c *Config) excludeDecision(path string) (pattern string, excluded, decided bool) {
+	for _, p := range c.ExcludePatterns {
+		fold := c.IgnoreCase || defaultExcludes[p]
+		negated := strings.HasPrefix(p, "!")
+		target := strings.TrimPrefix(p, `\`) // \!name matches a name starting with "!"
+		if negated {
+			target = p[1:]
+		}
+
+		if c.match(target, path, fold) {
+			pattern, excluded, decided = p, !negated, true
+		}
+	}
+	return pattern, excluded, decided
+}
+
+// hasNegations reports whether any exclude pattern is a "!" negation
+func (c *Config) hasNegations() bool {
 	for _, pattern := range c.ExcludePatterns {
-		if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+		if strings.HasPrefix(pattern, "!") {
 			return true
 		}
+	}
+	return false
+}
+
+// negatedBelow returns an anchored negation that re-includes paths below
+// path, or "" if there is none. Unanchored negations cannot reach into
+// excluded directories, as in gitignore.
+func (c *Config) negatedBelow(path string) string {
+	for _, pattern := range c.ExcludePatterns {
+		if strings.HasPrefix(pattern, "!") && c.leadsToAnchored(pattern[1:], path) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// belowRoot reports whether path is inside the root, excluding the root
+func (c *Config) belowRoot(path string) bool {
+	_, _, ok := c.anchoredSegments("/", path, false)
+	return ok
+}
+
+// SetRoot sets the directory that anchored patterns are relative to;
+// ProcessPath sets it to the path it analyzes
+func (c *Config) SetRoot(path string) {
+	c.root = AbsPath(path)
+}
+
+// matchPattern reports whether path matches an include/exclude pattern.
+// Patterns starting with "/" or with a slash before their end are anchored
+// to the root like gitignore patterns: "/build" matches the top-level build
+// directory and everything below it, but not src/build.
+func (c *Config) matchPattern(pattern, path string) bool {
+	return c.match(pattern, path, c.IgnoreCase)
+}
+
+// match matches a pattern like matchPattern, ignoring case if fold is set
+func (c *Config) match(pattern, path string, fold bool) bool {
+	if isAnchored(pattern) {
+		segments, parts, ok := c.anchoredSegments(pattern, path, fold)
+		return ok && matchSegments(append(segments, "**"), parts)
+	}
+
+	// Directory patterns like "vendor/" match a directory of that name at
+	// any depth below the root and everything below it, as in gitignore
+	if name, ok := strings.CutSuffix(pattern, "/"); ok {
+		parts := c.relativeSegments(path)
+		for i, part := range parts {
+			if fold {
+				name, part = strings.ToLower(name), strings.ToLower(part)
+			}
+			if matched, _ := filepath.Match(name, part); matched && (i < len(parts)-1 || DirExists(path)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if fold {
+		pattern, path = strings.ToLower(pattern), strings.ToLower(path)
+	}
+	matched, _ := filepath.Match(pattern, filepath.Base(path))
+	return matched
+}
 
-		// Check for directory patterns like "dir/"
-		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, strings.TrimSuffix(pattern, "/")) {
+// relativeSegments splits path relative to the root into its segments; the
+// root has none, and a path outside the root is its name alone
+func (c *Config) relativeSegments(path string) []string {
+	root := c.root
+	if root == "" {
+		root = AbsPath(c.Source)
+	}
+	rel, err := filepath.Rel(root, AbsPath(path))
+	if err == nil && rel == "." {
+		return nil
+	}
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return []string{filepath.Base(path)}
+	}
+	return strings.Split(filepath.ToSlash(rel), "/")
+}
+
+// leadsToAnchored reports whether path may be a directory above the paths
+// an anchored pattern matches, such as pkg for "/pkg/core"
+func (c *Config) leadsToAnchored(pattern, path string) bool {
+	if !isAnchored(pattern) {
+		return false
+	}
+
+	segments, parts, ok := c.anchoredSegments(pattern, path, c.IgnoreCase)
+	if !ok {
+		return false
+	}
+	for i, part := range parts {
+		if i == len(segments) {
+			return false
+		}
+		if segments[i] == "**" {
+			return true
+		}
+		if matched, _ := filepath.Match(segments[i], part); !matched {
+			return false
+		}
+	}
+	return len(parts) < len(segments)
+}
+
+// isAnchored reports whether a pattern is relative to the root rather than
+// matching names at any depth
+func isAnchored(pattern string) bool {
+	return strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+}
+
+// anchoredSegments splits an anchored pattern and the path relative to the
+// root into segments, lowercased if fold is set, or reports false for paths
+// outside the root
+func (c *Config) anchoredSegments(pattern, path string, fold bool) ([]string, []string, bool) {
+	root := c.root
+	if root == "" {
+		root = AbsPath(c.Source)
+	}
+	rel, err := filepath.Rel(root, AbsPath(path))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, nil, false
+	}
+
+	pattern, rel = strings.Trim(pattern, "/"), filepath.ToSlash(rel)
+	if fold {
+		pattern, rel = strings.ToLower(pattern), strings.ToLower(rel)
+	}
+	return strings.Split(pattern, "/"), strings.Split(rel, "/"), true
+}
+
+// IsHelmChart reports whether dir is one of the chart directories rendered
+// with --helm-render
+func (c *Config) IsHelmChart(dir string) bool {
+	if len(c.HelmCharts) == 0 {
+		return false
+	}
+	root := c.root
+	if root == "" {
+		root = AbsPath(c.Source)
+	}
+	rel, err := filepath.Rel(root, AbsPath(dir))
+	if err != nil {
+		return false
+	}
+	for _, chart := range c.HelmCharts {
+		if filepath.Clean(filepath.FromSlash(chart)) == rel {
 			return true
 		}
 	}
+	return false
+}
+
+// ValidFormat reports whether the given output format is supported
+func ValidFormat(format string) bool {
+	_, ok := formatExtensions[format]
+	return ok
+}
+
+// ValidBinaryMode reports whether the given binary file policy is supported
+func ValidBinaryMode(mode string) bool {
+	switch mode {
+	case BinaryPlaceholder, BinarySkip, BinaryHexdump, BinaryBase64:
+		return true
+	}
+	return false
+}
 
+// ValidTestsMode reports whether the given test file handling is supported
+func ValidTestsMode(mode string) bool {
+	switch mode {
+	case TestsInclude, TestsExclude, TestsOnly:
+		return true
+	}
 	return false
 }
 
+// ValidMigrationsMode reports whether the given migration handling is supported
+func ValidMigrationsMode(mode string) bool {
+	switch mode {
+	case MigrationsAll, MigrationsLatest, MigrationsSchema:
+		return true
+	}
+	return false
+}
+
+// DefaultOutputFileFor returns the default output file for a format
+func DefaultOutputFileFor(format string) string {
+	ext, ok := formatExtensions[format]
+	if !ok {
+		return DefaultOutputFile
+	}
+	return strings.TrimSuffix(DefaultOutputFile, filepath.Ext(DefaultOutputFile)) + ext
+}
+
+// ShouldIncludeTree checks a path and each of its parent directories below
+// root against the include/exclude patterns, as traversal would. Remote
+// sources use it to filter listings before downloading anything.
+func (c *Config) ShouldIncludeTree(root, path string) bool {
+	c.SetRoot(root)
+	// Compare whole path elements so /src/app-old is not taken to be below /src/app
+	prefix := root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, prefix); dir = filepath.Dir(dir) {
+		if c.ShouldExclude(dir) {
+			return false
+		}
+	}
+	return c.ShouldInclude(path) && !c.ShouldExclude(path)
+}
+
 // ParsePatterns splits a comma-separated string into a slice of patterns
 func ParsePatterns(patterns string) []string {
 	if patterns == "" {
@@ -133,7 +794,7 @@ func AbsPath(path string) string {
 
 // FileExists checks if a file exists
 func FileExists(path string) bool {
-	info, err := os.Stat(path)
+	info, err := os.Stat(pathutil.Long(path))
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -142,13 +803,24 @@ func FileExists(path string) bool {
 
 // DirExists checks if a directory exists
 func DirExists(path string) bool {
-	info, err := os.Stat(path)
+	info, err := os.Stat(pathutil.Long(path))
 	if os.IsNotExist(err) {
 		return false
 	}
 	return info.IsDir()
 }
 
+// defaultExcludes holds the default exclude patterns, which match
+// regardless of case so that FOO.EXE or IMG_0001.JPG fixtures are treated
+// like their lowercase names on every platform
+var defaultExcludes = func() map[string]bool {
+	patterns := map[string]bool{}
+	for _, pattern := range getDefaultExcludePatterns() {
+		patterns[pattern] = true
+	}
+	return patterns
+}()
+
 // getDefaultExcludePatterns returns the default patterns to exclude
 func getDefaultExcludePatterns() []string {
 	return []string{
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirectoryPatternMatchesAtAnyDepth(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"a/vendor", "src/build", "vendored"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A file named like a directory pattern is not a directory
+	if err := os.WriteFile(filepath.Join(root, "build"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := NewConfig()
+	cfg.Source = root
+	cfg.SetRoot(root)
+	cfg.ExcludePatterns = []string{"vendor/", "build/"}
+
+	tests := []struct {
+		path    string
+		exclude bool
+	}{
+		{"a/vendor", true},
+		{"a/vendor/x.go", true},
+		{"src/build", true},
+		{"src/build/out.o", true},
+		{"vendored", false},
+		{"vendored/x.go", false},
+		{"build", false},
+		{"a/x.go", false},
+	}
+	for _, test := range tests {
+		path := filepath.Join(root, filepath.FromSlash(test.path))
+		if got := cfg.ShouldExclude(path); got != test.exclude {
+			t.Errorf("ShouldExclude(%s) = %v, want %v", test.path, got, test.exclude)
+		}
+	}
+}
+
+func TestShouldIncludeTreeChecksOnlyDirectoriesBelowRoot(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "app")
+
+	cfg := NewConfig()
+	cfg.ExcludePatterns = []string{"app-old"}
+
+	tests := []struct {
+		path    string
+		include bool
+	}{
+		{"app/main.go", true},
+		{"app/app-old/main.go", false},
+		{"app-old/main.go", true},
+	}
+	for _, test := range tests {
+		path := filepath.Join(parent, filepath.FromSlash(test.path))
+		if got := cfg.ShouldIncludeTree(root, path); got != test.include {
+			t.Errorf("ShouldIncludeTree(%s) = %v, want %v", test.path, got, test.include)
+		}
+	}
+}
+
+func TestPatternsIgnoreCaseByDefault(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		pattern       string
+		path          string
+		caseSensitive bool
+		exclude       bool
+	}{
+		{"*.jpg", "IMG.JPG", false, true},
+		{"*.JPG", "photos/img.jpg", false, true},
+		{"/Build", "build/out.o", false, true},
+		{"*.jpg", "IMG.JPG", true, false},
+		{"*.jpg", "img.jpg", true, true},
+		{"/Build", "build/out.o", true, false},
+	}
+	for _, test := range tests {
+		cfg := NewConfig()
+		cfg.SetRoot(root)
+		cfg.ExcludePatterns = []string{test.pattern}
+		if test.caseSensitive {
+			cfg.IgnoreCase = false
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(test.path))
+		if got := cfg.ShouldExclude(path); got != test.exclude {
+			t.Errorf("ShouldExclude(%s) with %s, case-sensitive %v = %v, want %v",
+				test.path, test.pattern, test.caseSensitive, got, test.exclude)
+		}
+	}
+
+	// The default excludes ignore case even when matching case-sensitively
+	cfg := NewConfig()
+	cfg.SetRoot(root)
+	cfg.IgnoreCase = false
+	if path := filepath.Join(root, "SETUP.EXE"); !cfg.ShouldExclude(path) {
+		t.Errorf("ShouldExclude(SETUP.EXE) with the default excludes = false, want true")
+	}
+}
+
+func TestExcludeNegationOrder(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"vendor/acme-fork", "vendor/other"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		patterns []string
+		path     string
+		pattern  string
+		exclude  bool
+	}{
+		{[]string{"*.tmp"}, "a.tmp", "*.tmp", true},
+		{[]string{"*.tmp", "!keep.tmp"}, "src/keep.tmp", "!keep.tmp", false},
+		{[]string{"*.tmp", "!keep.tmp"}, "src/drop.tmp", "*.tmp", true},
+		// The last matching pattern wins
+		{[]string{"!keep.tmp", "*.tmp"}, "src/keep.tmp", "*.tmp", true},
+		// Files follow the nearest decided directory above them
+		{[]string{"vendor/", "!vendor/acme-fork/"}, "vendor/acme-fork/fork.go", "!vendor/acme-fork/", false},
+		{[]string{"vendor/", "!vendor/acme-fork/"}, "vendor/other/lib.go", "vendor/", true},
+		// An excluded directory holding re-included paths is entered
+		{[]string{"vendor/", "!vendor/acme-fork/"}, "vendor", "!vendor/acme-fork/", false},
+		// Unanchored negations do not reach into excluded directories
+		{[]string{"vendor/", "!lib.go"}, "vendor", "vendor/", true},
+		{[]string{`\!important.txt`}, "!important.txt", `\!important.txt`, true},
+		{[]string{"!keep.tmp"}, "other.go", "", false},
+	}
+	for _, test := range tests {
+		cfg := NewConfig()
+		cfg.SetRoot(root)
+		cfg.ExcludePatterns = test.patterns
+
+		path := filepath.Join(root, filepath.FromSlash(test.path))
+		pattern, exclude := cfg.ExcludedBy(path)
+		if pattern != test.pattern || exclude != test.exclude {
+			t.Errorf("ExcludedBy(%s) with %v = %q, %v, want %q, %v",
+				test.path, test.patterns, pattern, exclude, test.pattern, test.exclude)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package config
+
+import "path/filepath"
+
+// IsGitIgnored reports whether git's ignore rules (.gitignore,
+// .git/info/exclude, and core.excludesFile) exclude the given path
+func (c *Config) IsGitIgnored(path string) bool {
+	if c.NoGitIgnore {
+		return false
+	}
+
+	// Ask git once for every ignored path in the work tree
+	if c.gitIgnored == nil {
+		c.gitIgnored = map[string]bool{}
+		paths, err := c.Git().IgnoredPaths()
+		if err != nil {
+			return false
+		}
+		for _, p := range paths {
+			c.gitIgnored[p] = true
+		}
+	}
+
+	// Ignored directories are reported once, so check every parent as well
+	for p := AbsPath(path); ; {
+		if c.gitIgnored[p] {
+			return true
+		}
+
+		parent := filepath.Dir(p)
+		if parent == p {
+			return false
+		}
+		p = parent
+	}
+}
+
+// ReloadGitIgnored forgets the ignored paths loaded by IsGitIgnored, so the
+// next call asks git again and sees files created since
+func (c *Config) ReloadGitIgnored() {
+	c.gitIgnored = nil
+}
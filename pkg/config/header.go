@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FileHeaderStyles maps the named file header styles to their templates
+var FileHeaderStyles = map[string]string{
+	"default":  DefaultFileHeader,
+	"markdown": "### {path}",
+	"plain":    "FILE: {path}",
+}
+
+// ParseFileHeader resolves a file header style name or template. Templates
+// may spell line breaks as \n and must contain the {path} placeholder.
+func ParseFileHeader(value string) (string, error) {
+	if template, ok := FileHeaderStyles[value]; ok {
+		return template, nil
+	}
+
+	template := strings.NewReplacer(`\n`, "\n", `\t`, "\t").Replace(value)
+	if !strings.Contains(template, "{path}") {
+		return "", fmt.Errorf("file header '%s' is neither a style nor a template containing {path}", value)
+	}
+	return template, nil
+}
+
+// FormatFileHeader renders the file header for path, ending in a newline.
+// Lines consisting only of the separator are dropped when it is empty.
+func (c *Config) FormatFileHeader(path string) string {
+	var builder strings.Builder
+	for _, line := range strings.Split(c.FileHeader, "\n") {
+		if line == "{separator}" && c.Separator == "" {
+			continue
+		}
+		line = strings.ReplaceAll(line, "{separator}", c.Separator)
+		builder.WriteString(strings.ReplaceAll(line, "{path}", path))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LineRange is a range of lines kept from a file, 1-based and inclusive.
+// An End of 0 stands for the last line.
+type LineRange struct {
+	Start int
+	End   int
+}
+
+// String formats the range as given on the command line, such as "100-250"
+func (r LineRange) String() string {
+	switch {
+	case r.End == 0:
+		return fmt.Sprintf("%d-", r.Start)
+	case r.End == r.Start:
+		return strconv.Itoa(r.Start)
+	}
+	return fmt.Sprintf("%d-%d", r.Start, r.End)
+}
+
+// FormatLineRanges joins ranges for display, such as "1-80, 120-140"
+func FormatLineRanges(ranges []LineRange) string {
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fileRangePattern matches a file list entry ending in a line range, such as
+// main.go:100-250, main.go:42, or main.go:100-
+var fileRangePattern = regexp.MustCompile(`^(.+):(\d+)(?:-(\d*))?$`)
+
+// ParseFileRanges splits the line ranges off file list entries. It returns
+// the paths in their first order without repeats, and the ranges of each
+// file keyed by absolute path. An entry naming an existing file is taken as
+// a path even if it looks like a range, and a file also listed without a
+// range is included whole.
+func ParseFileRanges(entries []string) ([]string, map[string][]LineRange, error) {
+	var paths []string
+	ranges := map[string][]LineRange{}
+	whole := map[string]bool{}
+	listed := map[string]bool{}
+
+	for _, entry := range entries {
+		path := entry
+		m := fileRangePattern.FindStringSubmatch(entry)
+		if m != nil && !FileExists(entry) {
+			path = m[1]
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !listed[abs] {
+			listed[abs] = true
+			paths = append(paths, path)
+		}
+		if path == entry {
+			whole[abs] = true
+			continue
+		}
+
+		r := LineRange{}
+		r.Start, _ = strconv.Atoi(m[2])
+		r.End = r.Start
+		if strings.Contains(entry[len(path)+1:], "-") {
+			r.End, _ = strconv.Atoi(m[3])
+		}
+		if r.Start < 1 || (r.End != 0 && r.End < r.Start) {
+			return nil, nil, fmt.Errorf("invalid line range '%s' in '%s'", entry[len(path)+1:], entry)
+		}
+		ranges[abs] = append(ranges[abs], r)
+	}
+
+	for abs := range whole {
+		delete(ranges, abs)
+	}
+	return paths, ranges, nil
+}
+
+// LinesOf returns the line ranges selected for a file, or nil for the
+// whole file
+func (c *Config) LinesOf(path string) []LineRange {
+	return c.LineRanges[path]
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFileRanges(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.go")
+	util := filepath.Join(dir, "util.go")
+	// A file whose name looks like a range is taken as a path
+	odd := filepath.Join(dir, "notes:12")
+	if err := os.WriteFile(odd, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		paths   []string
+		ranges  map[string][]LineRange
+	}{
+		{"plain paths", []string{main, util}, []string{main, util}, map[string][]LineRange{}},
+		{"closed range", []string{main + ":100-250"}, []string{main}, map[string][]LineRange{main: {{100, 250}}}},
+		{"single line", []string{main + ":42"}, []string{main}, map[string][]LineRange{main: {{42, 42}}}},
+		{"open range", []string{main + ":100-"}, []string{main}, map[string][]LineRange{main: {{100, 0}}}},
+		{
+			"ranges of one file combined",
+			[]string{main + ":1-10", util, main + ":20-30"},
+			[]string{main, util},
+			map[string][]LineRange{main: {{1, 10}, {20, 30}}},
+		},
+		{"whole file wins", []string{main + ":1-10", main}, []string{main}, map[string][]LineRange{}},
+		{"existing file named like a range", []string{odd}, []string{odd}, map[string][]LineRange{}},
+	}
+	for _, test := range tests {
+		paths, ranges, err := ParseFileRanges(test.entries)
+		if err != nil {
+			t.Errorf("%s: ParseFileRanges(%v) failed: %v", test.name, test.entries, err)
+			continue
+		}
+		if !reflect.DeepEqual(paths, test.paths) {
+			t.Errorf("%s: paths = %v, want %v", test.name, paths, test.paths)
+		}
+		if !reflect.DeepEqual(ranges, test.ranges) {
+			t.Errorf("%s: ranges = %v, want %v", test.name, ranges, test.ranges)
+		}
+	}
+
+	for _, entry := range []string{main + ":0", main + ":0-5", main + ":250-100"} {
+		if _, _, err := ParseFileRanges([]string{entry}); err == nil {
+			t.Errorf("ParseFileRanges(%s) succeeded, want an invalid range error", entry)
+		}
+	}
+}
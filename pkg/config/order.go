@@ -0,0 +1,83 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// OrderRank returns the index of the first ordering rule matching the
+// slash-separated relative path, or len(c.OrderPatterns) if none matches
+func (c *Config) OrderRank(rel string) int {
+	if c.IgnoreCase {
+		rel = strings.ToLower(rel)
+	}
+
+	for i, pattern := range c.OrderPatterns {
+		if c.IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		if MatchPathPattern(pattern, rel) {
+			return i
+		}
+	}
+	return len(c.OrderPatterns)
+}
+
+// IsFull reports whether the slash-separated relative path matches a --full
+// pattern, or whether no patterns were given
+func (c *Config) IsFull(rel string) bool {
+	if len(c.FullPatterns) == 0 {
+		return true
+	}
+	if c.IgnoreCase {
+		rel = strings.ToLower(rel)
+	}
+
+	for _, pattern := range c.FullPatterns {
+		if c.IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		if MatchPathPattern(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPathPattern matches a slash-separated relative path against a glob
+// pattern. Patterns without a slash match the base name at any depth; "**"
+// matches any number of directories, so "pkg/**" matches everything below pkg.
+func MatchPathPattern(pattern, rel string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		matched, _ := filepath.Match(pattern, filepath.Base(rel))
+		return matched
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every possible number of consumed segments
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+		if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+
+	return len(path) == 0
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsDigestOutput reports whether path is the configured output file, its
+// backup or temporary file, or a file named like a default digest
+// (digest.txt, digest.jsonl.gz, digest.db.bak, ...). Traversal skips these
+// so a digest written inside the source tree is not ingested into the next.
+func (c *Config) IsDigestOutput(path string) bool {
+	if c.outputAbs == nil {
+		for _, output := range append([]string{c.OutputFile}, c.OutputFiles...) {
+			c.outputAbs = append(c.outputAbs, AbsPath(output))
+		}
+	}
+
+	abs := AbsPath(path)
+	for _, output := range c.outputAbs {
+		if abs == output || abs == output+".bak" {
+			return true
+		}
+		if filepath.Dir(abs) == filepath.Dir(output) &&
+			strings.HasPrefix(filepath.Base(abs), "."+filepath.Base(output)+".tmp-") {
+			return true
+		}
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".bak")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".zst")
+	for format := range formatExtensions {
+		if name == DefaultOutputFileFor(format) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFormat reports whether the run writes the given output format
+func (c *Config) HasFormat(format string) bool {
+	if len(c.Formats) == 0 {
+		return c.Format == format
+	}
+	for _, f := range c.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputFileFor returns the file a format is written to next to path,
+// replacing its format extension: digest.txt becomes digest.jsonl
+func OutputFileFor(path, format string) string {
+	base := path
+	for _, ext := range formatExtensions {
+		if trimmed := strings.TrimSuffix(path, ext); trimmed != path && len(trimmed) < len(base) {
+			base = trimmed
+		}
+	}
+	if base == path {
+		base = strings.TrimSuffix(path, filepath.Ext(path))
+	}
+	return base + formatExtensions[format]
+}
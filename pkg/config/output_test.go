@@ -0,0 +1,59 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestIsDigestOutput(t *testing.T) {
+	dir := t.TempDir()
+	cfg := NewConfig()
+	cfg.OutputFile = filepath.Join(dir, "out", "report.txt")
+
+	tests := []struct {
+		path   string
+		output bool
+	}{
+		{"out/report.txt", true},
+		{"out/report.txt.bak", true},
+		{"out/.report.txt.tmp-123", true},
+		{"src/report.txt", false},
+		{"src/.report.txt.tmp-123", false},
+		{"digest.txt", true},
+		{"src/digest.json", true},
+		{"digest.md", true},
+		{"digest.jsonl.gz", true},
+		{"digest.chunks.jsonl.zst", true},
+		{"digest.db.bak", true},
+		{"digest.go", false},
+		{"mydigest.txt", false},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, filepath.FromSlash(test.path))
+		if got := cfg.IsDigestOutput(path); got != test.output {
+			t.Errorf("IsDigestOutput(%s) = %v, want %v", test.path, got, test.output)
+		}
+	}
+}
+
+func TestOutputFileFor(t *testing.T) {
+	tests := []struct {
+		path   string
+		format string
+		want   string
+	}{
+		{"digest.txt", FormatJSONL, "digest.jsonl"},
+		{"digest.txt", FormatChunks, "digest.chunks.jsonl"},
+		{"digest.chunks.jsonl", FormatText, "digest.txt"},
+		{"digest.jsonl", FormatMarkdown, "digest.md"},
+		{"out/repo.txt", FormatJSON, "out/repo.json"},
+		{"repo.out", FormatDOT, "repo.dot"},
+		{"repo", FormatSQLite, "repo.db"},
+		{"my.project/digest", FormatText, "my.project/digest.txt"},
+	}
+	for _, test := range tests {
+		if got := OutputFileFor(test.path, test.format); got != test.want {
+			t.Errorf("OutputFileFor(%s, %s) = %s, want %s", test.path, test.format, got, test.want)
+		}
+	}
+}
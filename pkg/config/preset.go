@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Preset bundles the patterns curated for an ecosystem
+type Preset struct {
+	Exclude []string // Added to the default exclude patterns
+	Order   []string // Ordering rules used unless --order is given
+}
+
+// presets are the built-in presets by name. Excludes cover the build
+// output, caches, and generated files each toolchain leaves in the tree;
+// ordering puts the manifest and entry points first.
+var presets = map[string]Preset{
+	"go": {
+		Exclude: []string{"/bin", "*.test", "*.prof", "*.pprof", "coverage.out", "go.work.sum"},
+		Order:   []string{"go.mod", "go.work", "doc.go", "cmd/**", "internal/**", "pkg/**"},
+	},
+	"node": {
+		Exclude: []string{"coverage", ".next", ".nuxt", ".svelte-kit", ".turbo", ".parcel-cache", ".yarn",
+			"storybook-static", "*.min.js", "*.min.css", "*.map", "*.tsbuildinfo"},
+		Order: []string{"package.json", "tsconfig.json", "index.*", "src/**", "lib/**"},
+	},
+	"python": {
+		Exclude: []string{"__pycache__", "*.pyc", "*.pyo", ".venv", "venv", ".tox", ".nox", ".mypy_cache",
+			".pytest_cache", ".ruff_cache", "*.egg-info", ".eggs", "htmlcov", ".coverage"},
+		Order: []string{"pyproject.toml", "setup.py", "setup.cfg", "requirements*.txt", "__init__.py", "src/**"},
+	},
+	"rust": {
+		Exclude: []string{"target", "*.rlib", "*.rmeta"},
+		Order:   []string{"Cargo.toml", "build.rs", "src/lib.rs", "src/main.rs", "src/**", "crates/**"},
+	},
+}
+
+// PresetNames returns the names of the built-in presets in sorted order
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyPresets adds the exclude patterns of the named presets, in order,
+// and uses their ordering rules. It runs before the user's patterns are
+// added, so -e negations such as "!coverage" and --order override presets.
+func (c *Config) ApplyPresets(names []string) error {
+	for _, name := range names {
+		preset, ok := presets[name]
+		if !ok {
+			return fmt.Errorf("unknown preset '%s' (available: %s)", name, strings.Join(PresetNames(), ", "))
+		}
+		c.ExcludePatterns = append(c.ExcludePatterns, preset.Exclude...)
+		c.OrderPatterns = append(c.OrderPatterns, preset.Order...)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Price is the cost of input tokens for a model
+type Price struct {
+	Model      string  // Model name shown in the summary
+	PerMillion float64 // USD per million input tokens
+}
+
+// DefaultPricing lists the list prices of common models for input tokens.
+// Prices change; --pricing replaces the table with current or negotiated
+// prices.
+var DefaultPricing = []Price{
+	{Model: "gpt-4o", PerMillion: 2.50},
+	{Model: "gpt-4o-mini", PerMillion: 0.15},
+	{Model: "gpt-4.1", PerMillion: 2.00},
+	{Model: "claude-opus", PerMillion: 15.00},
+	{Model: "claude-sonnet", PerMillion: 3.00},
+	{Model: "claude-haiku", PerMillion: 0.80},
+	{Model: "gemini-2.5-pro", PerMillion: 1.25},
+	{Model: "gemini-2.5-flash", PerMillion: 0.30},
+}
+
+// ParsePricing parses a pricing table with one "MODEL PRICE" line per model,
+// where PRICE is in USD per million input tokens. Blank lines and lines
+// starting with "#" are ignored.
+func ParsePricing(data string) ([]Price, error) {
+	var prices []Price
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected 'MODEL PRICE'", i+1)
+		}
+		price, err := strconv.ParseFloat(strings.TrimPrefix(fields[1], "$"), 64)
+		if err != nil || price < 0 {
+			return nil, fmt.Errorf("line %d: invalid price '%s'", i+1, fields[1])
+		}
+		prices = append(prices, Price{Model: fields[0], PerMillion: price})
+	}
+
+	if len(prices) == 0 {
+		return nil, errors.New("no prices")
+	}
+	return prices, nil
+}
+
+// Cost returns the price of the given number of input tokens
+func (p Price) Cost(tokens int) float64 {
+	return float64(tokens) * p.PerMillion / 1e6
+}
@@ -0,0 +1,35 @@
+package config
+
+import "path/filepath"
+
+// Select restricts traversal to the given files and their parent
+// directories, such as the sources of a Go package and its dependencies
+func (c *Config) Select(files []string) {
+	c.selection = map[string]bool{}
+	for _, file := range files {
+		for path := AbsPath(file); !c.selection[path]; path = filepath.Dir(path) {
+			c.selection[path] = true
+			if filepath.Dir(path) == path {
+				break
+			}
+		}
+	}
+}
+
+// SelectsTests reports whether a file passes the test file handling, given
+// whether it is a test
+func (c *Config) SelectsTests(isTest bool) bool {
+	switch c.Tests {
+	case TestsExclude:
+		return !isTest
+	case TestsOnly:
+		return isTest
+	}
+	return true
+}
+
+// Selected reports whether traversal may enter path: always, unless Select
+// restricted it to a set of files
+func (c *Config) Selected(path string) bool {
+	return c.selection == nil || c.selection[AbsPath(path)]
+}
@@ -0,0 +1,335 @@
+package deps
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Dependency is a direct dependency declared in a manifest
+type Dependency struct {
+	Name    string // Package or module name
+	Version string // Version or constraint as written (may be empty)
+	Dev     bool   // Whether it is a development-only dependency
+}
+
+// Manifest holds the direct dependencies declared in a manifest file
+type Manifest struct {
+	Ecosystem    string       // Package ecosystem (go, npm, pypi, cargo, maven, ...)
+	Dependencies []Dependency // Direct dependencies in declaration order
+}
+
+// parsers maps manifest file names to their parsers
+var parsers = map[string]func(content string) *Manifest{
+	"go.mod":           parseGoMod,
+	"package.json":     parsePackageJSON,
+	"composer.json":    parseComposerJSON,
+	"requirements.txt": parseRequirements,
+	"pyproject.toml":   parsePyproject,
+	"Cargo.toml":       parseCargoToml,
+	"pom.xml":          parsePomXML,
+	"Gemfile":          parseGemfile,
+}
+
+// lockfiles are generated dependency lockfiles, which are large and rarely
+// useful as context
+var lockfiles = map[string]bool{
+	"go.sum": true, "package-lock.json": true, "npm-shrinkwrap.json": true,
+	"yarn.lock": true, "pnpm-lock.yaml": true, "bun.lockb": true,
+	"Cargo.lock": true, "poetry.lock": true, "Pipfile.lock": true, "uv.lock": true,
+	"Gemfile.lock": true, "composer.lock": true, "mix.lock": true, "packages.lock.json": true,
+}
+
+// IsManifest reports whether the file name is a recognized dependency manifest
+func IsManifest(name string) bool {
+	_, ok := parsers[name]
+	return ok
+}
+
+// IsLockfile reports whether the file name is a dependency lockfile
+func IsLockfile(name string) bool {
+	return lockfiles[name]
+}
+
+// Parse extracts the direct dependencies from a manifest file. It returns
+// nil if the name is not a recognized manifest or the content is invalid.
+func Parse(name, content string) *Manifest {
+	parse, ok := parsers[name]
+	if !ok {
+		return nil
+	}
+	return parse(content)
+}
+
+// parseGoMod reads require directives, skipping indirect dependencies
+func parseGoMod(content string) *Manifest {
+	manifest := &Manifest{Ecosystem: "go"}
+	inBlock := false
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "require (":
+			inBlock = true
+			continue
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimPrefix(line, "require ")
+		case !inBlock:
+			continue
+		}
+
+		if strings.Contains(line, "// indirect") {
+			continue
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			manifest.Dependencies = append(manifest.Dependencies, Dependency{Name: fields[0], Version: fields[1]})
+		}
+	}
+
+	return manifest
+}
+
+// parsePackageJSON reads dependencies and devDependencies
+func parsePackageJSON(content string) *Manifest {
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return nil
+	}
+
+	manifest := &Manifest{Ecosystem: "npm"}
+	manifest.Dependencies = append(manifest.Dependencies, sortedDependencies(pkg.Dependencies, false)...)
+	manifest.Dependencies = append(manifest.Dependencies, sortedDependencies(pkg.DevDependencies, true)...)
+	return manifest
+}
+
+// parseComposerJSON reads require and require-dev
+func parseComposerJSON(content string) *Manifest {
+	var pkg struct {
+		Require    map[string]string `json:"require"`
+		RequireDev map[string]string `json:"require-dev"`
+	}
+	if err := json.Unmarshal([]byte(content), &pkg); err != nil {
+		return nil
+	}
+
+	manifest := &Manifest{Ecosystem: "composer"}
+	manifest.Dependencies = append(manifest.Dependencies, sortedDependencies(pkg.Require, false)...)
+	manifest.Dependencies = append(manifest.Dependencies, sortedDependencies(pkg.RequireDev, true)...)
+	return manifest
+}
+
+// requirementPattern splits a PEP 508 requirement into name and version specifier
+var requirementPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9._-]*)(\[[^\]]*\])?\s*([<>=!~][^;#]*)?`)
+
+// parseRequirements reads a pip requirements file, skipping options and includes
+func parseRequirements(content string) *Manifest {
+	manifest := &Manifest{Ecosystem: "pypi"}
+
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "-") {
+			continue
+		}
+		if dep, ok := parseRequirement(line); ok {
+			manifest.Dependencies = append(manifest.Dependencies, dep)
+		}
+	}
+
+	return manifest
+}
+
+// parseRequirement parses a single PEP 508 requirement
+func parseRequirement(line string) (Dependency, bool) {
+	match := requirementPattern.FindStringSubmatch(line)
+	if match == nil {
+		return Dependency{}, false
+	}
+	return Dependency{Name: match[1], Version: strings.TrimSpace(match[3])}, true
+}
+
+// parsePyproject reads PEP 621 project dependencies and Poetry dependency tables
+func parsePyproject(content string) *Manifest {
+	manifest := &Manifest{Ecosystem: "pypi"}
+
+	for _, section := range parseTomlSections(content) {
+		switch section.name {
+		case "project":
+			for _, req := range tomlStringArray(section.lines, "dependencies") {
+				if dep, ok := parseRequirement(req); ok {
+					manifest.Dependencies = append(manifest.Dependencies, dep)
+				}
+			}
+		case "tool.poetry.dependencies", "tool.poetry.dev-dependencies", "tool.poetry.group.dev.dependencies":
+			dev := section.name != "tool.poetry.dependencies"
+			for _, dep := range tomlDependencyTable(section.lines, dev) {
+				if dep.Name != "python" {
+					manifest.Dependencies = append(manifest.Dependencies, dep)
+				}
+			}
+		}
+	}
+
+	return manifest
+}
+
+// parseCargoToml reads the dependency tables of a Cargo manifest
+func parseCargoToml(content string) *Manifest {
+	manifest := &Manifest{Ecosystem: "cargo"}
+
+	for _, section := range parseTomlSections(content) {
+		switch section.name {
+		case "dependencies", "dev-dependencies", "build-dependencies":
+			dev := section.name == "dev-dependencies"
+			manifest.Dependencies = append(manifest.Dependencies, tomlDependencyTable(section.lines, dev)...)
+		}
+	}
+
+	return manifest
+}
+
+// parsePomXML reads the dependencies of a Maven project
+func parsePomXML(content string) *Manifest {
+	var pom struct {
+		Dependencies []struct {
+			GroupID    string `xml:"groupId"`
+			ArtifactID string `xml:"artifactId"`
+			Version    string `xml:"version"`
+			Scope      string `xml:"scope"`
+		} `xml:"dependencies>dependency"`
+	}
+	if err := xml.Unmarshal([]byte(content), &pom); err != nil {
+		return nil
+	}
+
+	manifest := &Manifest{Ecosystem: "maven"}
+	for _, dep := range pom.Dependencies {
+		manifest.Dependencies = append(manifest.Dependencies, Dependency{
+			Name:    dep.GroupID + ":" + dep.ArtifactID,
+			Version: dep.Version,
+			Dev:     dep.Scope == "test",
+		})
+	}
+	return manifest
+}
+
+// gemPattern matches a gem declaration with an optional version constraint
+var gemPattern = regexp.MustCompile(`^gem\s+['"]([^'"]+)['"](?:\s*,\s*['"]([^'"]+)['"])?`)
+
+// parseGemfile reads gem declarations from a Bundler Gemfile
+func parseGemfile(content string) *Manifest {
+	manifest := &Manifest{Ecosystem: "rubygems"}
+
+	for _, line := range strings.Split(content, "\n") {
+		if match := gemPattern.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			manifest.Dependencies = append(manifest.Dependencies, Dependency{Name: match[1], Version: match[2]})
+		}
+	}
+
+	return manifest
+}
+
+// sortedDependencies converts a name to version map into dependencies sorted by name
+func sortedDependencies(m map[string]string, dev bool) []Dependency {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]Dependency, 0, len(names))
+	for _, name := range names {
+		result = append(result, Dependency{Name: name, Version: m[name], Dev: dev})
+	}
+	return result
+}
+
+// tomlSection is a [table] of a TOML document with its raw lines
+type tomlSection struct {
+	name  string
+	lines []string
+}
+
+// parseTomlSections splits a TOML document into its top-level tables. This
+// is not a full TOML parser; it covers the shapes used by dependency manifests.
+func parseTomlSections(content string) []tomlSection {
+	sections := []tomlSection{{}}
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "[[") && strings.HasSuffix(trimmed, "]") {
+			sections = append(sections, tomlSection{name: strings.Trim(trimmed, "[] ")})
+			continue
+		}
+		sections[len(sections)-1].lines = append(sections[len(sections)-1].lines, trimmed)
+	}
+
+	return sections
+}
+
+// tomlVersionPattern extracts the version key of an inline dependency table
+var tomlVersionPattern = regexp.MustCompile(`version\s*=\s*"([^"]*)"`)
+
+// tomlDependencyTable reads `name = "version"` and `name = { version = "..." }` entries
+func tomlDependencyTable(lines []string, dev bool) []Dependency {
+	var result []Dependency
+
+	for _, line := range lines {
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		dep := Dependency{Name: strings.Trim(strings.TrimSpace(name), `"`), Dev: dev}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			dep.Version = strings.Trim(value, `"`)
+		} else if match := tomlVersionPattern.FindStringSubmatch(value); match != nil {
+			dep.Version = match[1]
+		}
+		result = append(result, dep)
+	}
+
+	return result
+}
+
+// tomlStringPattern matches a double- or single-quoted TOML string
+var tomlStringPattern = regexp.MustCompile(`"([^"]*)"|'([^']*)'`)
+
+// tomlStringArray reads a possibly multi-line array of strings assigned to key
+func tomlStringArray(lines []string, key string) []string {
+	var result []string
+	inArray := false
+
+	for _, line := range lines {
+		if !inArray {
+			name, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(name) != key {
+				continue
+			}
+			line = strings.TrimSpace(value)
+			inArray = true
+		}
+
+		for _, part := range tomlStringPattern.FindAllStringSubmatch(line, -1) {
+			result = append(result, part[1]+part[2])
+		}
+		if strings.Contains(line, "]") {
+			break
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,205 @@
+// Package dockersource reads the filesystem of container images, to audit
+// what actually ships inside them.
+package dockersource
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// scheme prefixes container image sources
+const scheme = "docker://"
+
+// IsURL reports whether source is a docker://image:tag reference
+func IsURL(source string) bool {
+	return strings.HasPrefix(source, scheme)
+}
+
+// Fetch unpacks the flattened filesystem of the image named by a
+// docker://image:tag source into a new temporary directory, pulling the
+// image if it is not available locally. With cfg.Subpath, such as /app,
+// only that directory of the image is read. The files are streamed from a
+// container that is created but never started, and only the regular files
+// passing the include/exclude patterns and size limits of cfg are written.
+// It returns the local directory to analyze and a cleanup function removing
+// it.
+func Fetch(source string, cfg *config.Config) (string, func(), error) {
+	image := strings.TrimPrefix(source, scheme)
+	if image == "" || strings.HasPrefix(image, "-") || strings.ContainsAny(image, " \t\n") {
+		return "", nil, fmt.Errorf("invalid image reference: %s", source)
+	}
+	subpath := path.Clean("/" + filepath.ToSlash(cfg.Subpath))
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", nil, fmt.Errorf("docker:// sources require the docker command-line tool: %w", err)
+	}
+
+	if _, err := run("image", "inspect", "--format", "{{.Id}}", image); err != nil {
+		slog.Info("Pulling image", "image", image)
+		if _, err := run("pull", "--quiet", image); err != nil {
+			return "", nil, err
+		}
+	}
+
+	// A container gives access to the flattened filesystem; the command is
+	// never run, and is only there for images without one
+	out, err := run("create", image, "/ingest")
+	if err != nil {
+		return "", nil, err
+	}
+	container := strings.TrimSpace(string(out))
+	defer func() {
+		if _, err := run("rm", container); err != nil {
+			slog.Warn("Failed to remove container", "container", container, "error", err)
+		}
+	}()
+
+	name := path.Base(subpath)
+	if subpath == "/" {
+		name = imageName(image)
+	}
+	tmp, err := os.MkdirTemp("", "ingest-docker-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	root := filepath.Join(tmp, name)
+	if err := os.MkdirAll(root, 0755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	// docker cp archives the directory under its own name, which is dropped
+	args := []string{"export", container}
+	if subpath != "/" {
+		args = []string{"cp", container + ":" + subpath, "-"}
+	}
+	if err := stream(args, func(archive io.Reader) error {
+		return extract(archive, root, subpath != "/", cfg)
+	}); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return root, cleanup, nil
+}
+
+// imageName returns the repository name of an image reference, such as
+// "app" for ghcr.io/org/app:1.2 or app@sha256:...
+func imageName(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	name = name[strings.LastIndex(name, "/")+1:]
+	name, _, _ = strings.Cut(name, ":")
+	if name == "" {
+		return "image"
+	}
+	return name
+}
+
+// extract unpacks the regular files of a tar archive below root that pass
+// the include/exclude patterns and size limits of cfg, dropping the first
+// path component of every entry if stripTop is set
+func extract(archive io.Reader, root string, stripTop bool, cfg *config.Config) error {
+	reader := tar.NewReader(archive)
+	files, total := 0, int64(0)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		rel := strings.TrimPrefix(path.Clean("/"+header.Name), "/")
+		if stripTop {
+			_, rel, _ = strings.Cut(rel, "/")
+		}
+		if rel == "" || rel == "." {
+			// A single file copied by docker cp keeps its own name
+			rel = path.Base(header.Name)
+		}
+
+		dest := filepath.Join(root, filepath.FromSlash(rel))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in image archive: %s", header.Name)
+		}
+		if !cfg.ShouldIncludeTree(root, dest) {
+			continue
+		}
+
+		// Apply the same limits as local traversal before writing
+		if header.Size > cfg.MaxFileSize || files >= cfg.MaxFiles || total+header.Size > cfg.MaxTotalSize {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, header.FileInfo().Mode().Perm()|0600)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, reader); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		os.Chtimes(dest, header.ModTime, header.ModTime)
+
+		files++
+		total += header.Size
+	}
+}
+
+// stream runs a docker command and passes its standard output to read, so
+// large images are never held in memory
+func stream(args []string, read func(io.Reader) error) error {
+	var stderr bytes.Buffer
+	slog.Debug("Running docker", "args", strings.Join(args, " "))
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	readErr := read(stdout)
+	// Drain the rest so docker is not blocked writing
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return readErr
+}
+
+// run executes a docker command and returns its standard output
+func run(args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	slog.Debug("Running docker", "args", strings.Join(args, " "))
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,196 @@
+package embed
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Supported embedding providers
+const (
+	OpenAI = "openai"
+	Ollama = "ollama"
+)
+
+// batchSize is the number of texts sent in one request
+const batchSize = 64
+
+// provider describes how to reach an embedding API
+type provider struct {
+	model   string                                 // Default model
+	baseURL func() string                          // API base URL, from the environment or the default
+	path    string                                 // Endpoint path below the base URL
+	token   func() (string, error)                 // Bearer token, if the API requires one
+	parse   func(body []byte) ([][]float64, error) // Vectors from a response body
+}
+
+// providers maps provider names to their APIs
+var providers = map[string]*provider{
+	OpenAI: {
+		model: "text-embedding-3-small",
+		baseURL: func() string {
+			return envOr("OPENAI_BASE_URL", "https://api.openai.com/v1")
+		},
+		path: "/embeddings",
+		token: func() (string, error) {
+			if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+				return key, nil
+			}
+			return "", fmt.Errorf("openai embeddings require OPENAI_API_KEY")
+		},
+		parse: parseOpenAI,
+	},
+	Ollama: {
+		model: "nomic-embed-text",
+		baseURL: func() string {
+			host := envOr("OLLAMA_HOST", "http://localhost:11434")
+			if !strings.Contains(host, "://") {
+				host = "http://" + host
+			}
+			return host
+		},
+		path:  "/api/embed",
+		parse: parseOllama,
+	},
+}
+
+// Client computes embeddings through a provider's HTTP API
+type Client struct {
+	Model string // Embedding model
+
+	provider *provider
+	url      string
+	token    string
+	http     *http.Client
+}
+
+// Valid reports whether provider is a supported embedding provider
+func Valid(provider string) bool {
+	return providers[provider] != nil
+}
+
+// New returns a client for the named provider, using its default model if
+// model is empty. OpenAI reads OPENAI_API_KEY and optionally
+// OPENAI_BASE_URL for compatible servers; Ollama reads OLLAMA_HOST.
+func New(name, model string) (*Client, error) {
+	p := providers[name]
+	if p == nil {
+		return nil, fmt.Errorf("unknown embedding provider '%s'", name)
+	}
+	if model == "" {
+		model = p.model
+	}
+
+	client := &Client{
+		Model:    model,
+		provider: p,
+		url:      strings.TrimSuffix(p.baseURL(), "/") + p.path,
+		http:     &http.Client{Timeout: 5 * time.Minute},
+	}
+	if p.token != nil {
+		token, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		client.token = token
+	}
+	return client, nil
+}
+
+// Embed returns the embedding of every text, in order, sending them in
+// batches
+func (c *Client) Embed(texts []string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += batchSize {
+		batch := texts[start:min(start+batchSize, len(texts))]
+
+		embeddings, err := c.embedBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(embeddings) != len(batch) {
+			return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", c.url, len(embeddings), len(batch))
+		}
+		vectors = append(vectors, embeddings...)
+	}
+	return vectors, nil
+}
+
+// embedBatch sends one request; both APIs take the model and a list of inputs
+func (c *Client) embedBatch(texts []string) ([][]float64, error) {
+	body, err := json.Marshal(map[string]any{"model": c.Model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s: %s", c.url, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return c.provider.parse(data)
+}
+
+// parseOpenAI reads an OpenAI embeddings response, whose items carry their
+// input index
+func parseOpenAI(body []byte) ([][]float64, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float64 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+
+	vectors := make([][]float64, len(response.Data))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(vectors) {
+			return nil, fmt.Errorf("invalid embeddings response: index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
+
+// parseOllama reads an Ollama /api/embed response
+func parseOllama(body []byte) ([][]float64, error) {
+	var response struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("invalid embeddings response: %w", err)
+	}
+	return response.Embeddings, nil
+}
+
+// envOr returns the environment variable, or fallback if it is unset
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,151 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+)
+
+// dirRoles maps conventional top-level directory names to their roles
+var dirRoles = map[string]string{
+	"cmd": "executables", "bin": "executables and scripts",
+	"pkg": "library packages", "lib": "library code", "internal": "private packages",
+	"src": "sources", "app": "application code",
+	"api": "API definitions", "proto": "protocol definitions", "schemas": "schemas",
+	"migrations": "database migrations", "db": "database",
+	"web": "frontend", "ui": "frontend", "frontend": "frontend", "client": "client",
+	"server": "server", "backend": "backend",
+	"test": "tests", "tests": "tests", "e2e": "end-to-end tests", "testdata": "test fixtures",
+	"docs": "documentation", "doc": "documentation", "examples": "examples",
+	"scripts": "scripts", "tools": "tools", "hack": "development scripts",
+	"build": "build configuration", "deploy": "deployment", "deployments": "deployment",
+	"charts": "Helm charts", "k8s": "Kubernetes manifests", "infra": "infrastructure",
+	"terraform": "infrastructure", "config": "configuration", "configs": "configuration",
+	"assets": "static assets", "static": "static assets", "public": "static assets",
+	"vendor": "vendored dependencies", "third_party": "third-party code",
+	".github": "CI workflows", ".circleci": "CI configuration",
+}
+
+// formatArchitecture maps the top-level directories below root with their
+// inferred roles and counts, followed by the import edges between the Go
+// packages within root, or returns an empty string for a single file
+func formatArchitecture(root *analyzer.FileSystemNode) string {
+	if !root.IsDir {
+		return ""
+	}
+
+	packages := parseGoPackages(root)
+	var builder strings.Builder
+	builder.WriteString("Architecture:\n")
+
+	var dirs []*analyzer.FileSystemNode
+	topFiles := 0
+	width := 0
+	for _, child := range root.Children {
+		if child.IsDir {
+			dirs = append(dirs, child)
+			width = max(width, len(child.Name)+1)
+		} else {
+			topFiles++
+		}
+	}
+
+	for _, dir := range dirs {
+		counts := []string{pluralize(dir.FileCount, "file")}
+		if n := countPackagesBelow(packages, dir.Name); n > 0 {
+			counts = append(counts, pluralize(n, "Go package"))
+		}
+		if n := countEntrypoints(dir); n > 0 {
+			counts = append(counts, pluralize(n, "entrypoint"))
+		}
+		counts = append(counts, "~"+formatTokenCount(estimateTokens(dir))+" tokens")
+
+		description := strings.Join(counts, ", ")
+		if role := dirRoles[strings.ToLower(dir.Name)]; role != "" {
+			description = role + ": " + description
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s %s\n", width, dir.Name+"/", description))
+	}
+	if topFiles > 0 {
+		builder.WriteString(fmt.Sprintf("  %s at the top level\n", pluralize(topFiles, "file")))
+	}
+
+	builder.WriteString(formatImportEdges(packages))
+	return builder.String()
+}
+
+// formatImportEdges lists, for every Go package within the root, the
+// packages within the root it imports
+func formatImportEdges(packages map[string]*goPackage) string {
+	edges := map[string]map[string]bool{}
+	count := 0
+	for dir, pkg := range packages {
+		for _, syntax := range pkg.Files {
+			for _, spec := range syntax.Imports {
+				importPath, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				target := packageForImport(importPath, packages)
+				if target == nil || target.Dir == dir {
+					continue
+				}
+				if edges[dir] == nil {
+					edges[dir] = map[string]bool{}
+				}
+				if !edges[dir][target.Dir] {
+					edges[dir][target.Dir] = true
+					count++
+				}
+			}
+		}
+	}
+	if count == 0 {
+		return ""
+	}
+
+	dirs := make([]string, 0, len(edges))
+	for dir := range edges {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("\nGo package imports (%s, %s):\n",
+		pluralize(len(packages), "package"), pluralize(count, "edge")))
+	for _, dir := range dirs {
+		targets := make([]string, 0, len(edges[dir]))
+		for target := range edges[dir] {
+			targets = append(targets, displayDir(target))
+		}
+		sort.Strings(targets)
+		builder.WriteString(fmt.Sprintf("  %s -> %s\n", displayDir(dir), strings.Join(targets, ", ")))
+	}
+	return builder.String()
+}
+
+// countPackagesBelow counts the Go packages in the top-level directory dir
+// and below it
+func countPackagesBelow(packages map[string]*goPackage, dir string) int {
+	count := 0
+	for pkgDir := range packages {
+		if pkgDir == dir || strings.HasPrefix(pkgDir, dir+"/") {
+			count++
+		}
+	}
+	return count
+}
+
+// countEntrypoints counts the files below node that start execution
+func countEntrypoints(node *analyzer.FileSystemNode) int {
+	count := 0
+	for _, file := range node.Files() {
+		if file.Entrypoint != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,97 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// syntheticDigest analyzes a synthetic tree of dirs directories of files Go
+// sources each, with lines functions per file
+func syntheticDigest(b *testing.B, dirs, files, lines int) *Digest {
+	b.Helper()
+	dir := b.TempDir()
+
+	var body strings.Builder
+	for i := 0; i < lines; i++ {
+		fmt.Fprintf(&body, "// Func%d returns its argument plus %d\nfunc Func%d(x int) int { return x + %d }\n", i, i, i, i)
+	}
+	for d := 0; d < dirs; d++ {
+		sub := filepath.Join(dir, fmt.Sprintf("pkg%03d", d))
+		if err := os.MkdirAll(sub, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for f := 0; f < files; f++ {
+			content := fmt.Sprintf("package pkg%03d\n\n// File %d\n%s", d, f, body.String())
+			if err := os.WriteFile(filepath.Join(sub, fmt.Sprintf("file%03d.go", f)), []byte(content), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	cfg := config.NewConfig()
+	cfg.Source = dir
+	cfg.NoGit = true
+	cfg.TimestampFrom = config.TimestampNone
+	cfg.GoSymbols = true
+	cfg.Todos = true
+
+	result, err := analyzer.ProcessPath(dir, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return NewDigest([]*analyzer.FileSystemNode{result.Root}, NewHeader("ingest", "bench", cfg, nil), cfg)
+}
+
+func BenchmarkNewDigest(b *testing.B) {
+	digest := syntheticDigest(b, 20, 20, 100)
+	nodes := []*analyzer.FileSystemNode{digest.Sources[0].Root}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		NewDigest(nodes, digest.Header, digest.cfg)
+	}
+}
+
+func BenchmarkText(b *testing.B) {
+	digest := syntheticDigest(b, 20, 20, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		digest.Text()
+	}
+}
+
+func BenchmarkWriteJSONL(b *testing.B) {
+	digest := syntheticDigest(b, 20, 20, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := digest.WriteJSONL(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkWriteChunks(b *testing.B) {
+	digest := syntheticDigest(b, 20, 20, 100)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Chunks are cached on the digest, so measure the split every time
+		digest.chunks = nil
+		if err := digest.WriteChunks(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
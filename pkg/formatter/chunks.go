@@ -0,0 +1,180 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// Chunk is a window of a file's content sized for embedding
+type Chunk struct {
+	File      *FileEntry // File the chunk belongs to
+	ID        string     // Unique ID: path and chunk index, such as "main.go#2"
+	Index     int        // Position of the chunk within the file, from 0
+	StartLine int        // First line of the chunk (1-based)
+	EndLine   int        // Last line of the chunk (inclusive)
+	Tokens    int        // Estimated tokens of the content
+	Content   string     // Text of the chunk
+	Embedding []float64  // Embedding vector, if embeddings were requested
+}
+
+// chunkRecord is a single line of chunks output
+type chunkRecord struct {
+	Type      string    `json:"type"`                // Always "chunk"
+	ID        string    `json:"id"`                  // Unique ID: path and chunk index
+	Source    string    `json:"source"`              // Identity of the analyzed source
+	Path      string    `json:"path"`                // Slash-separated path relative to the source
+	Index     int       `json:"index"`               // Position of the chunk within the file, from 0
+	StartLine int       `json:"start_line"`          // First line of the chunk (1-based)
+	EndLine   int       `json:"end_line"`            // Last line of the chunk (inclusive)
+	Language  string    `json:"language,omitempty"`  // Detected language
+	Tokens    int       `json:"tokens"`              // Estimated tokens of the content
+	SHA256    string    `json:"sha256,omitempty"`    // SHA-256 of the raw file contents
+	Content   string    `json:"content"`             // Text of the chunk
+	Embedding []float64 `json:"embedding,omitempty"` // Embedding vector (with --embed)
+}
+
+// chunkSegment is a line of content, or a piece of a line too long for a
+// chunk, with its 1-based line number
+type chunkSegment struct {
+	text string
+	line int
+}
+
+// Chunks splits the text files of the digest into chunks of at most the
+// configured number of tokens, where consecutive chunks of a file share
+// about the configured overlap. Binary files, placeholders of duplicates,
+// and empty files are left out. The chunks are computed once, so
+// embeddings set on them are kept.
+func (d *Digest) Chunks() []*Chunk {
+	if d.chunks != nil {
+		return d.chunks
+	}
+
+	d.chunks = []*Chunk{}
+	for _, source := range d.Sources {
+		for _, file := range source.Files {
+			if file.Node.IsBinary || file.DuplicateOf != "" || strings.TrimSpace(file.Content) == "" {
+				continue
+			}
+
+			for i, segments := range chunkContent(file.Content, d.cfg.ChunkTokens, d.cfg.ChunkOverlap) {
+				chunk := &Chunk{
+					File:      file,
+					ID:        fmt.Sprintf("%s#%d", file.Path, i),
+					Index:     i,
+					StartLine: segments[0].line,
+					EndLine:   segments[len(segments)-1].line,
+					Content:   joinSegments(segments),
+				}
+				chunk.Tokens = utils.EstimateTokens(chunk.Content)
+				d.chunks = append(d.chunks, chunk)
+			}
+		}
+	}
+
+	return d.chunks
+}
+
+// WriteChunks writes the chunks of the digest as JSON lines
+func (d *Digest) WriteChunks(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	for _, chunk := range d.Chunks() {
+		record := chunkRecord{
+			Type:      "chunk",
+			ID:        chunk.ID,
+			Source:    d.Header.Source,
+			Path:      chunk.File.Path,
+			Index:     chunk.Index,
+			StartLine: chunk.StartLine,
+			EndLine:   chunk.EndLine,
+			Language:  chunk.File.Language,
+			Tokens:    chunk.Tokens,
+			SHA256:    chunk.File.SHA256,
+			Content:   chunk.Content,
+			Embedding: chunk.Embedding,
+		}
+
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// chunkContent splits content into windows of whole lines of at most size
+// tokens, each starting so that it repeats about overlap tokens of the
+// previous one. Lines longer than a chunk are split into pieces.
+func chunkContent(content string, size, overlap int) [][]chunkSegment {
+	segments := splitSegments(content, size)
+
+	var chunks [][]chunkSegment
+	for start := 0; start < len(segments); {
+		// Take segments while they fit, but always at least one
+		end, tokens := start, 0
+		for end < len(segments) && (end == start || tokens+segmentTokens(segments[end]) <= size) {
+			tokens += segmentTokens(segments[end])
+			end++
+		}
+		chunks = append(chunks, segments[start:end])
+		if end == len(segments) {
+			break
+		}
+
+		// Step back over the overlap, always moving forward
+		next, shared := end, 0
+		for next-1 > start && shared+segmentTokens(segments[next-1]) <= overlap {
+			next--
+			shared += segmentTokens(segments[next])
+		}
+		start = next
+	}
+
+	return chunks
+}
+
+// splitSegments splits content into lines, and lines longer than size
+// tokens into pieces of that size
+func splitSegments(content string, size int) []chunkSegment {
+	maxBytes := size * 4 // Matches the estimate of 4 bytes per token
+	var segments []chunkSegment
+
+	for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		for len(line) >= maxBytes {
+			cut := maxBytes - 1
+			for cut > 0 && !utf8.RuneStart(line[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(line)
+			}
+			segments = append(segments, chunkSegment{text: line[:cut], line: i + 1})
+			line = line[cut:]
+		}
+		segments = append(segments, chunkSegment{text: line + "\n", line: i + 1})
+	}
+
+	return segments
+}
+
+// segmentTokens estimates the tokens of a segment, rounding up so that the
+// segments of a chunk never add up to less than the chunk's estimate
+func segmentTokens(segment chunkSegment) int {
+	return (len(segment.text) + 3) / 4
+}
+
+// joinSegments returns the text of a chunk
+func joinSegments(segments []chunkSegment) string {
+	var builder strings.Builder
+	for _, segment := range segments {
+		builder.WriteString(segment.text)
+	}
+	return builder.String()
+}
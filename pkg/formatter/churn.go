@@ -0,0 +1,61 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/metrics"
+)
+
+// formatChurn ranks the files below root changed by the most commits since
+// cfg.ChurnSince, then by the most authors, with their complexity where it
+// can be computed. Without git it returns an empty string, since the
+// summary already notes why.
+func formatChurn(root *analyzer.FileSystemNode, cfg *config.Config) string {
+	if !cfg.Git().Available() {
+		return ""
+	}
+
+	var files []*analyzer.FileSystemNode
+	for _, file := range root.Files() {
+		if file.Commits > 0 {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return fmt.Sprintf("Churn hotspots: no changes since %s\n", cfg.ChurnSince)
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].Commits != files[j].Commits {
+			return files[i].Commits > files[j].Commits
+		}
+		if files[i].Authors != files[j].Authors {
+			return files[i].Authors > files[j].Authors
+		}
+		return files[i].RelPath(root) < files[j].RelPath(root)
+	})
+	changed := len(files)
+	files = files[:min(len(files), maxHotspots)]
+
+	width := 0
+	for _, file := range files {
+		width = max(width, len(file.RelPath(root)))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Churn hotspots (most commits since %s, of %s):\n", cfg.ChurnSince, pluralize(changed, "changed file")))
+	for _, file := range files {
+		detail := pluralize(file.Commits, "commit") + ", " + pluralize(file.Authors, "author")
+		if measurable(file) {
+			if complexity, ok := metrics.CyclomaticComplexity(file.Language, file.Content); ok {
+				detail += fmt.Sprintf(", complexity %d", complexity.Total)
+			}
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s  %s\n", width, file.RelPath(root), detail))
+	}
+	return builder.String()
+}
@@ -0,0 +1,36 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// FormatCost renders the estimated cost of sending the given number of input
+// tokens to each priced model, or returns an empty string without prices
+func FormatCost(tokens int, prices []config.Price) string {
+	if len(prices) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, price := range prices {
+		width = max(width, len(price.Model))
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Estimated input cost:\n")
+	for _, price := range prices {
+		builder.WriteString(fmt.Sprintf("  %-*s %s\n", width, price.Model, formatCost(price.Cost(tokens))))
+	}
+	return builder.String()
+}
+
+// formatCost formats a cost in USD, showing sub-cent amounts as "< $0.01"
+func formatCost(usd float64) string {
+	if usd > 0 && usd < 0.005 {
+		return "< $0.01"
+	}
+	return fmt.Sprintf("$%.2f", usd)
+}
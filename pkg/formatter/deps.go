@@ -0,0 +1,40 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/deps"
+)
+
+// formatDependencies summarizes the direct dependencies of every recognized
+// manifest, or returns an empty string if there are none
+func formatDependencies(root *analyzer.FileSystemNode) string {
+	var builder strings.Builder
+
+	for _, file := range root.Files() {
+		manifest := deps.Parse(file.Name, file.Content)
+		if manifest == nil {
+			continue
+		}
+
+		builder.WriteString(fmt.Sprintf("%s (%s, %d direct):\n", file.RelPath(root), manifest.Ecosystem,
+			len(manifest.Dependencies)))
+		for _, dep := range manifest.Dependencies {
+			line := "  " + dep.Name
+			if dep.Version != "" {
+				line += " " + dep.Version
+			}
+			if dep.Dev {
+				line += " (dev)"
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "Dependencies:\n" + builder.String()
+}
@@ -0,0 +1,303 @@
+package formatter
+
+import (
+	"io/fs"
+	"strings"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// Digest is the structured result of a run: the header and, for every
+// analyzed source, its summary, tree, files in digest order, and optional
+// sections. Library users can consume it directly; Text, WriteJSON, Markdown,
+// WriteJSONL, WriteChunks, WriteMermaid, WriteDOT, WriteSQLite, and Template
+// render it in each output format.
+type Digest struct {
+	Header  *Header   // Tool, version, timestamp, and source identity
+	SHA256  string    // SHA-256 of the checksum manifest across all sources
+	Sources []*Source // Each analyzed file or directory
+
+	cfg    *config.Config
+	chunks []*Chunk // Chunks of the text files, computed on first use
+}
+
+// Source is one analyzed file or directory within a digest
+type Source struct {
+	Root       *analyzer.FileSystemNode // Root node of the source
+	Summary    Summary                  // Aggregate figures of the source
+	Tree       *TreeEntry               // Directory structure below the root
+	Files      []*FileEntry             // Files in digest order
+	Sections   []Section                // Optional sections shown before the files, in order
+	Appendices []Section                // Optional sections shown after the files, in order
+}
+
+// Names of the optional sections of a source
+const (
+	SectionArchitecture = "architecture"
+	SectionDependencies = "dependencies"
+	SectionTerraform    = "terraform"
+	SectionTodos        = "todos"
+	SectionHotspots     = "hotspots"
+	SectionChurn        = "churn"
+	SectionPullRequest  = "pull_request"
+	SectionPatch        = "patch"
+	SectionSymbols      = "symbols"
+)
+
+// Section is an optional text section of a source, such as the dependency
+// summary or the churn ranking
+type Section struct {
+	Name string // One of the Section* names
+	Text string // Rendered section, ending with a newline
+}
+
+// License is a license or notice file at the top level of a source
+type License struct {
+	File   string // Base name of the file
+	SPDX   string // Identified SPDX license, or "unknown" (empty for notices)
+	Notice bool   // Whether the file is a NOTICE file rather than a license
+}
+
+// Summary holds the aggregate figures of a source
+type Summary struct {
+	Name          string          // Base name of the source
+	IsDir         bool            // Whether the source is a directory
+	Files         int             // Number of files analyzed
+	Dirs          int             // Number of directories analyzed
+	Size          int64           // Total size in bytes
+	Lines         int             // Lines of a file source
+	Tokens        int             // Estimated tokens across all files
+	SHA256        string          // File SHA-256 or tree checksum manifest SHA-256
+	Extensions    []ExtensionStat // Per-extension figures, largest first
+	Duplicates    int             // Files replaced by a reference to an identical file
+	DuplicateSize int64           // Bytes saved by deduplication
+	Stats         *config.Stats   // Omissions and skipped paths (directories only)
+	Licenses      []License       // License and notice files at the top level
+	GitNote       string          // Why git-based features were skipped, if they were
+}
+
+// TreeEntry is a file or directory in the directory structure
+type TreeEntry struct {
+	Name       string       // Base name
+	Path       string       // Slash-separated path relative to the source
+	IsDir      bool         // Whether the entry is a directory
+	Files      int          // Files in the directory and below
+	Dirs       int          // Directories below the directory
+	Size       int64        // Size in bytes, summed for directories
+	Tokens     int          // Estimated tokens, summed for directories
+	ModTime    time.Time    // Last modification time
+	Mode       fs.FileMode  // File mode and permission bits
+	LinkTarget string       // Target of the symbolic link
+	Entrypoint string       // How the file starts execution, such as "func main"
+	Children   []*TreeEntry // Entries of a directory in tree order
+}
+
+// FileEntry is a file as it appears in the digest
+type FileEntry struct {
+	Node        *analyzer.FileSystemNode // Underlying file node
+	Path        string                   // Slash-separated path relative to the source
+	Header      string                   // Path shown in the text file header
+	Language    string                   // Detected language
+	MIME        string                   // MIME type detected from the contents
+	Size        int64                    // Size in bytes
+	Tokens      int                      // Estimated tokens of the content
+	SHA256      string                   // SHA-256 of the raw file contents
+	ModTime     time.Time                // Last modification time
+	Mode        fs.FileMode              // File mode and permission bits
+	LinkTarget  string                   // Target of the symbolic link
+	DuplicateOf string                   // Path of the identical file included instead
+	Content     string                   // File content or placeholder
+}
+
+// NewDigest builds the digest of the analyzed nodes
+func NewDigest(nodes []*analyzer.FileSystemNode, header *Header, cfg *config.Config) *Digest {
+	digest := &Digest{
+		Header: header,
+		SHA256: analyzer.ManifestSHA256(nodes...),
+		cfg:    cfg,
+	}
+
+	for _, root := range nodes {
+		digest.Sources = append(digest.Sources, newSource(root, cfg))
+	}
+
+	return digest
+}
+
+// newSource builds the model of an analyzed source: its summary, tree,
+// files in digest order, and the optional sections enabled by cfg
+func newSource(root *analyzer.FileSystemNode, cfg *config.Config) *Source {
+	source := &Source{
+		Root: root,
+		Tree: newTreeEntry(root, root),
+	}
+	for _, file := range orderedFiles(root, cfg) {
+		source.Files = append(source.Files, newFileEntry(root, file))
+	}
+
+	// Map the layout and summarize the manifests before the raw contents
+	addSection := func(sections *[]Section, name, text string) {
+		if text != "" {
+			*sections = append(*sections, Section{Name: name, Text: text})
+		}
+	}
+	if cfg.Architecture {
+		addSection(&source.Sections, SectionArchitecture, formatArchitecture(root))
+	}
+	if !cfg.NoDeps {
+		addSection(&source.Sections, SectionDependencies, formatDependencies(root))
+	}
+	if !cfg.NoTerraform {
+		addSection(&source.Sections, SectionTerraform, formatTerraform(root))
+	}
+	if cfg.Todos {
+		addSection(&source.Sections, SectionTodos, formatTodos(root))
+	}
+	if cfg.Hotspots {
+		addSection(&source.Sections, SectionHotspots, formatHotspots(root))
+	}
+	if cfg.Churn {
+		addSection(&source.Sections, SectionChurn, formatChurn(root, cfg))
+	}
+
+	// Show the changes, and the discussion of a pull request, before the
+	// current contents of the files
+	if cfg.PullRequest != nil {
+		addSection(&source.Sections, SectionPullRequest, formatPullRequest(cfg.PullRequest))
+	}
+	if cfg.Patch != "" {
+		addSection(&source.Sections, SectionPatch, formatPatch(cfg.Patch))
+	}
+
+	// Cross-reference exported Go symbols after the contents
+	if cfg.GoSymbols {
+		addSection(&source.Appendices, SectionSymbols, formatGoSymbols(root))
+	}
+
+	// The sections may ask for git, so explain its absence after them
+	source.Summary = newSummary(root)
+	source.Summary.GitNote = cfg.Git().Note()
+	return source
+}
+
+// newSummary computes the summary figures of a source
+func newSummary(root *analyzer.FileSystemNode) Summary {
+	summary := Summary{
+		Name:     root.Name,
+		IsDir:    root.IsDir,
+		Files:    root.FileCount,
+		Dirs:     root.DirCount,
+		Size:     root.Size,
+		Tokens:   estimateTokens(root),
+		SHA256:   root.SHA256,
+		Licenses: licenses(root),
+	}
+
+	if root.IsDir {
+		summary.SHA256 = analyzer.ManifestSHA256(root)
+		summary.Extensions = ExtensionStats(root)
+		summary.Duplicates, summary.DuplicateSize = duplicateSavings(root)
+		summary.Stats = root.Stats
+	} else {
+		summary.Files = 1
+		summary.Lines = strings.Count(root.Content, "\n") + 1
+	}
+
+	return summary
+}
+
+// newTreeEntry builds the tree below node
+func newTreeEntry(root, node *analyzer.FileSystemNode) *TreeEntry {
+	entry := &TreeEntry{
+		Name:       node.Name,
+		Path:       node.RelPath(root),
+		IsDir:      node.IsDir,
+		Files:      node.FileCount,
+		Dirs:       node.DirCount,
+		Size:       node.Size,
+		Tokens:     estimateTokens(node),
+		ModTime:    node.ModTime,
+		Mode:       node.Mode,
+		LinkTarget: node.LinkTarget,
+		Entrypoint: node.Entrypoint,
+	}
+
+	for _, child := range node.Children {
+		entry.Children = append(entry.Children, newTreeEntry(root, child))
+	}
+	return entry
+}
+
+// newFileEntry describes a file below root
+func newFileEntry(root, file *analyzer.FileSystemNode) *FileEntry {
+	return &FileEntry{
+		Node:        file,
+		Path:        file.RelPath(root),
+		Header:      headerPath(file),
+		Language:    file.Language,
+		MIME:        file.MIME,
+		Size:        file.Size,
+		Tokens:      utils.EstimateTokens(file.Content),
+		SHA256:      file.SHA256,
+		ModTime:     file.ModTime,
+		Mode:        file.Mode,
+		LinkTarget:  file.LinkTarget,
+		DuplicateOf: file.DuplicateOf,
+		Content:     file.Content,
+	}
+}
+
+// Text renders the text digest: the header, an optional table of contents,
+// and each source's summary, sections, and file contents, wrapped in the
+// prompt of --prompt-file and --prompt
+func (d *Digest) Text() string {
+	output := ""
+	var tocEntries []TOCEntry
+
+	for i, source := range d.Sources {
+		// Add separator between multiple files
+		if i > 0 && d.cfg.Separator != "" {
+			output += "\n" + d.cfg.Separator + "\n\n"
+		} else if i > 0 {
+			output += "\n"
+		}
+
+		output += formatSummary(source, d.cfg) + "\n"
+		if warnings := formatWarnings(source); warnings != "" {
+			output += warnings + "\n"
+		}
+		output += formatDirectoryStructure(source, d.cfg) + "\n"
+		for _, section := range source.Sections {
+			output += section.Text + "\n"
+		}
+
+		// Shift file offsets to their position in the whole digest
+		contents, entries := formatFileContents(source, d.cfg)
+		offset, lines := len(output), strings.Count(output, "\n")
+		for _, entry := range entries {
+			entry.Offset += offset
+			entry.Line += lines
+			tocEntries = append(tocEntries, entry)
+		}
+
+		output += contents
+		for _, section := range source.Appendices {
+			output += section.Text + "\n"
+		}
+	}
+
+	// The issue threads belong to the whole digest rather than a source
+	if len(d.cfg.Issues) > 0 {
+		output += formatIssues(d.cfg.Issues) + "\n"
+	}
+
+	// The preamble precedes the table of contents, which must count it
+	prefix := promptPreamble(d.cfg) + d.Header.Text()
+	if d.cfg.TOC {
+		return appendPrompt(PrependTOC(prefix, output, tocEntries), d.cfg)
+	}
+	return appendPrompt(prefix+output, d.cfg)
+}
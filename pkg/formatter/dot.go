@@ -0,0 +1,69 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// Node sizes of the DOT tree in inches: a node with the most tokens of its
+// source gets dotMaxSize, and the area of the others shrinks with their
+// token count down to dotMinSize
+const (
+	dotMinSize = 0.3
+	dotMaxSize = 4.0
+)
+
+// WriteDOT writes the directory tree of every source as a Graphviz graph
+// whose node areas are proportional to the estimated tokens below them, so
+// rendering it shows where the bulk of the source lives. Directories are
+// folders and files are boxes, labeled with their token counts. The header
+// is written as comments.
+func (d *Digest) WriteDOT(w io.Writer) error {
+	var builder strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(d.Header.Text()), "\n") {
+		builder.WriteString("// " + line + "\n")
+	}
+	builder.WriteString("digraph tree {\n")
+	builder.WriteString("    rankdir=LR;\n")
+	builder.WriteString("    node [fontname=\"Helvetica\", fixedsize=shape, style=filled, fillcolor=\"#e8eef7\"];\n")
+	builder.WriteString("    edge [arrowhead=none];\n")
+
+	next := 0
+	var write func(entry *TreeEntry, parent string, largest int)
+	write = func(entry *TreeEntry, parent string, largest int) {
+		next++
+		id := fmt.Sprintf("n%d", next)
+
+		shape := "box"
+		if entry.IsDir {
+			shape = "folder"
+		}
+		size := dotMinSize
+		if largest > 0 {
+			size = max(dotMinSize, dotMaxSize*math.Sqrt(float64(entry.Tokens)/float64(largest)))
+		}
+		label := fmt.Sprintf("%s\\n~%s tokens", dotString(entry.Name), formatTokenCount(entry.Tokens))
+		builder.WriteString(fmt.Sprintf("    %s [label=\"%s\", shape=%s, width=%.2f, height=%.2f];\n", id, label, shape, size, size))
+		if parent != "" {
+			builder.WriteString(fmt.Sprintf("    %s -> %s;\n", parent, id))
+		}
+
+		for _, child := range entry.Children {
+			write(child, id, largest)
+		}
+	}
+	for _, source := range d.Sources {
+		write(source.Tree, "", source.Tree.Tokens)
+	}
+
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// dotString escapes s for a quoted DOT string
+func dotString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,100 @@
+package formatter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// maxSummaryExtensions is the number of extensions listed in the summary;
+// the stats subcommand lists all of them
+const maxSummaryExtensions = 10
+
+// ExtensionStat aggregates the files sharing an extension
+type ExtensionStat struct {
+	Extension string // Lowercase extension including the dot, or "(none)"
+	Files     int    // Number of files
+	Size      int64  // Total size in bytes
+	Tokens    int    // Estimated tokens of the included content
+}
+
+// ExtensionStats aggregates the files below the given roots by extension,
+// sorted by tokens, then size, descending
+func ExtensionStats(roots ...*analyzer.FileSystemNode) []ExtensionStat {
+	byExt := map[string]*ExtensionStat{}
+	for _, root := range roots {
+		for _, file := range root.Files() {
+			ext := strings.ToLower(filepath.Ext(file.Name))
+			if ext == "" || ext == file.Name {
+				ext = "(none)"
+			}
+
+			stat := byExt[ext]
+			if stat == nil {
+				stat = &ExtensionStat{Extension: ext}
+				byExt[ext] = stat
+			}
+			stat.Files++
+			stat.Size += file.Size
+			stat.Tokens += utils.EstimateTokens(file.Content)
+		}
+	}
+
+	stats := make([]ExtensionStat, 0, len(byExt))
+	for _, stat := range byExt {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		a, b := stats[i], stats[j]
+		if a.Tokens != b.Tokens {
+			return a.Tokens > b.Tokens
+		}
+		if a.Size != b.Size {
+			return a.Size > b.Size
+		}
+		return a.Extension < b.Extension
+	})
+	return stats
+}
+
+// FormatExtensionStats renders a table of per-extension counts, sizes, and
+// token shares. A positive limit lists only that many extensions.
+func FormatExtensionStats(stats []ExtensionStat, limit int) string {
+	total := 0
+	for _, stat := range stats {
+		total += stat.Tokens
+	}
+
+	shown := stats
+	if limit > 0 && len(shown) > limit {
+		shown = shown[:limit]
+	}
+
+	var builder strings.Builder
+	for _, stat := range shown {
+		share := 0.0
+		if total > 0 {
+			share = float64(stat.Tokens) * 100 / float64(total)
+		}
+		builder.WriteString(fmt.Sprintf("  %-10s %9s %10s %8s tokens %5.1f%%\n", stat.Extension,
+			pluralize(stat.Files, "file"), formatSize(stat.Size), formatTokenCount(stat.Tokens), share))
+	}
+	if len(shown) < len(stats) {
+		builder.WriteString(fmt.Sprintf("  ... and %s more\n", pluralize(len(stats)-len(shown), "extension")))
+	}
+
+	return builder.String()
+}
+
+// formatExtensionSummary returns the extension block of a directory summary
+func formatExtensionSummary(node *analyzer.FileSystemNode) string {
+	stats := ExtensionStats(node)
+	if len(stats) == 0 {
+		return ""
+	}
+	return "\nBy extension:\n" + FormatExtensionStats(stats, maxSummaryExtensions)
+}
@@ -1,97 +1,369 @@
 package formatter
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/agris/ingest-clone/pkg/analyzer"
 	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/license"
 )
 
 // AnalysisResult holds the formatted analysis results
 type AnalysisResult struct {
-	Summary            string // Summary of the analysis
-	DirectoryStructure string // Tree-like representation of the directory structure
-	FileContents       string // Contents of the files
+	Summary            string     // Summary of the analysis
+	Warnings           string     // Paths skipped because of errors
+	DirectoryStructure string     // Tree-like representation of the directory structure
+	Architecture       string     // Roles of the top-level directories and Go import edges (if enabled)
+	Dependencies       string     // Direct dependencies of recognized manifests
+	Terraform          string     // Providers, modules, and resources of Terraform configurations
+	Todos              string     // Consolidated TODO/FIXME/HACK/XXX comments (if enabled)
+	Hotspots           string     // Files ranked by cyclomatic complexity (if enabled)
+	Churn              string     // Files ranked by recent commits and authors (if enabled)
+	PullRequest        string     // Description and comments of a pull request digest
+	Patch              string     // Diff of a patch digest, before the contents of the touched files
+	FileContents       string     // Contents of the files
+	Symbols            string     // Appendix cross-referencing exported Go symbols (if enabled)
+	Files              []TOCEntry // Location of each file header within FileContents
 }
 
-// FormatResults formats the analysis results
+// TOCEntry locates a file header inside the digest
+type TOCEntry struct {
+	Path   string // Path shown in the file header
+	Offset int    // Byte offset of the header
+	Line   int    // Line number of the header (1-based)
+}
+
+// FormatResults formats the analysis results of a single source, rendering
+// the same parts as the text digest
 func FormatResults(root *analyzer.FileSystemNode, cfg *config.Config) *AnalysisResult {
-	result := &AnalysisResult{}
+	source := newSource(root, cfg)
+	result := &AnalysisResult{
+		Summary:            formatSummary(source, cfg),
+		Warnings:           formatWarnings(source),
+		DirectoryStructure: formatDirectoryStructure(source, cfg),
+	}
+	for _, section := range source.Sections {
+		switch section.Name {
+		case SectionArchitecture:
+			result.Architecture = section.Text
+		case SectionDependencies:
+			result.Dependencies = section.Text
+		case SectionTerraform:
+			result.Terraform = section.Text
+		case SectionTodos:
+			result.Todos = section.Text
+		case SectionHotspots:
+			result.Hotspots = section.Text
+		case SectionChurn:
+			result.Churn = section.Text
+		case SectionPullRequest:
+			result.PullRequest = section.Text
+		case SectionPatch:
+			result.Patch = section.Text
+		}
+	}
+	result.FileContents, result.Files = formatFileContents(source, cfg)
+	for _, section := range source.Appendices {
+		if section.Name == SectionSymbols {
+			result.Symbols = section.Text
+		}
+	}
+	return result
+}
+
+// formatSummary renders the summary of a source
+func formatSummary(source *Source, cfg *config.Config) string {
+	s := source.Summary
+	var summary strings.Builder
+
+	if s.IsDir {
+		summary.WriteString(fmt.Sprintf("Directory: %s\n\n", s.Name))
+		summary.WriteString(fmt.Sprintf("Files analyzed: %d\n", s.Files))
+		summary.WriteString(fmt.Sprintf("Total size: %s\n", formatSize(s.Size)))
+		summary.WriteString(fmt.Sprintf("Tree SHA-256: %s\n", s.SHA256))
+	} else {
+		summary.WriteString(fmt.Sprintf("File: %s\n\n", s.Name))
+		summary.WriteString(fmt.Sprintf("Size: %s\n", formatSize(s.Size)))
+		summary.WriteString(fmt.Sprintf("Lines: %d\n", s.Lines))
+		summary.WriteString(fmt.Sprintf("SHA-256: %s\n", s.SHA256))
+	}
+
+	// Add token count estimation (simplified)
+	if s.Tokens > 0 {
+		summary.WriteString(fmt.Sprintf("\nEstimated tokens: %s\n", formatTokenCount(s.Tokens)))
+		summary.WriteString(FormatCost(s.Tokens, cfg.Pricing))
+	}
 
-	// Generate summary
-	result.Summary = formatSummary(root, cfg)
+	// Show which file types dominate the digest
+	if len(s.Extensions) > 0 {
+		summary.WriteString("\nBy extension:\n" + FormatExtensionStats(s.Extensions, maxSummaryExtensions))
+	}
 
-	// Generate directory structure
-	result.DirectoryStructure = formatDirectoryStructure(root)
+	// Report exactly what the limits cut off
+	summary.WriteString(formatOmitted(s.Stats, cfg))
+	summary.WriteString(formatStats(s.Stats, cfg))
 
-	// Generate file contents
-	result.FileContents = formatFileContents(root)
+	// Report how much deduplication saved
+	if s.Duplicates > 0 {
+		summary.WriteString(fmt.Sprintf("Duplicates: %s referenced instead of repeated (%s saved)\n",
+			pluralize(s.Duplicates, "file"), formatSize(s.DuplicateSize)))
+	}
 
-	return result
+	// Surface the project license without scrolling the full digest
+	summary.WriteString(formatLicenses(s.Licenses))
+
+	// Show where execution starts
+	summary.WriteString(formatEntrypoints(source.Tree))
+
+	// Explain skipped git-based features rather than failing
+	if s.GitNote != "" {
+		summary.WriteString(fmt.Sprintf("\nNote: %s; git-based features were skipped\n", s.GitNote))
+	}
+
+	return summary.String()
 }
 
-// formatSummary generates a summary of the analysis
-func formatSummary(node *analyzer.FileSystemNode, cfg *config.Config) string {
+// formatStats reports the files left out, replaced, or reduced for reasons
+// other than limits
+func formatStats(stats *config.Stats, cfg *config.Config) string {
+	if stats == nil {
+		return ""
+	}
+
 	var summary strings.Builder
+	if stats.ExcludedFiles > 0 || stats.PrunedDirs > 0 {
+		summary.WriteString(fmt.Sprintf("Excluded by patterns: %s and %s (not read)\n",
+			pluralize(stats.ExcludedFiles, "file"), pluralize(stats.PrunedDirs, "directory")))
+	}
+	if stats.OmittedDigests > 0 {
+		summary.WriteString(fmt.Sprintf("Skipped %s of earlier output\n", pluralize(stats.OmittedDigests, "digest")))
+	}
+	if stats.OmittedBinary > 0 {
+		summary.WriteString(fmt.Sprintf("Skipped %s\n", pluralize(stats.OmittedBinary, "binary file")))
+	}
+	if stats.OmittedMounts > 0 {
+		summary.WriteString(fmt.Sprintf("Skipped %s (--one-file-system)\n", pluralize(stats.OmittedMounts, "mount point")))
+	}
+	if stats.OmittedMigrations > 0 {
+		summary.WriteString(fmt.Sprintf("Summarized %s (--migrations %s)\n", pluralize(stats.OmittedMigrations, "older migration"), cfg.Migrations))
+	}
+	if stats.HelmTemplates > 0 {
+		summary.WriteString(fmt.Sprintf("Replaced %s with rendered manifests (--helm-render)\n", pluralize(stats.HelmTemplates, "Helm template")))
+	}
+	if stats.RedactedSecrets > 0 {
+		summary.WriteString(fmt.Sprintf("Redacted %s in Terraform state files\n", pluralize(stats.RedactedSecrets, "sensitive value")))
+	}
+	if stats.SummarizedFiles > 0 {
+		summary.WriteString(fmt.Sprintf("Replaced %s with summaries by %s\n", pluralize(stats.SummarizedFiles, "file"), cfg.SummarizeWith))
+	}
+	if stats.OmittedContent > 0 {
+		summary.WriteString(fmt.Sprintf("Listed %s without content (--content-depth %d)\n", pluralize(stats.OmittedContent, "file"), cfg.ContentDepth))
+	}
+	if stats.LicenseHeaders > 0 {
+		summary.WriteString(fmt.Sprintf("Stripped license headers from %s (%s tokens)\n",
+			pluralize(stats.LicenseHeaders, "file"), formatTokenCount(stats.LicenseTokens)))
+	}
+	if stats.OmittedBlank > 0 {
+		summary.WriteString(fmt.Sprintf("Listed %s without content (empty or whitespace only)\n", pluralize(stats.OmittedBlank, "file")))
+	}
+	if stats.SummaryOnlyFiles > 0 {
+		summary.WriteString(fmt.Sprintf("Listed %s without content (--summary-rest)\n", pluralize(stats.SummaryOnlyFiles, "file")))
+	}
+	return summary.String()
+}
+
+// formatWarnings lists the paths of a source skipped because of errors, or
+// returns an empty string if there were none
+func formatWarnings(source *Source) string {
+	stats := source.Summary.Stats
+	if stats == nil || len(stats.Errors) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Warnings (%s skipped):\n", pluralize(len(stats.Errors), "path")))
+	for _, pe := range stats.Errors {
+		// Path errors repeat the path, so show only the operation and cause
+		msg := pe.Err.Error()
+		var fsErr *fs.PathError
+		if errors.As(pe.Err, &fsErr) {
+			msg = fsErr.Op + ": " + fsErr.Err.Error()
+		}
+
+		rel, err := filepath.Rel(source.Root.Path, pe.Path)
+		if err != nil {
+			rel = pe.Path
+		}
+		builder.WriteString(fmt.Sprintf("%s: %s\n", filepath.ToSlash(rel), msg))
+	}
+
+	return builder.String()
+}
 
+// formatOmitted reports the files and directories left out because a limit
+// was reached, or returns an empty string if nothing was cut off
+func formatOmitted(stats *config.Stats, cfg *config.Config) string {
+	if stats == nil {
+		return ""
+	}
+
+	var lines []string
+	if stats.OmittedByDepth > 0 || stats.OmittedDirsByDepth > 0 {
+		lines = append(lines, fmt.Sprintf("%s and %s below max depth %d",
+			pluralize(stats.OmittedByDepth, "file"), pluralize(stats.OmittedDirsByDepth, "directory"), cfg.MaxDirDepth))
+	}
+	if stats.OmittedByMaxFiles > 0 {
+		lines = append(lines, fmt.Sprintf("%s over max files (%d)", pluralize(stats.OmittedByMaxFiles, "file"), cfg.MaxFiles))
+	}
+	if stats.OmittedByTotalSize > 0 {
+		lines = append(lines, fmt.Sprintf("%s over max total size (%s)", pluralize(stats.OmittedByTotalSize, "file"), formatSize(cfg.MaxTotalSize)))
+	}
+	if stats.OmittedByTokens > 0 {
+		lines = append(lines, fmt.Sprintf("%s over max tokens (%s)", pluralize(stats.OmittedByTokens, "file"), formatTokenCount(cfg.MaxTokens)))
+	}
+	if stats.OmittedByFileSize > 0 {
+		lines = append(lines, fmt.Sprintf("%s larger than max file size (%s)", pluralize(stats.OmittedByFileSize, "file"), formatSize(cfg.MaxFileSize)))
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return "\nOmitted due to limits:\n  " + strings.Join(lines, "\n  ") + "\n"
+}
+
+// duplicateSavings counts the files replaced by references to identical files
+// and the bytes saved
+func duplicateSavings(node *analyzer.FileSystemNode) (int, int64) {
+	count, size := 0, int64(0)
+	for _, file := range node.Files() {
+		if file.DuplicateOf != "" {
+			count++
+			size += file.Size
+		}
+	}
+	return count, size
+}
+
+// licenses identifies the license and notice files at the top level of the
+// node
+func licenses(node *analyzer.FileSystemNode) []License {
+	candidates := []*analyzer.FileSystemNode{node}
 	if node.IsDir {
-		summary.WriteString(fmt.Sprintf("Directory: %s\n\n", node.Name))
-		summary.WriteString(fmt.Sprintf("Files analyzed: %d\n", node.FileCount))
-		summary.WriteString(fmt.Sprintf("Total size: %s\n", formatSize(node.Size)))
-	} else {
-		summary.WriteString(fmt.Sprintf("File: %s\n\n", node.Name))
-		summary.WriteString(fmt.Sprintf("Size: %s\n", formatSize(node.Size)))
-		summary.WriteString(fmt.Sprintf("Lines: %d\n", strings.Count(node.Content, "\n")+1))
+		candidates = node.Children
 	}
 
-	// Add token count estimation (simplified)
-	tokenCount := estimateTokens(node)
-	if tokenCount > 0 {
-		summary.WriteString(fmt.Sprintf("\nEstimated tokens: %s\n", formatTokenCount(tokenCount)))
+	var found []License
+	for _, child := range candidates {
+		if child.IsDir || !license.IsLicenseFile(child.Name) {
+			continue
+		}
+
+		if license.IsNoticeFile(child.Name) {
+			found = append(found, License{File: child.Name, Notice: true})
+			continue
+		}
+
+		id := license.Identify(child.Content)
+		if id == "" {
+			id = "unknown"
+		}
+		found = append(found, License{File: child.Name, SPDX: id})
 	}
+	return found
+}
 
-	return summary.String()
+// formatLicenses lists the license and notice files with their identified
+// SPDX licenses, or returns an empty string if there are none
+func formatLicenses(licenses []License) string {
+	if len(licenses) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	for _, l := range licenses {
+		if l.Notice {
+			builder.WriteString(fmt.Sprintf("Notice: %s\n", l.File))
+		} else {
+			builder.WriteString(fmt.Sprintf("License: %s (%s)\n", l.SPDX, l.File))
+		}
+	}
+	return "\n" + builder.String()
+}
+
+// formatEntrypoints lists the files of the tree that start execution, or
+// returns an empty string if there are none
+func formatEntrypoints(tree *TreeEntry) string {
+	var builder strings.Builder
+	var walk func(entry *TreeEntry)
+	walk = func(entry *TreeEntry) {
+		if !entry.IsDir && entry.Entrypoint != "" {
+			builder.WriteString(fmt.Sprintf("  %s (%s)\n", entry.Path, entry.Entrypoint))
+		}
+		for _, child := range entry.Children {
+			walk(child)
+		}
+	}
+	walk(tree)
+
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "\nEntrypoints:\n" + builder.String()
 }
 
-// formatDirectoryStructure generates a tree-like representation of the directory structure
-func formatDirectoryStructure(node *analyzer.FileSystemNode) string {
+// formatDirectoryStructure renders the directory structure of a source as a tree
+func formatDirectoryStructure(source *Source, cfg *config.Config) string {
 	var builder strings.Builder
 	builder.WriteString("Directory structure:\n")
 
-	if node.IsDir {
-		prefix := ""
-		isLast := true
-		buildTree(node, prefix, isLast, &builder)
+	tree := source.Tree
+	if tree.IsDir {
+		buildTree(tree, 0, "", true, cfg, &builder)
 	} else {
-		builder.WriteString(fmt.Sprintf("└── %s\n", node.Name))
+		builder.WriteString(fmt.Sprintf("└── %s%s%s\n", tree.Name, treeMetadata(tree, cfg), entrypointMark(tree)))
 	}
 
 	return builder.String()
 }
 
-// buildTree recursively builds a tree representation
-func buildTree(node *analyzer.FileSystemNode, prefix string, isLast bool, builder *strings.Builder) {
-	// Add the current node to the tree
+// buildTree recursively renders an entry at the given depth below the root
+func buildTree(entry *TreeEntry, depth int, prefix string, isLast bool, cfg *config.Config, builder *strings.Builder) {
+	// Add the current entry to the tree
 	currentPrefix := "└── "
 	if !isLast {
 		currentPrefix = "├── "
 	}
 
 	// Add trailing slash for directories
-	name := node.Name
-	if node.IsDir {
+	name := entry.Name
+	if entry.IsDir {
 		name += "/"
 	}
-
-	builder.WriteString(fmt.Sprintf("%s%s%s\n", prefix, currentPrefix, name))
+	name += treeMetadata(entry, cfg) + entrypointMark(entry)
 
 	// If this is not a directory or has no children, return
-	if !node.IsDir || len(node.Children) == 0 {
+	if !entry.IsDir || len(entry.Children) == 0 {
+		builder.WriteString(fmt.Sprintf("%s%s%s%s\n", prefix, currentPrefix, name, treeStats(entry, cfg)))
+		return
+	}
+
+	// Collapse directories below the tree depth into aggregate counts
+	if cfg.TreeDepth > 0 && depth >= cfg.TreeDepth {
+		counts := []string{pluralize(entry.Files, "file"), pluralize(entry.Dirs, "dir")}
+		if cfg.TreeStats {
+			counts = append(counts, formatSize(entry.Size), fmt.Sprintf("~%s tokens", formatTokenCount(entry.Tokens)))
+		}
+		builder.WriteString(fmt.Sprintf("%s%s%s (%s)\n", prefix, currentPrefix, name, strings.Join(counts, ", ")))
 		return
 	}
 
+	builder.WriteString(fmt.Sprintf("%s%s%s%s\n", prefix, currentPrefix, name, treeStats(entry, cfg)))
+
 	// Prepare the prefix for children
 	childPrefix := prefix
 	if isLast {
@@ -101,50 +373,102 @@ func buildTree(node *analyzer.FileSystemNode, prefix string, isLast bool, builde
 	}
 
 	// Process children
-	for i, child := range node.Children {
-		isChildLast := i == len(node.Children)-1
-		buildTree(child, childPrefix, isChildLast, builder)
+	for i, child := range entry.Children {
+		isChildLast := i == len(entry.Children)-1
+		buildTree(child, depth+1, childPrefix, isChildLast, cfg, builder)
 	}
 }
 
-// formatFileContents formats the contents of all files
-func formatFileContents(node *analyzer.FileSystemNode) string {
-	var builder strings.Builder
-
-	if !node.IsDir {
-		// For a single file, just return its content with a header
-		builder.WriteString(formatFileContent(node))
-		return builder.String()
+// treeMetadata returns the metadata column shown after a name in the tree,
+// or an empty string unless enabled
+func treeMetadata(entry *TreeEntry, cfg *config.Config) string {
+	if !cfg.TreeMetadata {
+		return ""
 	}
 
-	// For a directory, recursively format all files
-	formatDirectoryContent(node, &builder)
+	meta := fmt.Sprintf("  [%s %s]", entry.Mode, entry.ModTime.UTC().Format("2006-01-02 15:04"))
+	if entry.LinkTarget != "" {
+		meta += " -> " + entry.LinkTarget
+	}
+	return meta
+}
 
-	return builder.String()
+// entrypointMark returns the note shown after an entry point in the tree
+func entrypointMark(entry *TreeEntry) string {
+	if entry.Entrypoint == "" {
+		return ""
+	}
+	return fmt.Sprintf("  (entrypoint: %s)", entry.Entrypoint)
 }
 
-// formatDirectoryContent recursively formats the contents of a directory
-func formatDirectoryContent(node *analyzer.FileSystemNode, builder *strings.Builder) {
-	if !node.IsDir {
-		builder.WriteString(formatFileContent(node))
-		return
+// treeStats returns the aggregate file count, size, and tokens shown after
+// a directory in the tree, or an empty string for files or unless enabled
+func treeStats(entry *TreeEntry, cfg *config.Config) string {
+	if !cfg.TreeStats || !entry.IsDir {
+		return ""
 	}
 
-	// Recursively process children
-	for _, child := range node.Children {
-		formatDirectoryContent(child, builder)
+	return fmt.Sprintf(" (%s, %s, ~%s tokens)", pluralize(entry.Files, "file"),
+		formatSize(entry.Size), formatTokenCount(entry.Tokens))
+}
+
+// pluralize formats a count with a singular or plural noun
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+	if strings.HasSuffix(noun, "y") {
+		return fmt.Sprintf("%d %sies", count, strings.TrimSuffix(noun, "y"))
 	}
+	return fmt.Sprintf("%d %ss", count, noun)
 }
 
-// formatFileContent formats the content of a file
-func formatFileContent(node *analyzer.FileSystemNode) string {
-	if node.IsDir {
-		return ""
+// formatFileContents renders the files of a source in digest order and
+// records where each file header starts
+func formatFileContents(source *Source, cfg *config.Config) (string, []TOCEntry) {
+	var builder strings.Builder
+	var entries []TOCEntry
+	line := 1
+
+	// Empty files appear in the tree only; a lone empty file keeps its header
+	files := source.Files
+	if source.Summary.IsDir {
+		files = slices.DeleteFunc(slices.Clone(files), func(file *FileEntry) bool { return file.Node.IsBlank })
+	}
+	for i, file := range files {
+		// Tests come last under their own heading
+		if i > 0 && isTestSection(file.Node, cfg) && !isTestSection(files[i-1].Node, cfg) {
+			heading := fmt.Sprintf("Tests (%s):\n\n", pluralize(len(files)-i, "file"))
+			line += strings.Count(heading, "\n")
+			builder.WriteString(heading)
+		}
+
+		content := formatFileContent(file, cfg)
+		entries = append(entries, TOCEntry{Path: file.Header, Offset: builder.Len(), Line: line})
+		line += strings.Count(content, "\n")
+		builder.WriteString(content)
 	}
 
+	return builder.String(), entries
+}
+
+// formatFileContent renders a file with its header
+func formatFileContent(file *FileEntry, cfg *config.Config) string {
 	var builder strings.Builder
 
 	// Add file header
+	builder.WriteString(cfg.FormatFileHeader(file.Header))
+	builder.WriteString(formatOwners(file.Node))
+
+	// Add file content
+	builder.WriteString(file.Content)
+	builder.WriteString("\n\n")
+
+	return builder.String()
+}
+
+// headerPath returns the path shown in a file header
+func headerPath(node *analyzer.FileSystemNode) string {
 	relPath := filepath.Base(filepath.Dir(node.Path))
 	if relPath == "." {
 		relPath = ""
@@ -152,12 +476,45 @@ func formatFileContent(node *analyzer.FileSystemNode) string {
 		relPath += "/"
 	}
 
-	builder.WriteString(fmt.Sprintf("%s\nFILE: %s%s\n%s\n",
-		config.Separator, relPath, node.Name, config.Separator))
+	if node.Lines != nil {
+		noun := "lines"
+		if len(node.Lines) == 1 && node.Lines[0].Start == node.Lines[0].End {
+			noun = "line"
+		}
+		return fmt.Sprintf("%s%s (%s %s)", relPath, node.Name, noun, config.FormatLineRanges(node.Lines))
+	}
+	return relPath + node.Name
+}
 
-	// Add file content
-	builder.WriteString(node.Content)
-	builder.WriteString("\n\n")
+// PrependTOC inserts a table of contents between prefix and body mapping each
+// file to its line and byte offset in the final digest. Entry offsets are
+// relative to body.
+func PrependTOC(prefix, body string, entries []TOCEntry) string {
+	// The TOC shifts everything after it, so recompute it until its own
+	// length is stable; fixed-width columns make this converge immediately
+	toc := ""
+	for i := 0; i < 5; i++ {
+		next := formatTOC(entries, len(prefix)+len(toc), strings.Count(prefix+toc, "\n"))
+		if len(next) == len(toc) {
+			toc = next
+			break
+		}
+		toc = next
+	}
+
+	return prefix + toc + body
+}
+
+// formatTOC renders the table of contents shifted by the given byte and line offsets
+func formatTOC(entries []TOCEntry, offset, lines int) string {
+	var builder strings.Builder
+	builder.WriteString("Table of contents:\n")
+	builder.WriteString(fmt.Sprintf("%8s %12s  %s\n", "LINE", "BYTE", "FILE"))
+
+	for _, entry := range entries {
+		builder.WriteString(fmt.Sprintf("%8d %12d  %s\n", entry.Line+lines, entry.Offset+offset, entry.Path))
+	}
+	builder.WriteString("\n")
 
 	return builder.String()
 }
@@ -218,3 +575,20 @@ func formatTokenCount(count int) string {
 
 	return fmt.Sprintf("%.1fM", float64(count)/1000000)
 }
+
+// formatOwners lists the CODEOWNERS owners and main authors of a file below
+// its header, or returns an empty string if it has neither
+func formatOwners(node *analyzer.FileSystemNode) string {
+	var builder strings.Builder
+	if len(node.Owners) > 0 {
+		builder.WriteString("Owners: " + strings.Join(node.Owners, ", ") + "\n")
+	}
+	if len(node.TopAuthors) > 0 {
+		authors := make([]string, len(node.TopAuthors))
+		for i, author := range node.TopAuthors {
+			authors[i] = fmt.Sprintf("%s (%d%%)", author.Name, author.Share)
+		}
+		builder.WriteString("Authors: " + strings.Join(authors, ", ") + "\n")
+	}
+	return builder.String()
+}
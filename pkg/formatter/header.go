@@ -0,0 +1,77 @@
+package formatter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/sshsource"
+)
+
+// Header identifies the tool and source that generated a digest
+type Header struct {
+	Tool        string    // Name of the generating tool
+	Version     string    // Version of the generating tool
+	GeneratedAt time.Time // Generation time (zero if omitted for reproducibility)
+	Source      string    // Identity of the analyzed source
+	Commit      string    // Commit of the source repository (empty if not a git source)
+	Command     string    // Command line that reproduces the digest
+}
+
+// NewHeader builds the header for a run, resolving the timestamp according
+// to cfg.TimestampFrom. A git timestamp that cannot be resolved is omitted
+// rather than replaced by the current time, keeping the output reproducible.
+func NewHeader(tool, version string, cfg *config.Config, files []string) *Header {
+	header := &Header{Tool: tool, Version: version}
+
+	switch {
+	case len(files) > 0:
+		header.Source = strings.Join(files, ",")
+	case strings.Contains(cfg.Source, "://"), sshsource.IsRemote(cfg.Source):
+		header.Source = cfg.Source
+	default:
+		header.Source = filepath.Base(config.AbsPath(cfg.Source))
+		header.Commit, _ = cfg.Git().Head()
+	}
+
+	switch cfg.TimestampFrom {
+	case config.TimestampNow:
+		header.GeneratedAt = time.Now().UTC()
+	case config.TimestampGit:
+		if t, err := cfg.Git().CommitTime(); err == nil {
+			header.GeneratedAt = t.UTC()
+		}
+	}
+
+	return header
+}
+
+// Timestamp returns the generation time in RFC 3339 format, or an empty string
+func (h *Header) Timestamp() string {
+	if h.GeneratedAt.IsZero() {
+		return ""
+	}
+	return h.GeneratedAt.Format(time.RFC3339)
+}
+
+// Text renders the header for the text format
+func (h *Header) Text() string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("Generated by %s %s", h.Tool, h.Version))
+	if ts := h.Timestamp(); ts != "" {
+		builder.WriteString(" on " + ts)
+	}
+	builder.WriteString("\nSource: " + h.Source)
+	if h.Commit != "" {
+		builder.WriteString("@" + h.Commit)
+	}
+	if h.Command != "" {
+		builder.WriteString("\nCommand: " + h.Command)
+	}
+	builder.WriteString("\n\n")
+
+	return builder.String()
+}
@@ -0,0 +1,75 @@
+package formatter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/metrics"
+)
+
+// maxHotspots limits how many files the hotspots section ranks
+const maxHotspots = 10
+
+// hotspot is a file with its complexity and line counts
+type hotspot struct {
+	path       string
+	complexity metrics.Complexity
+	lines      metrics.Lines
+}
+
+// measurable reports whether the metrics of a file describe its own
+// content rather than a placeholder or reference
+func measurable(file *analyzer.FileSystemNode) bool {
+	return !file.IsBinary && !file.IsBlank && file.DuplicateOf == ""
+}
+
+// formatHotspots ranks the files below root with the highest cyclomatic
+// complexity, with their code, comment, and blank lines
+func formatHotspots(root *analyzer.FileSystemNode) string {
+	var spots []hotspot
+	for _, file := range root.Files() {
+		if !measurable(file) {
+			continue
+		}
+		complexity, ok := metrics.CyclomaticComplexity(file.Language, file.Content)
+		if !ok {
+			continue
+		}
+		spots = append(spots, hotspot{
+			path:       file.RelPath(root),
+			complexity: complexity,
+			lines:      metrics.CountLines(file.Language, file.Content),
+		})
+	}
+	if len(spots) == 0 {
+		return "Hotspots: none\n"
+	}
+
+	sort.SliceStable(spots, func(i, j int) bool {
+		if spots[i].complexity.Total != spots[j].complexity.Total {
+			return spots[i].complexity.Total > spots[j].complexity.Total
+		}
+		return spots[i].path < spots[j].path
+	})
+	measured := len(spots)
+	spots = spots[:min(len(spots), maxHotspots)]
+
+	width := 0
+	for _, spot := range spots {
+		width = max(width, len(spot.path))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Hotspots (most complex of %s):\n", pluralize(measured, "measured file")))
+	for _, spot := range spots {
+		detail := fmt.Sprintf("complexity %d", spot.complexity.Total)
+		if spot.complexity.MaxFunc != "" {
+			detail += fmt.Sprintf(" (max %d in %s)", spot.complexity.Max, spot.complexity.MaxFunc)
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s  %s, %d code, %d comment, %d blank lines\n",
+			width, spot.path, detail, spot.lines.Code, spot.lines.Comment, spot.lines.Blank))
+	}
+	return builder.String()
+}
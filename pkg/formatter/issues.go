@@ -0,0 +1,42 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/issues"
+)
+
+// formatIssues lists issue threads: each issue with its description and
+// comments, oldest first
+func formatIssues(threads []*issues.Issue) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Issues (%d):\n", len(threads)))
+	for _, issue := range threads {
+		builder.WriteString(fmt.Sprintf("\nIssue #%d: %s\n", issue.Number, issue.Title))
+		builder.WriteString(fmt.Sprintf("Author: @%s\n", issue.Author))
+		builder.WriteString(fmt.Sprintf("State: %s\n", issue.State))
+		if len(issue.Labels) > 0 {
+			builder.WriteString(fmt.Sprintf("Labels: %s\n", strings.Join(issue.Labels, ", ")))
+		}
+		if issue.URL != "" {
+			builder.WriteString(fmt.Sprintf("URL: %s\n", issue.URL))
+		}
+		if issue.Body != "" {
+			builder.WriteString("\n" + issue.Body + "\n")
+		}
+
+		if len(issue.Comments) == 0 {
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("\nComments (%d):\n", len(issue.Comments)))
+		for _, comment := range issue.Comments {
+			heading := "  @" + comment.Author
+			if !comment.Created.IsZero() {
+				heading += ", " + comment.Created.UTC().Format("2006-01-02")
+			}
+			writeComment(&builder, heading+":", comment.Body)
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,123 @@
+package formatter
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// jsonDigest is the single JSON document of the json format
+type jsonDigest struct {
+	Tool        string            `json:"tool"`                   // Name of the generating tool
+	Version     string            `json:"version"`                // Version of the generating tool
+	GeneratedAt string            `json:"generated_at,omitempty"` // RFC 3339 generation time
+	Source      string            `json:"source"`                 // Identity of the analyzed source
+	Commit      string            `json:"commit,omitempty"`       // Commit of the source repository
+	Command     string            `json:"command,omitempty"`      // Command line that reproduces the digest
+	SHA256      string            `json:"sha256"`                 // SHA-256 of the checksum manifest of all files
+	PullRequest *jsonlPullRequest `json:"pull_request,omitempty"` // Pull request of a pull request digest
+	Patch       string            `json:"patch,omitempty"`        // Unified diff of a patch digest
+	Sources     []jsonSource      `json:"sources"`                // Each analyzed file or directory
+	Issues      []jsonlIssue      `json:"issues,omitempty"`       // Issue threads appended with --with-issues
+}
+
+// jsonSource is an analyzed file or directory in the json format
+type jsonSource struct {
+	Name       string        `json:"name"`                 // Base name of the source
+	IsDir      bool          `json:"is_dir"`               // Whether the source is a directory
+	FileCount  int           `json:"file_count"`           // Number of files analyzed
+	DirCount   int           `json:"dir_count"`            // Number of directories analyzed
+	Size       int64         `json:"size"`                 // Total size in bytes
+	Tokens     int           `json:"tokens"`               // Estimated tokens across all files
+	SHA256     string        `json:"sha256"`               // File SHA-256 or tree checksum manifest SHA-256
+	Duplicates int           `json:"duplicates,omitempty"` // Files replaced by a reference to an identical file
+	Tree       *jsonTree     `json:"tree"`                 // Directory structure below the root
+	Sections   []jsonSection `json:"sections,omitempty"`   // Optional sections shown before the files
+	Files      []jsonlRecord `json:"files"`                // Files in digest order
+	Appendices []jsonSection `json:"appendices,omitempty"` // Optional sections shown after the files
+}
+
+// jsonTree is a file or directory in the directory structure
+type jsonTree struct {
+	Name     string      `json:"name"`               // Base name
+	Path     string      `json:"path"`               // Slash-separated path relative to the source
+	IsDir    bool        `json:"is_dir"`             // Whether the entry is a directory
+	Size     int64       `json:"size"`               // Size in bytes, summed for directories
+	Tokens   int         `json:"tokens"`             // Estimated tokens, summed for directories
+	Children []*jsonTree `json:"children,omitempty"` // Entries of a directory in tree order
+}
+
+// jsonSection is an optional text section of a source
+type jsonSection struct {
+	Name string `json:"name"` // One of the Section* names
+	Text string `json:"text"` // Rendered section
+}
+
+// WriteJSON writes the digest as a single JSON document: the header fields,
+// then every source with its summary figures, tree, sections, and files.
+// Files are described as in the JSONL format.
+func (d *Digest) WriteJSON(w io.Writer) error {
+	doc := jsonDigest{
+		Tool:        d.Header.Tool,
+		Version:     d.Header.Version,
+		GeneratedAt: d.Header.Timestamp(),
+		Source:      d.Header.Source,
+		Commit:      d.Header.Commit,
+		Command:     d.Header.Command,
+		SHA256:      d.SHA256,
+		Patch:       d.cfg.Patch,
+		Sources:     []jsonSource{},
+	}
+	if d.cfg.PullRequest != nil {
+		record := pullRequestRecord(d.cfg.PullRequest)
+		doc.PullRequest = &record
+	}
+
+	for _, source := range d.Sources {
+		s := jsonSource{
+			Name:       source.Summary.Name,
+			IsDir:      source.Summary.IsDir,
+			FileCount:  source.Summary.Files,
+			DirCount:   source.Summary.Dirs,
+			Size:       source.Summary.Size,
+			Tokens:     source.Summary.Tokens,
+			SHA256:     source.Summary.SHA256,
+			Duplicates: source.Summary.Duplicates,
+			Tree:       newJSONTree(source.Tree),
+			Files:      []jsonlRecord{},
+		}
+		for _, section := range source.Sections {
+			s.Sections = append(s.Sections, jsonSection(section))
+		}
+		for _, file := range source.Files {
+			s.Files = append(s.Files, fileRecord(file))
+		}
+		for _, section := range source.Appendices {
+			s.Appendices = append(s.Appendices, jsonSection(section))
+		}
+		doc.Sources = append(doc.Sources, s)
+	}
+
+	for _, issue := range d.cfg.Issues {
+		doc.Issues = append(doc.Issues, issueRecord(issue))
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(doc)
+}
+
+// newJSONTree converts the tree below entry
+func newJSONTree(entry *TreeEntry) *jsonTree {
+	tree := &jsonTree{
+		Name:   entry.Name,
+		Path:   entry.Path,
+		IsDir:  entry.IsDir,
+		Size:   entry.Size,
+		Tokens: entry.Tokens,
+	}
+	for _, child := range entry.Children {
+		tree.Children = append(tree.Children, newJSONTree(child))
+	}
+	return tree
+}
@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/issues"
+	"github.com/agris/ingest-clone/pkg/pullrequest"
+)
+
+// jsonlHeader is the first line of JSONL output identifying the digest
+type jsonlHeader struct {
+	Type        string `json:"type"`                   // Always "header"
+	Tool        string `json:"tool"`                   // Name of the generating tool
+	Version     string `json:"version"`                // Version of the generating tool
+	GeneratedAt string `json:"generated_at,omitempty"` // RFC 3339 generation time
+	Source      string `json:"source"`                 // Identity of the analyzed source
+	Commit      string `json:"commit,omitempty"`       // Commit of the source repository
+	Command     string `json:"command,omitempty"`      // Command line that reproduces the digest
+	SHA256      string `json:"sha256"`                 // SHA-256 of the checksum manifest of all files
+}
+
+// jsonlRecord is a single line of JSONL output describing one file
+type jsonlRecord struct {
+	Type     string        `json:"type"`               // Always "file"
+	Path     string        `json:"path"`               // Slash-separated path relative to the source
+	Size     int64         `json:"size"`               // Size in bytes
+	Language string        `json:"language,omitempty"` // Detected language
+	MIME     string        `json:"mime,omitempty"`     // MIME type detected from the contents
+	Tokens   int           `json:"tokens"`             // Estimated tokens of the content
+	SHA256   string        `json:"sha256,omitempty"`   // SHA-256 of the raw file contents
+	ModTime  string        `json:"mtime"`              // RFC 3339 modification time
+	Mode     string        `json:"mode"`               // Permission bits in octal, such as "0644"
+	Link     string        `json:"link,omitempty"`     // Target of the symbolic link
+	Lines    string        `json:"lines,omitempty"`    // Line ranges kept, such as "100-250, 300-"
+	Owners   []string      `json:"owners,omitempty"`   // Owners assigned by CODEOWNERS (with --authors)
+	Authors  []jsonlAuthor `json:"authors,omitempty"`  // Main authors by git blame (with --authors)
+	Content  string        `json:"content"`            // File content or placeholder
+}
+
+// jsonlPatch is the line of JSONL output holding the diff of a patch digest
+type jsonlPatch struct {
+	Type    string `json:"type"`    // Always "patch"
+	Content string `json:"content"` // Unified diff
+}
+
+// jsonlPullRequest is the line of JSONL output describing the pull request
+// of a pull request digest
+type jsonlPullRequest struct {
+	Type     string         `json:"type"`               // Always "pull_request"
+	Number   int            `json:"number"`             // Number of the pull request
+	Title    string         `json:"title"`              // Title
+	Author   string         `json:"author"`             // Login of the author
+	State    string         `json:"state"`              // open, closed, or merged
+	URL      string         `json:"url,omitempty"`      // Web URL
+	Base     string         `json:"base"`               // Branch the changes are merged into
+	Head     string         `json:"head"`               // Branch holding the changes
+	Body     string         `json:"body"`               // Description
+	Comments []jsonlComment `json:"comments,omitempty"` // Comments, reviews, and review comments, oldest first
+}
+
+// jsonlComment is a comment, review, or review comment on a pull request
+type jsonlComment struct {
+	Author  string `json:"author"`           // Login of the author
+	Created string `json:"created"`          // RFC 3339 time of the comment
+	Review  string `json:"review,omitempty"` // State of a review, such as "approved"
+	Path    string `json:"path,omitempty"`   // File of a review comment
+	Line    int    `json:"line,omitempty"`   // Line of a review comment
+	Body    string `json:"body"`             // Text
+}
+
+// jsonlIssue is a line of JSONL output holding an issue thread appended
+// with --with-issues
+type jsonlIssue struct {
+	Type     string         `json:"type"`               // Always "issue"
+	Number   int            `json:"number"`             // Number of the issue
+	Title    string         `json:"title"`              // Title
+	Author   string         `json:"author"`             // Login of the author
+	State    string         `json:"state"`              // open or closed
+	Labels   []string       `json:"labels,omitempty"`   // Names of the labels
+	URL      string         `json:"url,omitempty"`      // Web URL
+	Body     string         `json:"body"`               // Description
+	Comments []jsonlComment `json:"comments,omitempty"` // Comments, oldest first
+}
+
+// jsonlAuthor is a main author of a file
+type jsonlAuthor struct {
+	Name  string `json:"name"`  // Author name
+	Email string `json:"email"` // Author email
+	Share int    `json:"share"` // Percentage of the committed lines
+}
+
+// WriteJSONL writes a header line followed by one JSON object per line for
+// every file in the digest. The pull request and diff of a review digest
+// come before the files, and issue threads after them.
+func (d *Digest) WriteJSONL(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+
+	err := encoder.Encode(jsonlHeader{
+		Type:        "header",
+		Tool:        d.Header.Tool,
+		Version:     d.Header.Version,
+		GeneratedAt: d.Header.Timestamp(),
+		Source:      d.Header.Source,
+		Commit:      d.Header.Commit,
+		Command:     d.Header.Command,
+		SHA256:      d.SHA256,
+	})
+	if err != nil {
+		return err
+	}
+
+	if d.cfg.PullRequest != nil {
+		if err := encoder.Encode(pullRequestRecord(d.cfg.PullRequest)); err != nil {
+			return err
+		}
+	}
+	if d.cfg.Patch != "" {
+		if err := encoder.Encode(jsonlPatch{Type: "patch", Content: d.cfg.Patch}); err != nil {
+			return err
+		}
+	}
+
+	for _, source := range d.Sources {
+		for _, file := range source.Files {
+			if err := encoder.Encode(fileRecord(file)); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, issue := range d.cfg.Issues {
+		if err := encoder.Encode(issueRecord(issue)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fileRecord describes a file of the digest
+func fileRecord(file *FileEntry) jsonlRecord {
+	record := jsonlRecord{
+		Type:     "file",
+		Path:     file.Path,
+		Size:     file.Size,
+		Language: file.Language,
+		MIME:     file.MIME,
+		Tokens:   file.Tokens,
+		SHA256:   file.SHA256,
+		ModTime:  file.ModTime.UTC().Format(time.RFC3339),
+		Mode:     fmt.Sprintf("%04o", file.Mode.Perm()),
+		Link:     file.LinkTarget,
+		Lines:    config.FormatLineRanges(file.Node.Lines),
+		Owners:   file.Node.Owners,
+		Content:  file.Content,
+	}
+	for _, author := range file.Node.TopAuthors {
+		record.Authors = append(record.Authors, jsonlAuthor(author))
+	}
+	return record
+}
+
+// pullRequestRecord describes the pull request of a pull request digest
+func pullRequestRecord(pr *pullrequest.PullRequest) jsonlPullRequest {
+	record := jsonlPullRequest{
+		Type:   "pull_request",
+		Number: pr.Number,
+		Title:  pr.Title,
+		Author: pr.Author,
+		State:  pr.State,
+		URL:    pr.URL,
+		Base:   pr.Base,
+		Head:   pr.Head,
+		Body:   pr.Body,
+	}
+	for _, comment := range pr.Comments {
+		record.Comments = append(record.Comments, jsonlComment{
+			Author:  comment.Author,
+			Created: comment.Created.UTC().Format(time.RFC3339),
+			Review:  comment.Review,
+			Path:    comment.Path,
+			Line:    comment.Line,
+			Body:    comment.Body,
+		})
+	}
+	return record
+}
+
+// issueRecord describes an issue thread appended with --with-issues
+func issueRecord(issue *issues.Issue) jsonlIssue {
+	record := jsonlIssue{
+		Type:   "issue",
+		Number: issue.Number,
+		Title:  issue.Title,
+		Author: issue.Author,
+		State:  issue.State,
+		Labels: issue.Labels,
+		URL:    issue.URL,
+		Body:   issue.Body,
+	}
+	for _, comment := range issue.Comments {
+		record.Comments = append(record.Comments, jsonlComment{
+			Author:  comment.Author,
+			Created: comment.Created.UTC().Format(time.RFC3339),
+			Body:    comment.Body,
+		})
+	}
+	return record
+}
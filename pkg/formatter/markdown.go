@@ -0,0 +1,79 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the digest as a Markdown document: the header, then for
+// every source its summary, directory structure, and sections in fenced
+// blocks, and each file under its own heading in a code block tagged with
+// its language
+func (d *Digest) Markdown() string {
+	var builder strings.Builder
+
+	builder.WriteString("# " + d.Header.Source + "\n\n")
+	for _, line := range strings.Split(strings.TrimSpace(d.Header.Text()), "\n") {
+		builder.WriteString("- " + line + "\n")
+	}
+
+	for _, source := range d.Sources {
+		builder.WriteString("\n## " + source.Summary.Name + "\n\n")
+		writeFenced(&builder, "", formatSummary(source, d.cfg)+formatWarnings(source))
+
+		tree := strings.TrimPrefix(formatDirectoryStructure(source, d.cfg), "Directory structure:\n")
+		builder.WriteString("\n### Directory structure\n\n")
+		writeFenced(&builder, "", tree)
+
+		for _, section := range source.Sections {
+			builder.WriteString("\n")
+			writeFenced(&builder, "", section.Text)
+		}
+
+		if len(source.Files) > 0 {
+			builder.WriteString("\n### Files\n")
+		}
+		for _, file := range source.Files {
+			if source.Summary.IsDir && file.Node.IsBlank {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("\n#### %s\n\n", file.Header))
+			if owners := formatOwners(file.Node); owners != "" {
+				builder.WriteString(owners + "\n")
+			}
+			writeFenced(&builder, file.Language, file.Content)
+		}
+
+		for _, section := range source.Appendices {
+			builder.WriteString("\n")
+			writeFenced(&builder, "", section.Text)
+		}
+	}
+
+	// The issue threads belong to the whole digest rather than a source
+	if len(d.cfg.Issues) > 0 {
+		builder.WriteString("\n")
+		writeFenced(&builder, "", formatIssues(d.cfg.Issues))
+	}
+
+	return builder.String()
+}
+
+// writeFenced writes text as a fenced code block, using a fence longer than
+// any run of backticks in the text so it cannot end the block early
+func writeFenced(builder *strings.Builder, language, text string) {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+
+	builder.WriteString(fence + language + "\n")
+	builder.WriteString(strings.TrimSuffix(text, "\n") + "\n")
+	builder.WriteString(fence + "\n")
+}
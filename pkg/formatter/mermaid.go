@@ -0,0 +1,140 @@
+package formatter
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/jsimports"
+)
+
+// dependencyGraph holds the modules of a source and the modules each
+// imports, keyed by display name
+type dependencyGraph struct {
+	modules map[string]bool
+	edges   map[string]map[string]bool
+}
+
+// addEdge records that module from imports module to
+func (g *dependencyGraph) addEdge(from, to string) {
+	g.modules[from] = true
+	g.modules[to] = true
+	if g.edges[from] == nil {
+		g.edges[from] = map[string]bool{}
+	}
+	g.edges[from][to] = true
+}
+
+// newDependencyGraph collects the dependencies within root: Go packages
+// importing other packages of the root, and JavaScript and TypeScript files
+// importing other files through relative specifiers. Packages and bare
+// specifiers from outside the root are left out.
+func newDependencyGraph(root *analyzer.FileSystemNode) *dependencyGraph {
+	graph := &dependencyGraph{modules: map[string]bool{}, edges: map[string]map[string]bool{}}
+	if !root.IsDir {
+		return graph
+	}
+
+	packages := parseGoPackages(root)
+	for dir, pkg := range packages {
+		graph.modules[displayDir(dir)] = true
+		for _, syntax := range pkg.Files {
+			for _, spec := range syntax.Imports {
+				importPath, err := strconv.Unquote(spec.Path.Value)
+				if err != nil {
+					continue
+				}
+				if target := packageForImport(importPath, packages); target != nil && target.Dir != dir {
+					graph.addEdge(displayDir(dir), displayDir(target.Dir))
+				}
+			}
+		}
+	}
+
+	files := map[string]bool{}
+	for _, file := range root.Files() {
+		files[file.RelPath(root)] = true
+	}
+	for _, file := range root.Files() {
+		if file.IsBinary || !jsimports.IsScript(file.Language) {
+			continue
+		}
+		rel := file.RelPath(root)
+		graph.modules[rel] = true
+		for _, spec := range jsimports.Specifiers(file.Language, file.Content) {
+			if !jsimports.IsRelative(spec) {
+				continue
+			}
+			for _, candidate := range jsimports.Candidates(path.Join(path.Dir(rel), spec)) {
+				if files[candidate] && candidate != rel {
+					graph.addEdge(rel, candidate)
+					break
+				}
+			}
+		}
+	}
+
+	return graph
+}
+
+// WriteMermaid writes the dependencies of every source as a Mermaid
+// flowchart: Go packages linked to the packages they import, and
+// JavaScript and TypeScript files linked to the files they import. The
+// header is written as comments. With several sources, module names are
+// prefixed with the source name.
+func (d *Digest) WriteMermaid(w io.Writer) error {
+	var builder strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(d.Header.Text()), "\n") {
+		builder.WriteString("%% " + line + "\n")
+	}
+	builder.WriteString("graph TD\n")
+
+	ids := map[string]string{}
+	id := func(name string) string {
+		if ids[name] == "" {
+			ids[name] = fmt.Sprintf("m%d", len(ids)+1)
+		}
+		return ids[name]
+	}
+
+	for _, source := range d.Sources {
+		graph := newDependencyGraph(source.Root)
+		prefix := ""
+		if len(d.Sources) > 1 {
+			prefix = source.Root.Name + "/"
+		}
+
+		modules := make([]string, 0, len(graph.modules))
+		for module := range graph.modules {
+			modules = append(modules, module)
+		}
+		sort.Strings(modules)
+
+		for _, module := range modules {
+			builder.WriteString(fmt.Sprintf("    %s[\"%s\"]\n", id(prefix+module), mermaidLabel(prefix+module)))
+		}
+		for _, module := range modules {
+			targets := make([]string, 0, len(graph.edges[module]))
+			for target := range graph.edges[module] {
+				targets = append(targets, target)
+			}
+			sort.Strings(targets)
+			for _, target := range targets {
+				builder.WriteString(fmt.Sprintf("    %s --> %s\n", id(prefix+module), id(prefix+target)))
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// mermaidLabel escapes the characters of a label that end a quoted Mermaid
+// string
+func mermaidLabel(label string) string {
+	return strings.ReplaceAll(label, `"`, "#quot;")
+}
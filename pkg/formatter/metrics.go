@@ -0,0 +1,64 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/agris/ingest-clone/pkg/metrics"
+)
+
+// metricsColumns are the columns of the metrics CSV
+var metricsColumns = []string{"path", "bytes", "lines", "tokens", "language", "modified", "code", "comment", "blank", "complexity"}
+
+// WriteMetricsCSV writes one CSV row per file of the digest with its path,
+// size in bytes, lines and estimated tokens of its content, language, last
+// modification time, code, comment, and blank lines, and cyclomatic
+// complexity, after a header row. Binary files have no line counts, and
+// files in languages without complexity support no complexity. With several
+// sources, paths are prefixed with the source name.
+func (d *Digest) WriteMetricsCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(metricsColumns); err != nil {
+		return err
+	}
+
+	for _, source := range d.Sources {
+		prefix := ""
+		if len(d.Sources) > 1 {
+			prefix = source.Root.Name + "/"
+		}
+		for _, file := range source.Files {
+			lines, code, comment, blank, complexity := "", "", "", "", ""
+			if !file.Node.IsBinary {
+				counts := metrics.CountLines(file.Language, file.Content)
+				lines = strconv.Itoa(counts.Code + counts.Comment + counts.Blank)
+				code, comment, blank = strconv.Itoa(counts.Code), strconv.Itoa(counts.Comment), strconv.Itoa(counts.Blank)
+			}
+			if measurable(file.Node) {
+				if c, ok := metrics.CyclomaticComplexity(file.Language, file.Content); ok {
+					complexity = strconv.Itoa(c.Total)
+				}
+			}
+			err := writer.Write([]string{
+				prefix + file.Path,
+				strconv.FormatInt(file.Size, 10),
+				lines,
+				strconv.Itoa(file.Tokens),
+				file.Language,
+				file.ModTime.UTC().Format(time.RFC3339),
+				code,
+				comment,
+				blank,
+				complexity,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,75 @@
+package formatter
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// orientationDocs lists top-level documents placed before code by default,
+// in the order they are shown
+var orientationDocs = []string{"README", "ARCHITECTURE", "CONTRIBUTING"}
+
+// orderedFiles returns the files below root in the order they appear in the
+// file contents: files matching the ordering rules first, in rule order, then
+// the rest in tree order. Within each group, top-level orientation documents
+// come first unless disabled. When tests are included, they follow all other
+// files.
+func orderedFiles(root *analyzer.FileSystemNode, cfg *config.Config) []*analyzer.FileSystemNode {
+	files := root.Files()
+	if len(cfg.OrderPatterns) == 0 && cfg.NoReadmeFirst && cfg.Tests != config.TestsInclude {
+		return files
+	}
+
+	type rank struct {
+		test      bool
+		rule, doc int
+	}
+	ranks := make(map[*analyzer.FileSystemNode]rank, len(files))
+	for _, file := range files {
+		rel := file.RelPath(root)
+		r := rank{test: isTestSection(file, cfg), rule: cfg.OrderRank(rel), doc: len(orientationDocs)}
+		if !cfg.NoReadmeFirst {
+			r.doc = orientationRank(rel)
+		}
+		ranks[file] = r
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := ranks[files[i]], ranks[files[j]]
+		if a.test != b.test {
+			return b.test
+		}
+		if a.rule != b.rule {
+			return a.rule < b.rule
+		}
+		return a.doc < b.doc
+	})
+	return files
+}
+
+// isTestSection reports whether a file belongs in the tests section, which
+// only exists when tests are included alongside the other files
+func isTestSection(file *analyzer.FileSystemNode, cfg *config.Config) bool {
+	return cfg.Tests == config.TestsInclude && utils.IsTestFile(file.Path)
+}
+
+// orientationRank returns the position of a top-level orientation document
+// such as README.md, or len(orientationDocs) for any other file
+func orientationRank(rel string) int {
+	if strings.Contains(rel, "/") {
+		return len(orientationDocs)
+	}
+
+	name := strings.ToUpper(strings.TrimSuffix(rel, filepath.Ext(rel)))
+	for i, doc := range orientationDocs {
+		if name == doc {
+			return i
+		}
+	}
+	return len(orientationDocs)
+}
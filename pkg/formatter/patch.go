@@ -0,0 +1,36 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/patch"
+)
+
+// formatPatch lists the files changed by a diff with their added and
+// removed lines, followed by the diff itself
+func formatPatch(diff string) string {
+	files := patch.Parse(diff)
+	added, removed, width := 0, 0, 0
+	for _, file := range files {
+		added += file.Added
+		removed += file.Removed
+		width = max(width, len(file.Path))
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Patch (%s changed, +%d -%d):\n", pluralize(len(files), "file"), added, removed))
+	for _, file := range files {
+		status := ""
+		switch {
+		case file.IsNew:
+			status = " (new)"
+		case file.IsDeleted:
+			status = " (deleted)"
+		}
+		builder.WriteString(fmt.Sprintf("  %-*s  +%d -%d%s\n", width, file.Path, file.Added, file.Removed, status))
+	}
+	builder.WriteString("\n")
+	builder.WriteString(strings.TrimRight(diff, "\n") + "\n")
+	return builder.String()
+}
@@ -0,0 +1,34 @@
+package formatter
+
+import (
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// WrapPrompt turns a rendered text digest into a complete prompt: the
+// --prompt-file preamble precedes it and the --prompt instructions follow
+// it, each separated from the digest by a blank line
+func WrapPrompt(output string, cfg *config.Config) string {
+	return appendPrompt(promptPreamble(cfg)+output, cfg)
+}
+
+// promptPreamble returns the --prompt-file preamble followed by a blank
+// line, or an empty string without one
+func promptPreamble(cfg *config.Config) string {
+	preamble := strings.TrimRight(cfg.PromptPreamble, "\n")
+	if preamble == "" {
+		return ""
+	}
+	return preamble + "\n\n"
+}
+
+// appendPrompt places the --prompt instructions after output, separated by
+// a blank line
+func appendPrompt(output string, cfg *config.Config) string {
+	prompt := strings.TrimSpace(cfg.Prompt)
+	if prompt == "" {
+		return output
+	}
+	return strings.TrimRight(output, "\n") + "\n\n" + prompt + "\n"
+}
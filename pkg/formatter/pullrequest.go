@@ -0,0 +1,65 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/pullrequest"
+)
+
+// formatPullRequest describes a pull request with its description and its
+// comments, reviews, and review comments, oldest first
+func formatPullRequest(pr *pullrequest.PullRequest) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Pull request #%d: %s\n", pr.Number, pr.Title))
+	builder.WriteString(fmt.Sprintf("Author: @%s\n", pr.Author))
+	builder.WriteString(fmt.Sprintf("State: %s\n", pr.State))
+	builder.WriteString(fmt.Sprintf("Branches: %s -> %s\n", pr.Head, pr.Base))
+	if pr.URL != "" {
+		builder.WriteString(fmt.Sprintf("URL: %s\n", pr.URL))
+	}
+
+	if pr.Body != "" {
+		builder.WriteString("\n" + pr.Body + "\n")
+	}
+
+	if len(pr.Comments) == 0 {
+		builder.WriteString("\nComments: none\n")
+		return builder.String()
+	}
+	builder.WriteString(fmt.Sprintf("\nComments (%d):\n", len(pr.Comments)))
+	for _, comment := range pr.Comments {
+		writeComment(&builder, commentHeading(comment), comment.Body)
+	}
+	return builder.String()
+}
+
+// writeComment writes a comment of a thread after a blank line: its heading,
+// then its body indented below it
+func writeComment(builder *strings.Builder, heading, body string) {
+	builder.WriteString("\n" + heading + "\n")
+	if body == "" {
+		return
+	}
+	for _, line := range strings.Split(body, "\n") {
+		builder.WriteString(strings.TrimRight("    "+line, " ") + "\n")
+	}
+}
+
+// commentHeading introduces a comment with its author, kind, and date, such
+// as "@alice on api/handler.go:42, 2024-05-01:"
+func commentHeading(comment pullrequest.Comment) string {
+	heading := "  @" + comment.Author
+	switch {
+	case comment.Review != "":
+		heading += " reviewed (" + comment.Review + ")"
+	case comment.Path != "" && comment.Line > 0:
+		heading += fmt.Sprintf(" on %s:%d", comment.Path, comment.Line)
+	case comment.Path != "":
+		heading += fmt.Sprintf(" on %s (outdated)", comment.Path)
+	}
+	if !comment.Created.IsZero() {
+		heading += ", " + comment.Created.UTC().Format("2006-01-02")
+	}
+	return heading + ":"
+}
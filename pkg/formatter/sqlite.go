@@ -0,0 +1,165 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// sqliteSchema is the schema of SQLite digests. The metadata table holds the
+// header (tool, version, generated_at, source) and the sha256 of the checksum
+// manifest as key/value pairs. Each analyzed source (the
+// positional argument or each -f file) gets a row in sources, and every file
+// below it a row in files with its path relative to that source.
+const sqliteSchema = `CREATE TABLE metadata (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE sources (
+	id         INTEGER PRIMARY KEY,
+	path       TEXT NOT NULL,    -- absolute path of the analyzed file or directory
+	name       TEXT NOT NULL,    -- base name of the source
+	is_dir     INTEGER NOT NULL, -- 1 if the source is a directory
+	file_count INTEGER NOT NULL, -- number of files analyzed
+	size       INTEGER NOT NULL, -- total size in bytes
+	tokens     INTEGER NOT NULL, -- estimated tokens across all files
+	sha256     TEXT NOT NULL     -- SHA-256 of the checksum manifest of the source
+);
+CREATE TABLE files (
+	id        INTEGER PRIMARY KEY,
+	source_id INTEGER NOT NULL REFERENCES sources(id),
+	path      TEXT NOT NULL,    -- slash-separated path relative to the source
+	name      TEXT NOT NULL,    -- base name of the file
+	size      INTEGER NOT NULL, -- size in bytes
+	language  TEXT,             -- detected language, NULL if unknown
+	mime      TEXT,             -- MIME type detected from the contents, NULL if unknown
+	tokens    INTEGER NOT NULL, -- estimated tokens of the content
+	sha256    TEXT,             -- SHA-256 of the raw contents, NULL if unreadable
+	content   TEXT NOT NULL     -- file content or placeholder
+);
+CREATE INDEX files_sha256 ON files(sha256);
+CREATE INDEX files_path ON files(path);
+CREATE INDEX files_language ON files(language);
+`
+
+// sqliteChunksSchema holds the chunks of the files and their embeddings,
+// added when embeddings are requested. Embeddings are JSON arrays of floats.
+const sqliteChunksSchema = `CREATE TABLE chunks (
+	id         INTEGER PRIMARY KEY,
+	file_id    INTEGER NOT NULL REFERENCES files(id),
+	name       TEXT NOT NULL,    -- path and chunk index, such as main.go#2
+	idx        INTEGER NOT NULL, -- position of the chunk within the file, from 0
+	start_line INTEGER NOT NULL, -- first line of the chunk (1-based)
+	end_line   INTEGER NOT NULL, -- last line of the chunk (inclusive)
+	tokens     INTEGER NOT NULL, -- estimated tokens of the content
+	content    TEXT NOT NULL,    -- text of the chunk
+	embedding  TEXT NOT NULL     -- embedding vector as a JSON array
+);
+CREATE INDEX chunks_file_id ON chunks(file_id);
+`
+
+// WriteSQLite writes the digest to a SQLite database at path, replacing any
+// existing file. It requires the sqlite3 command-line tool.
+func (d *Digest) WriteSQLite(path string) error {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return fmt.Errorf("sqlite format requires the sqlite3 command-line tool: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	var script strings.Builder
+	script.WriteString("BEGIN;\n")
+	script.WriteString(sqliteSchema)
+	if d.cfg.Embed != "" {
+		script.WriteString(sqliteChunksSchema)
+	}
+
+	metadata := [][2]string{
+		{"tool", d.Header.Tool},
+		{"version", d.Header.Version},
+		{"generated_at", d.Header.Timestamp()},
+		{"source", d.Header.Source},
+		{"commit", d.Header.Commit},
+		{"command", d.Header.Command},
+		{"sha256", d.SHA256},
+		{"embedding_model", d.cfg.EmbedModel},
+	}
+	for _, kv := range metadata {
+		if kv[1] == "" {
+			continue
+		}
+		script.WriteString(fmt.Sprintf("INSERT INTO metadata VALUES (%s, %s);\n", sqlQuote(kv[0]), sqlQuote(kv[1])))
+	}
+
+	fileIDs := map[*FileEntry]int{}
+	for i, source := range d.Sources {
+		sourceID := i + 1
+		root := source.Root
+		script.WriteString(fmt.Sprintf("INSERT INTO sources VALUES (%d, %s, %s, %d, %d, %d, %d, %s);\n",
+			sourceID, sqlQuote(root.Path), sqlQuote(root.Name), sqlBool(root.IsDir),
+			len(source.Files), source.Summary.Size, source.Summary.Tokens, sqlQuote(source.Summary.SHA256)))
+
+		for _, file := range source.Files {
+			language := "NULL"
+			if file.Language != "" {
+				language = sqlQuote(file.Language)
+			}
+			mimeType := "NULL"
+			if file.MIME != "" {
+				mimeType = sqlQuote(file.MIME)
+			}
+			sha := "NULL"
+			if file.SHA256 != "" {
+				sha = sqlQuote(file.SHA256)
+			}
+
+			script.WriteString(fmt.Sprintf("INSERT INTO files (source_id, path, name, size, language, mime, tokens, sha256, content) VALUES (%d, %s, %s, %d, %s, %s, %d, %s, %s);\n",
+				sourceID, sqlQuote(file.Path), sqlQuote(file.Node.Name), file.Size,
+				language, mimeType, file.Tokens, sha, sqlQuote(file.Content)))
+			fileIDs[file] = len(fileIDs) + 1
+		}
+	}
+
+	if d.cfg.Embed != "" {
+		for _, chunk := range d.Chunks() {
+			embedding, err := json.Marshal(chunk.Embedding)
+			if err != nil {
+				return err
+			}
+			script.WriteString(fmt.Sprintf("INSERT INTO chunks (file_id, name, idx, start_line, end_line, tokens, content, embedding) VALUES (%d, %s, %d, %d, %d, %d, %s, %s);\n",
+				fileIDs[chunk.File], sqlQuote(chunk.ID), chunk.Index, chunk.StartLine, chunk.EndLine,
+				chunk.Tokens, sqlQuote(chunk.Content), sqlQuote(string(embedding))))
+		}
+	}
+
+	script.WriteString("COMMIT;\n")
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("sqlite3", "-bail", path)
+	cmd.Stdin = strings.NewReader(script.String())
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("sqlite3: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
+// sqlQuote quotes a string as a SQL literal
+func sqlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// sqlBool converts a boolean to a SQL integer
+func sqlBool(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,214 @@
+package formatter
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+)
+
+// goSymbol is an exported package-level Go declaration
+type goSymbol struct {
+	Name  string          // Declared name
+	Kind  string          // func, type, const, or var
+	File  string          // Defining file relative to the root
+	Users map[string]bool // Other files referencing the symbol
+}
+
+// goPackage collects the parsed files and symbols of one directory
+type goPackage struct {
+	Dir     string               // Directory relative to the root
+	Name    string               // Package name
+	Files   map[string]*ast.File // Parsed files by path relative to the root
+	Symbols map[string]*goSymbol // Exported symbols by name
+}
+
+// formatGoSymbols lists every exported package-level Go symbol with the
+// file defining it and the files referencing it. References are found
+// syntactically: qualified identifiers through imports of packages within
+// the root, and plain identifiers from other files of the same package.
+// Methods and fields are not tracked.
+func formatGoSymbols(root *analyzer.FileSystemNode) string {
+	packages := parseGoPackages(root)
+	if len(packages) == 0 {
+		return ""
+	}
+
+	for _, pkg := range packages {
+		for file, syntax := range pkg.Files {
+			collectReferences(pkg, file, syntax, packages)
+		}
+	}
+
+	dirs := make([]string, 0, len(packages))
+	for dir := range packages {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var builder strings.Builder
+	count := 0
+	for _, dir := range dirs {
+		pkg := packages[dir]
+		if len(pkg.Symbols) == 0 {
+			continue
+		}
+
+		names := make([]string, 0, len(pkg.Symbols))
+		for name := range pkg.Symbols {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		builder.WriteString(fmt.Sprintf("%s (package %s)\n", displayDir(dir), pkg.Name))
+		for _, name := range names {
+			symbol := pkg.Symbols[name]
+			builder.WriteString(fmt.Sprintf("  %s %s  %s\n", symbol.Kind, symbol.Name, symbol.File))
+			if len(symbol.Users) > 0 {
+				users := make([]string, 0, len(symbol.Users))
+				for user := range symbol.Users {
+					users = append(users, user)
+				}
+				sort.Strings(users)
+				builder.WriteString(fmt.Sprintf("    used in: %s\n", strings.Join(users, ", ")))
+			}
+			count++
+		}
+	}
+
+	return fmt.Sprintf("Go symbols (%d):\n%s", count, builder.String())
+}
+
+// displayDir names the root directory "." in the symbol listing
+func displayDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
+
+// parseGoPackages parses the Go files below root and collects their
+// exported declarations by directory. Files that do not parse are skipped.
+func parseGoPackages(root *analyzer.FileSystemNode) map[string]*goPackage {
+	packages := map[string]*goPackage{}
+	fset := token.NewFileSet()
+
+	for _, file := range root.Files() {
+		if !strings.HasSuffix(file.Name, ".go") || file.IsBinary {
+			continue
+		}
+		rel := file.RelPath(root)
+		syntax, err := parser.ParseFile(fset, rel, file.Content, parser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+
+		dir := path.Dir(rel)
+		if dir == "." {
+			dir = ""
+		}
+		pkg := packages[dir]
+		if pkg == nil {
+			pkg = &goPackage{Dir: dir, Name: syntax.Name.Name, Files: map[string]*ast.File{}, Symbols: map[string]*goSymbol{}}
+			packages[dir] = pkg
+		}
+		pkg.Files[rel] = syntax
+
+		for _, decl := range syntax.Decls {
+			for name, kind := range declaredNames(decl) {
+				if ast.IsExported(name) {
+					pkg.Symbols[name] = &goSymbol{Name: name, Kind: kind, File: rel, Users: map[string]bool{}}
+				}
+			}
+		}
+	}
+
+	return packages
+}
+
+// declaredNames returns the package-level names a declaration introduces
+// with their kind; methods introduce none
+func declaredNames(decl ast.Decl) map[string]string {
+	names := map[string]string{}
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv == nil {
+			names[d.Name.Name] = "func"
+		}
+	case *ast.GenDecl:
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names[s.Name.Name] = "type"
+			case *ast.ValueSpec:
+				for _, ident := range s.Names {
+					names[ident.Name] = d.Tok.String()
+				}
+			}
+		}
+	}
+	return names
+}
+
+// collectReferences records the symbols a file references, both qualified
+// through imports and unqualified within its own package
+func collectReferences(pkg *goPackage, file string, syntax *ast.File, packages map[string]*goPackage) {
+	// Map import names to packages within the root
+	imported := map[string]*goPackage{}
+	for _, spec := range syntax.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		target := packageForImport(importPath, packages)
+		if target == nil {
+			continue
+		}
+
+		name := target.Name
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imported[name] = target
+	}
+
+	ast.Inspect(syntax, func(n ast.Node) bool {
+		switch node := n.(type) {
+		case *ast.SelectorExpr:
+			if ident, ok := node.X.(*ast.Ident); ok {
+				if target := imported[ident.Name]; target != nil {
+					if symbol := target.Symbols[node.Sel.Name]; symbol != nil && symbol.File != file {
+						symbol.Users[file] = true
+					}
+					return false
+				}
+			}
+		case *ast.Ident:
+			if symbol := pkg.Symbols[node.Name]; symbol != nil && symbol.File != file {
+				symbol.Users[file] = true
+			}
+		}
+		return true
+	})
+}
+
+// packageForImport finds the package within the root whose directory is
+// the longest suffix of the import path
+func packageForImport(importPath string, packages map[string]*goPackage) *goPackage {
+	var best *goPackage
+	for dir, pkg := range packages {
+		if dir == "" {
+			continue
+		}
+		if (importPath == dir || strings.HasSuffix(importPath, "/"+dir)) && (best == nil || len(dir) > len(best.Dir)) {
+			best = pkg
+		}
+	}
+	return best
+}
@@ -0,0 +1,83 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// TemplateData is the data available to custom output templates. Sources
+// holds one entry per analyzed source; Summary, Tree, and Files combine all
+// of them for the common single-source case.
+type TemplateData struct {
+	Header  *Header          // Tool, version, timestamp, and source identity
+	Summary string           // Summaries of all sources
+	Tree    string           // Directory structures of all sources
+	Files   []*FileEntry     // Files of all sources in digest order
+	Sources []TemplateSource // Each analyzed source
+}
+
+// TemplateSource describes one analyzed file or directory
+type TemplateSource struct {
+	Root    *analyzer.FileSystemNode // Root node of the source
+	Summary string                   // Summary of the analysis
+	Tree    string                   // Tree-like representation of the directory structure
+	SHA256  string                   // SHA-256 of the checksum manifest of the source
+	Files   []*FileEntry             // Files below the root in digest order
+}
+
+// templateFuncs are the helper functions available to templates
+var templateFuncs = template.FuncMap{
+	"join":         strings.Join,
+	"trim":         strings.TrimSpace,
+	"upper":        strings.ToUpper,
+	"lower":        strings.ToLower,
+	"replace":      strings.ReplaceAll,
+	"formatSize":   utils.FormatSize,
+	"formatTokens": utils.FormatTokenCount,
+}
+
+// Template renders the digest with the Go text/template at path
+func (d *Digest) Template(path string) (string, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return "", err
+	}
+
+	data := &TemplateData{Header: d.Header}
+	summaries, trees := []string{}, []string{}
+
+	for _, s := range d.Sources {
+		source := TemplateSource{
+			Root:    s.Root,
+			Summary: formatSummary(s, d.cfg),
+			Tree:    formatDirectoryStructure(s, d.cfg),
+			SHA256:  s.Summary.SHA256,
+			Files:   s.Files,
+		}
+
+		data.Sources = append(data.Sources, source)
+		data.Files = append(data.Files, source.Files...)
+		summaries = append(summaries, source.Summary)
+		trees = append(trees, source.Tree)
+	}
+
+	data.Summary = strings.Join(summaries, "\n")
+	data.Tree = strings.Join(trees, "\n")
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
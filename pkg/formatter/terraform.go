@@ -0,0 +1,80 @@
+package formatter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+	"github.com/agris/ingest-clone/pkg/terraform"
+)
+
+// formatTerraform summarizes the providers, modules, resources, and data
+// sources of every directory holding Terraform files, or returns an empty
+// string if there are none
+func formatTerraform(root *analyzer.FileSystemNode) string {
+	var dirs []string
+	configs := map[string]*terraform.Config{}
+	for _, file := range root.Files() {
+		if !terraform.IsConfig(file.Name) {
+			continue
+		}
+		dir := path.Dir(file.RelPath(root))
+		if dir == "." {
+			dir = root.Name
+		}
+		if configs[dir] == nil {
+			dirs = append(dirs, dir)
+			configs[dir] = &terraform.Config{}
+		}
+		configs[dir].Add(terraform.Parse(file.Content))
+	}
+
+	var builder strings.Builder
+	for _, dir := range dirs {
+		cfg := configs[dir]
+		if cfg.Empty() {
+			continue
+		}
+
+		managed := 0
+		for _, resource := range cfg.Resources {
+			if !resource.Data {
+				managed++
+			}
+		}
+		builder.WriteString(fmt.Sprintf("%s (%s, %s, %s, %s):\n", dir,
+			pluralize(len(cfg.Providers), "provider"), pluralize(len(cfg.Modules), "module"),
+			pluralize(managed, "resource"), pluralize(len(cfg.Resources)-managed, "data source")))
+
+		for _, provider := range cfg.Providers {
+			builder.WriteString("  " + joinFields("provider", provider.Name, provider.Source, provider.Version) + "\n")
+		}
+		for _, module := range cfg.Modules {
+			builder.WriteString("  " + joinFields("module", module.Name, module.Source, module.Version) + "\n")
+		}
+		for _, resource := range cfg.Resources {
+			kind := "resource"
+			if resource.Data {
+				kind = "data"
+			}
+			builder.WriteString(fmt.Sprintf("  %s %s.%s (%s)\n", kind, resource.Type, resource.Name, resource.Provider))
+		}
+	}
+
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "Terraform:\n" + builder.String()
+}
+
+// joinFields joins the non-empty fields with spaces
+func joinFields(fields ...string) string {
+	var nonEmpty []string
+	for _, field := range fields {
+		if field != "" {
+			nonEmpty = append(nonEmpty, field)
+		}
+	}
+	return strings.Join(nonEmpty, " ")
+}
@@ -0,0 +1,42 @@
+package formatter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/analyzer"
+)
+
+// todoPattern matches TODO-style markers inside a comment on a single line
+var todoPattern = regexp.MustCompile(`(//|/\*|^\s*\*|#|--|<!--|;)\s*.*?\b(TODO|FIXME|HACK|XXX)\b`)
+
+// maxTodoLength limits how much of each marked line is shown
+const maxTodoLength = 120
+
+// formatTodos lists every TODO, FIXME, HACK, and XXX comment with its file and line
+func formatTodos(root *analyzer.FileSystemNode) string {
+	var items []string
+
+	for _, file := range root.Files() {
+		for i, line := range strings.Split(file.Content, "\n") {
+			match := todoPattern.FindStringSubmatchIndex(line)
+			if match == nil {
+				continue
+			}
+
+			// Show the line from the marker onwards
+			text := strings.TrimSpace(line[match[4]:])
+			if len(text) > maxTodoLength {
+				text = text[:maxTodoLength] + "..."
+			}
+			items = append(items, fmt.Sprintf("%s:%d: %s", file.RelPath(root), i+1, text))
+		}
+	}
+
+	if len(items) == 0 {
+		return "TODOs: none\n"
+	}
+
+	return fmt.Sprintf("TODOs (%d):\n%s\n", len(items), strings.Join(items, "\n"))
+}
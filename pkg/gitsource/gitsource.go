@@ -0,0 +1,136 @@
+package gitsource
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// urlSchemes are the URL schemes cloned with git
+var urlSchemes = []string{"https://", "http://", "git://", "ssh://", "git+ssh://", "file://"}
+
+// scpPattern matches scp-style git remotes such as git@github.com:org/repo.git
+var scpPattern = regexp.MustCompile(`^[^@/\s]+@[^:/\s]+:.+$`)
+
+// IsURL reports whether source is a git repository URL: an http(s)://,
+// git://, ssh://, or file:// URL, or an scp-style remote that ends in .git,
+// uses the conventional git@ user, or names a known git host. Other
+// user@host:/path sources are left to the ssh directory source.
+func IsURL(source string, hosts map[string]string) bool {
+	for _, scheme := range urlSchemes {
+		if strings.HasPrefix(source, scheme) {
+			return true
+		}
+	}
+	if !scpPattern.MatchString(source) {
+		return false
+	}
+	return strings.HasPrefix(source, "git@") || strings.HasSuffix(strings.TrimSuffix(source, "/"), ".git") ||
+		hostKind(remoteHost(source), hosts) != ""
+}
+
+// Fetch makes a shallow clone of the repository at source in a new
+// temporary directory, or a full one for the features reading its history. Browse URLs of GitHub, GitLab, and Bitbucket are
+// resolved to the repository, branch, and directory they show. With a
+// subpath, from cfg.Subpath or the URL, the clone is partial and sparse, so
+// only the blobs below that directory are downloaded, and the subdirectory
+// is returned as the directory to analyze. It returns a cleanup function
+// removing the clone.
+func Fetch(source string, cfg *config.Config) (string, func(), error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil, fmt.Errorf("git URL sources require the git command-line tool: %w", err)
+	}
+
+	remote := Parse(source, cfg.GitHosts)
+	if cfg.Subpath != "" {
+		remote.Subpath = cfg.Subpath
+	}
+
+	subpath := strings.Trim(filepath.ToSlash(remote.Subpath), "/")
+	if subpath != "" && (path.Clean(subpath) != subpath || subpath == ".." || strings.HasPrefix(subpath, "../")) {
+		return "", nil, fmt.Errorf("invalid subpath: %s", remote.Subpath)
+	}
+
+	tmp, err := os.MkdirTemp("", "ingest-git-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	dest := filepath.Join(tmp, RepoName(remote.URL))
+	env := authEnv(remote)
+
+	args := append([]string{"clone", "--quiet"}, depthArgs(cfg)...)
+	if remote.Ref != "" {
+		args = append(args, "--branch", remote.Ref)
+	}
+	if subpath != "" {
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	if err := run("", env, append(args, "--", remote.URL, dest)...); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if subpath == "" {
+		return dest, cleanup, nil
+	}
+
+	// Cone mode checks out the subpath and the files on the way to it
+	if err := run(dest, env, "sparse-checkout", "set", "--", subpath); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	root := filepath.Join(dest, filepath.FromSlash(subpath))
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		cleanup()
+		return "", nil, fmt.Errorf("subpath '%s' is not a directory in the repository", subpath)
+	}
+	return root, cleanup, nil
+}
+
+// depthArgs returns the clone and fetch arguments limiting the history to
+// the latest commit, or none when --churn, --blame, or --authors read it
+func depthArgs(cfg *config.Config) []string {
+	if cfg.Churn || cfg.Blame || cfg.Authors {
+		return nil
+	}
+	return []string{"--depth", "1"}
+}
+
+// RepoName returns the repository name of a git URL, such as "repo" for
+// https://github.com/org/repo.git
+func RepoName(source string) string {
+	name := strings.TrimSuffix(strings.TrimRight(source, "/"), ".git")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+	if name == "" {
+		return "repo"
+	}
+	return name
+}
+
+// run executes a git command with the extra environment, without prompting
+// for credentials. LFS objects are left as pointers, so only those of
+// selected files are fetched, and only with --lfs.
+func run(dir string, env []string, args ...string) error {
+	var stderr bytes.Buffer
+	slog.Debug("Running git", "args", strings.Join(args, " "), "dir", dir)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_LFS_SKIP_SMUDGE=1"), env...)
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package gitsource
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Kinds of git hosting services
+const (
+	GitHub    = "github"
+	GitLab    = "gitlab"
+	Bitbucket = "bitbucket"
+)
+
+// defaultHosts maps the public hosting services to their kind
+var defaultHosts = map[string]string{
+	"github.com":    GitHub,
+	"gitlab.com":    GitLab,
+	"bitbucket.org": Bitbucket,
+}
+
+// tokenEnv lists, per kind, the environment variables holding an access
+// token and the user name the service expects with it
+var tokenEnv = map[string]struct {
+	vars []string
+	user string
+}{
+	GitHub:    {[]string{"GITHUB_TOKEN", "GH_TOKEN"}, "x-access-token"},
+	GitLab:    {[]string{"GITLAB_TOKEN", "CI_JOB_TOKEN"}, "oauth2"},
+	Bitbucket: {[]string{"BITBUCKET_TOKEN"}, "x-token-auth"},
+}
+
+// browseMarkers are the path segments that start the branch and directory
+// part of each kind's web URLs, such as /tree/main/cmd on GitHub
+var browseMarkers = map[string][]string{
+	GitHub:    {"tree", "blob"},
+	GitLab:    {"-"},
+	Bitbucket: {"src"},
+}
+
+// Remote is a git repository URL resolved from a source
+type Remote struct {
+	URL     string // URL passed to git clone
+	Host    string // Host name of the remote
+	Kind    string // Hosting service kind, empty if unknown
+	Ref     string // Branch or tag shown by a browse URL
+	Subpath string // Directory shown by a browse URL
+}
+
+// ParseHosts parses --git-host values of the form host=kind, where kind is
+// github, gitlab, or bitbucket, for self-hosted instances
+func ParseHosts(values []string) (map[string]string, error) {
+	hosts := map[string]string{}
+	for _, value := range values {
+		host, kind, ok := strings.Cut(value, "=")
+		if !ok || host == "" {
+			return nil, fmt.Errorf("git host '%s' is not of the form host=kind", value)
+		}
+		if _, known := tokenEnv[kind]; !known {
+			return nil, fmt.Errorf("unknown git host kind '%s' (use github, gitlab, or bitbucket)", kind)
+		}
+		hosts[strings.ToLower(host)] = kind
+	}
+	return hosts, nil
+}
+
+// Parse resolves source to the repository to clone. Web URLs such as
+// https://github.com/org/repo/tree/main/cmd,
+// https://gitlab.com/group/sub/repo/-/tree/main/cmd, and
+// https://bitbucket.org/team/repo/src/main/cmd are reduced to the
+// repository URL, with the branch and directory kept.
+func Parse(source string, hosts map[string]string) Remote {
+	remote := Remote{URL: source, Host: remoteHost(source)}
+	remote.Kind = hostKind(remote.Host, hosts)
+
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || remote.Kind == "" {
+		return remote
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i, segment := range segments {
+		if i < 2 || !isMarker(remote.Kind, segment) {
+			continue
+		}
+
+		rest := segments[i+1:]
+		// GitLab puts the view after the marker: /-/tree/<ref>/<path>
+		if remote.Kind == GitLab && len(rest) > 0 && (rest[0] == "tree" || rest[0] == "blob") {
+			rest = rest[1:]
+		}
+		if len(rest) > 0 {
+			remote.Ref = rest[0]
+			remote.Subpath = strings.Join(rest[1:], "/")
+		}
+
+		u.Path = "/" + strings.Join(segments[:i], "/")
+		u.RawQuery, u.Fragment = "", ""
+		remote.URL = u.String()
+		break
+	}
+	return remote
+}
+
+// isMarker reports whether a path segment starts the browse part of a URL
+func isMarker(kind, segment string) bool {
+	for _, marker := range browseMarkers[kind] {
+		if segment == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteHost returns the lowercased host name of a URL or scp-style remote
+func remoteHost(source string) string {
+	if u, err := url.Parse(source); err == nil && u.Host != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	if match := scpPattern.FindString(source); match != "" {
+		_, rest, _ := strings.Cut(match, "@")
+		host, _, _ := strings.Cut(rest, ":")
+		return strings.ToLower(host)
+	}
+	return ""
+}
+
+// hostKind returns the hosting service kind of host, checking the
+// configured self-hosted instances before the public services
+func hostKind(host string, hosts map[string]string) string {
+	if kind, ok := hosts[host]; ok {
+		return kind
+	}
+	return defaultHosts[host]
+}
+
+// authEnv returns the environment configuring git to send the access token
+// for an HTTPS remote as a basic authorization header scoped to its host.
+// Passing it through GIT_CONFIG_* keeps the token out of the process list.
+func authEnv(remote Remote) []string {
+	if !strings.HasPrefix(remote.URL, "https://") || remote.Kind == "" {
+		return nil
+	}
+
+	token := accessToken(remote.Kind)
+	if token == "" {
+		return nil
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(tokenEnv[remote.Kind].user + ":" + token))
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.https://" + remote.Host + "/.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+	}
+}
+
+// accessToken returns the first access token set in the environment for a
+// hosting service kind, or an empty string
+func accessToken(kind string) string {
+	for _, name := range tokenEnv[kind].vars {
+		if token := os.Getenv(name); token != "" {
+			return token
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package gitsource
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/issues"
+)
+
+// IssueTracker returns the issue tracker of the GitHub or GitLab repository
+// at remote: a git URL, browse URL, pull request URL, or scp-style remote
+// such as an origin. Self-hosted instances use the API below /api/v3 on
+// GitHub and /api/v4 on GitLab.
+func IssueTracker(remote string, hosts map[string]string) (issues.Tracker, error) {
+	if ref, ok := PullRequestRef(remote, hosts); ok {
+		return issues.Tracker{Kind: issues.GitHub, API: ref.API, Project: ref.Owner + "/" + ref.Repo}, nil
+	}
+
+	parsed := Parse(remote, hosts)
+	var project string
+	if u, err := url.Parse(parsed.URL); err == nil && u.Host != "" {
+		project = u.Path
+	} else if _, rest, ok := strings.Cut(parsed.URL, ":"); ok {
+		project = rest
+	}
+	project = strings.TrimSuffix(strings.Trim(project, "/"), ".git")
+
+	if project == "" || !strings.Contains(project, "/") {
+		return issues.Tracker{}, fmt.Errorf("no repository path in '%s'", remote)
+	}
+	switch parsed.Kind {
+	case GitHub:
+		api := "https://" + parsed.Host + "/api/v3"
+		if parsed.Host == "github.com" {
+			api = "https://api.github.com"
+		}
+		return issues.Tracker{Kind: issues.GitHub, API: api, Project: project}, nil
+	case GitLab:
+		return issues.Tracker{Kind: issues.GitLab, API: "https://" + parsed.Host + "/api/v4", Project: project}, nil
+	}
+	return issues.Tracker{}, fmt.Errorf("'%s' is not a GitHub or GitLab repository (see --git-host)", remote)
+}
+
+// Issues reads the numbered issues of the repository at remote, with their
+// comments, authenticating with the access token from the environment
+func Issues(remote string, hosts map[string]string, numbers []int) ([]*issues.Issue, error) {
+	tracker, err := IssueTracker(remote, hosts)
+	if err != nil {
+		return nil, err
+	}
+	client := issues.New(tracker, accessToken(tracker.Kind))
+
+	var found []*issues.Issue
+	for _, number := range numbers {
+		issue, err := client.Fetch(number)
+		if err != nil {
+			return nil, fmt.Errorf("issue #%d: %w", number, err)
+		}
+		found = append(found, issue)
+	}
+	return found, nil
+}
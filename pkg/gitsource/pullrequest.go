@@ -0,0 +1,141 @@
+package gitsource
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+	"github.com/agris/ingest-clone/pkg/patch"
+	"github.com/agris/ingest-clone/pkg/pullrequest"
+)
+
+// PullRequestRef resolves a GitHub pull request URL such as
+// https://github.com/org/repo/pull/1234, optionally followed by a tab like
+// /files, to the pull request it shows. Self-hosted GitHub instances use the
+// API below /api/v3.
+func PullRequestRef(source string, hosts map[string]string) (pullrequest.Ref, bool) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "https" || hostKind(strings.ToLower(u.Hostname()), hosts) != GitHub {
+		return pullrequest.Ref{}, false
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(segments) < 4 || segments[0] == "" || segments[1] == "" || segments[2] != "pull" {
+		return pullrequest.Ref{}, false
+	}
+	number, err := strconv.Atoi(segments[3])
+	if err != nil || number <= 0 {
+		return pullrequest.Ref{}, false
+	}
+
+	api := "https://" + u.Host + "/api/v3"
+	if strings.EqualFold(u.Hostname(), "github.com") {
+		api = "https://api.github.com"
+	}
+	return pullrequest.Ref{API: api, Owner: segments[0], Repo: strings.TrimSuffix(segments[1], ".git"), Number: number}, true
+}
+
+// IsPullRequest reports whether source is a GitHub pull request URL
+func IsPullRequest(source string, hosts map[string]string) bool {
+	_, ok := PullRequestRef(source, hosts)
+	return ok
+}
+
+// PullRequest reads the pull request at the URL source, with its diff and
+// comments, authenticating with the GitHub token from the environment
+func PullRequest(source string, hosts map[string]string) (*pullrequest.PullRequest, error) {
+	ref, ok := PullRequestRef(source, hosts)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a GitHub pull request URL", source)
+	}
+	return pullrequest.New(accessToken(GitHub)).Fetch(ref)
+}
+
+// FetchPullRequest checks out the head of the pull request at the URL
+// source in a new temporary directory. Only the files changed by cfg.Patch,
+// the diff of the pull request, are checked out, and their blobs alone are
+// downloaded. It returns a cleanup function removing the checkout.
+func FetchPullRequest(source string, cfg *config.Config) (string, func(), error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", nil, fmt.Errorf("pull request sources require the git command-line tool: %w", err)
+	}
+	ref, ok := PullRequestRef(source, cfg.GitHosts)
+	if !ok {
+		return "", nil, fmt.Errorf("'%s' is not a GitHub pull request URL", source)
+	}
+	u, _ := url.Parse(source)
+	remote := Remote{
+		URL:  fmt.Sprintf("https://%s/%s/%s.git", u.Host, ref.Owner, ref.Repo),
+		Host: strings.ToLower(u.Hostname()),
+		Kind: GitHub,
+	}
+
+	tmp, err := os.MkdirTemp("", "ingest-pr-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	dest := filepath.Join(tmp, ref.Repo)
+	env := authEnv(remote)
+
+	// The sparse checkout patterns are anchored and escaped paths
+	var patterns strings.Builder
+	for _, file := range patch.Parse(cfg.Patch) {
+		if !file.IsDeleted {
+			patterns.WriteString(sparsePattern(file.Path) + "\n")
+		}
+	}
+
+	steps := [][]string{
+		{"init", "--quiet", dest},
+		{"-C", dest, "remote", "add", "origin", remote.URL},
+		{"-C", dest, "config", "core.sparseCheckout", "true"},
+	}
+	for _, args := range steps {
+		if err := run("", env, args...); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+	}
+	info := filepath.Join(dest, ".git", "info")
+	if err := os.MkdirAll(info, 0755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if err := os.WriteFile(filepath.Join(info, "sparse-checkout"), []byte(patterns.String()), 0644); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	// GitHub keeps the head of every pull request, including those from
+	// forks, at refs/pull/N/head of the base repository
+	head := fmt.Sprintf("refs/pull/%d/head", ref.Number)
+	fetch := append(append([]string{"fetch", "--quiet"}, depthArgs(cfg)...), "--filter=blob:none", "origin", head)
+	if err := run(dest, env, fetch...); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if err := run(dest, env, "checkout", "--quiet", "FETCH_HEAD"); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return dest, cleanup, nil
+}
+
+// sparsePattern returns the sparse checkout pattern matching exactly the
+// slash-separated path, escaping the characters patterns give a meaning to
+func sparsePattern(path string) string {
+	var b strings.Builder
+	b.WriteString("/")
+	for _, r := range path {
+		if strings.ContainsRune(`\*?[!# `, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
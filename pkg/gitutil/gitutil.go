@@ -0,0 +1,267 @@
+package gitutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnavailable is returned when git cannot be used for the source
+var ErrUnavailable = errors.New("git unavailable")
+
+// Repo provides lazy access to the git work tree containing a source path.
+// Git is a soft dependency: nothing is probed until a feature asks for it,
+// and when git is missing, disabled, or the source is not a work tree the
+// reason is kept so it can be reported instead of failing the run.
+type Repo struct {
+	dir      string // Directory the probe starts from
+	disabled bool   // Whether git probing was disabled by the user
+
+	once   sync.Once
+	used   bool   // Whether any feature asked for git
+	root   string // Top-level directory of the work tree
+	reason string // Why git is unavailable (empty if available)
+}
+
+// Open returns a Repo for the given source path without probing git yet
+func Open(source string, disabled bool) *Repo {
+	dir := source
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		dir = filepath.Dir(source)
+	}
+
+	return &Repo{dir: dir, disabled: disabled}
+}
+
+// probe checks for the git binary and a surrounding work tree once
+func (r *Repo) probe() {
+	r.once.Do(func() {
+		if r.disabled {
+			r.reason = "git probing disabled by --no-git"
+			return
+		}
+
+		if _, err := exec.LookPath("git"); err != nil {
+			r.reason = "git executable not found in PATH"
+			return
+		}
+
+		out, err := run(r.dir, "rev-parse", "--show-toplevel")
+		if err != nil {
+			r.reason = "source is not inside a git work tree"
+			return
+		}
+
+		r.root = strings.TrimSpace(out)
+	})
+}
+
+// Available reports whether git can be used for the source
+func (r *Repo) Available() bool {
+	r.probe()
+	r.used = true
+	return r.reason == ""
+}
+
+// Root returns the top-level directory of the work tree, or an empty string
+func (r *Repo) Root() string {
+	if !r.Available() {
+		return ""
+	}
+	return r.root
+}
+
+// Run executes a git command at the root of the work tree
+func (r *Repo) Run(args ...string) (string, error) {
+	if !r.Available() {
+		return "", fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	return run(r.root, args...)
+}
+
+// CommitTime returns the committer date of HEAD
+func (r *Repo) CommitTime() (time.Time, error) {
+	out, err := r.Run("log", "-1", "--format=%cI")
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(out))
+}
+
+// Head returns the commit hash of HEAD. Unlike the other features it does
+// not count as asking for git, since the commit only describes the source.
+func (r *Repo) Head() (string, error) {
+	r.probe()
+	if r.reason != "" {
+		return "", fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	out, err := run(r.root, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Origin returns the top-level directory of the work tree and the URL of
+// its origin remote, which is empty if there is none. Like Head it does not
+// count as asking for git.
+func (r *Repo) Origin() (root, url string, err error) {
+	r.probe()
+	if r.reason != "" {
+		return "", "", fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	out, err := run(r.root, "remote", "get-url", "origin")
+	if err != nil {
+		return r.root, "", nil
+	}
+	return r.root, strings.TrimSpace(out), nil
+}
+
+// IgnoredPaths returns the absolute paths of untracked files and directories
+// excluded by .gitignore, .git/info/exclude, and the user's core.excludesFile.
+// Like Head it does not count as asking for git, since every run applies the
+// ignore rules where git is available.
+func (r *Repo) IgnoredPaths() ([]string, error) {
+	r.probe()
+	if r.reason != "" {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	out, err := run(r.root, "ls-files", "-z", "--others", "--ignored", "--exclude-standard", "--directory")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, rel := range strings.Split(out, "\x00") {
+		rel = strings.TrimSuffix(rel, "/")
+		if rel == "" {
+			continue
+		}
+		paths = append(paths, filepath.Join(r.root, filepath.FromSlash(rel)))
+	}
+
+	return paths, nil
+}
+
+// FileChurn counts the commits that changed a file and their authors
+type FileChurn struct {
+	Commits int // Commits changing the file
+	Authors int // Distinct author emails of those commits
+}
+
+// Churn returns how often each file below dir changed in the commits since
+// the given date, in any format git accepts such as "6 months ago", keyed by
+// slash-separated path relative to dir. Merge commits are not counted, and
+// renamed files count from their new name.
+func (r *Repo) Churn(dir, since string) (map[string]FileChurn, error) {
+	if !r.Available() {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	out, err := run(dir, "-c", "core.quotePath=false", "log", "--since="+since, "--no-merges",
+		"--relative", "--name-only", "--format=%x00%aE", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+
+	commits := map[string]int{}
+	authors := map[string]map[string]bool{}
+	for _, entry := range strings.Split(out, "\x00") {
+		author, files, _ := strings.Cut(entry, "\n")
+		for _, file := range strings.Split(files, "\n") {
+			if file == "" {
+				continue
+			}
+			commits[file]++
+			if authors[file] == nil {
+				authors[file] = map[string]bool{}
+			}
+			authors[file][strings.ToLower(author)] = true
+		}
+	}
+
+	churn := make(map[string]FileChurn, len(commits))
+	for file, n := range commits {
+		churn[file] = FileChurn{Commits: n, Authors: len(authors[file])}
+	}
+	return churn, nil
+}
+
+// BlameLine is the commit that last changed a line of a file
+type BlameLine struct {
+	Commit string // Full commit hash, all zeros for uncommitted changes
+	Author string // Author name
+	Email  string // Author email, without angle brackets
+}
+
+// Committed reports whether the line is part of a commit rather than an
+// uncommitted change in the work tree
+func (l BlameLine) Committed() bool {
+	return strings.Trim(l.Commit, "0") != ""
+}
+
+// Blame returns, for every line of the file at path, the commit that last
+// changed it, ignoring whitespace changes. It fails for files git does not
+// track.
+func (r *Repo) Blame(path string) ([]BlameLine, error) {
+	if !r.Available() {
+		return nil, fmt.Errorf("%w: %s", ErrUnavailable, r.reason)
+	}
+	out, err := run(filepath.Dir(path), "blame", "-w", "--line-porcelain", "--", filepath.Base(path))
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []BlameLine
+	var current BlameLine
+	for _, line := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			// The content line ends the entry
+			lines = append(lines, current)
+			current = BlameLine{}
+		case strings.HasPrefix(line, "author "):
+			current.Author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-mail "):
+			current.Email = strings.Trim(strings.TrimPrefix(line, "author-mail "), "<>")
+		case current.Commit == "" && line != "":
+			current.Commit, _, _ = strings.Cut(line, " ")
+		}
+	}
+	return lines, nil
+}
+
+// Note returns a human-readable explanation when a feature asked for git but
+// it was unavailable, or an empty string otherwise
+func (r *Repo) Note() string {
+	if r == nil || !r.used || r.reason == "" {
+		return ""
+	}
+	return r.reason
+}
+
+// run executes git in the given directory and returns its standard output
+func run(dir string, args ...string) (string, error) {
+	slog.Debug("Running git", "args", strings.Join(args, " "), "dir", dir)
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			return "", err
+		}
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+
+	return stdout.String(), nil
+}
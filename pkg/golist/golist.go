@@ -0,0 +1,100 @@
+package golist
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// listFields are the go list fields needed to locate package sources
+const listFields = "ImportPath,Dir,GoFiles,CgoFiles,CFiles,CXXFiles,HFiles,SFiles,EmbedFiles,Module,Error"
+
+// pkg is the subset of go list -json output describing a package
+type pkg struct {
+	ImportPath string
+	Dir        string
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	HFiles     []string
+	SFiles     []string
+	EmbedFiles []string
+	Module     *struct {
+		Main  bool
+		GoMod string
+	}
+	Error *struct {
+		Err string
+	}
+}
+
+// Files returns the absolute paths of the source files of the Go package
+// pattern, resolved in dir, and the go.mod of its module. With withDeps it
+// adds every package of the main module the package imports, directly or
+// indirectly; standard library and third-party packages are left out. It
+// requires the go command.
+func Files(dir, pattern string, withDeps bool) ([]string, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, fmt.Errorf("--go-package requires the go command: %w", err)
+	}
+
+	args := []string{"list", "-json=" + listFields}
+	if withDeps {
+		args = append(args, "-deps")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("go", append(args, "--", pattern)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list %s: %v: %s", pattern, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var files []string
+	seen := map[string]bool{}
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	decoder := json.NewDecoder(&stdout)
+	for {
+		var p pkg
+		if err := decoder.Decode(&p); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("go list %s: %w", pattern, err)
+		}
+
+		if p.Error != nil {
+			return nil, fmt.Errorf("go list %s: %s", pattern, p.Error.Err)
+		}
+		if p.Module == nil || !p.Module.Main {
+			continue
+		}
+
+		if p.Module.GoMod != "" {
+			add(p.Module.GoMod)
+		}
+		for _, group := range [][]string{p.GoFiles, p.CgoFiles, p.CFiles, p.CXXFiles, p.HFiles, p.SFiles, p.EmbedFiles} {
+			for _, name := range group {
+				add(filepath.Join(p.Dir, name))
+			}
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no packages of the main module match %s", pattern)
+	}
+	return files, nil
+}
@@ -0,0 +1,192 @@
+// Package gosymbol locates Go declarations by name, so a digest can include
+// only the definitions of interest instead of whole files.
+package gosymbol
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// Find locates the named declarations in the Go files below dir and returns
+// the lines to keep of each file that has one, keyed by absolute path: the
+// declarations with their doc comments, and the package clause and imports
+// as context. Names are "Name", "pkg.Name", "Type.Method", or
+// "pkg.Type.Method", where pkg is the package name; they match functions,
+// methods, types, constants, and variables. Directories skipped by the go
+// command (vendor, testdata, and names starting with "." or "_") are not
+// searched. It also returns the names that matched nothing.
+func Find(dir string, names []string) (map[string][]config.LineRange, []string, error) {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	found := map[string]bool{}
+	ranges := map[string][]config.LineRange{}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			name := d.Name()
+			if path != dir && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments|parser.SkipObjectResolution)
+		if err != nil {
+			// Files that do not parse cannot be searched
+			return nil
+		}
+
+		matches := findInFile(fset, file, wanted, found)
+		if len(matches) == 0 {
+			return nil
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		ranges[abs] = merge(append(matches, context(fset, file)...))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	return ranges, missing, nil
+}
+
+// findInFile returns the lines of the declarations of file whose names are
+// wanted, recording the names found
+func findInFile(fset *token.FileSet, file *ast.File, wanted, found map[string]bool) []config.LineRange {
+	var matches []config.LineRange
+	pkg := file.Name.Name
+	match := func(keys []string, start, end token.Pos) {
+		for _, key := range keys {
+			if wanted[key] {
+				found[key] = true
+				matches = append(matches, lines(fset, start, end))
+			}
+		}
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			keys := []string{decl.Name.Name, pkg + "." + decl.Name.Name}
+			if recv := receiverType(decl); recv != "" {
+				keys = []string{recv + "." + decl.Name.Name, pkg + "." + recv + "." + decl.Name.Name}
+			}
+			match(keys, docStart(decl.Doc, decl.Pos()), decl.End())
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				var idents []*ast.Ident
+				var doc *ast.CommentGroup
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					idents, doc = []*ast.Ident{spec.Name}, spec.Doc
+				case *ast.ValueSpec:
+					idents, doc = spec.Names, spec.Doc
+				}
+
+				// A spec of a grouped declaration keeps the lines opening
+				// and closing the group
+				start, end := docStart(decl.Doc, decl.Pos()), decl.End()
+				if decl.Lparen.IsValid() {
+					start, end = docStart(doc, spec.Pos()), spec.End()
+				}
+				for _, ident := range idents {
+					before := len(matches)
+					match([]string{ident.Name, pkg + "." + ident.Name}, start, end)
+					if len(matches) > before && decl.Lparen.IsValid() {
+						matches = append(matches, lines(fset, docStart(decl.Doc, decl.Pos()), decl.Lparen), lines(fset, decl.Rparen, decl.Rparen))
+					}
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// context returns the lines of the package clause and the imports of file
+func context(fset *token.FileSet, file *ast.File) []config.LineRange {
+	ranges := []config.LineRange{lines(fset, file.Package, file.Name.End())}
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT {
+			ranges = append(ranges, lines(fset, gen.Pos(), gen.End()))
+		}
+	}
+	return ranges
+}
+
+// receiverType returns the name of the receiver type of a method, without
+// pointer and type parameters, or "" for a function
+func receiverType(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return ""
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
+
+// docStart returns where a declaration starts, including its doc comment
+func docStart(doc *ast.CommentGroup, pos token.Pos) token.Pos {
+	if doc != nil {
+		return doc.Pos()
+	}
+	return pos
+}
+
+// lines returns the range of lines from start to end
+func lines(fset *token.FileSet, start, end token.Pos) config.LineRange {
+	return config.LineRange{Start: fset.Position(start).Line, End: fset.Position(end).Line}
+}
+
+// merge sorts ranges and joins those that overlap or touch
+func merge(ranges []config.LineRange) []config.LineRange {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	merged := []config.LineRange{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End+1 {
+			last.End = max(last.End, r.End)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
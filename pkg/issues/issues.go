@@ -0,0 +1,253 @@
+// Package issues fetches issue threads from GitHub and GitLab, so the
+// requirements discussed in them can accompany the code.
+package issues
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Kinds of issue trackers
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Tracker is the issue tracker of a repository
+type Tracker struct {
+	Kind    string // GitHub or GitLab
+	API     string // Base URL of the REST API, such as https://api.github.com
+	Project string // Slash-separated path of the repository, such as org/repo
+}
+
+// Issue is an issue with its comments
+type Issue struct {
+	Number   int       // Number of the issue in its repository
+	Title    string    // Title
+	Body     string    // Description
+	Author   string    // Login of the author
+	State    string    // open or closed
+	Labels   []string  // Names of the labels
+	URL      string    // Web URL
+	Comments []Comment // Comments, oldest first
+}
+
+// Comment is a comment on an issue
+type Comment struct {
+	Author  string    // Login of the author
+	Body    string    // Text
+	Created time.Time // Time the comment was made
+}
+
+// user is the author of an API object
+type user struct {
+	Login    string `json:"login"`    // GitHub
+	Username string `json:"username"` // GitLab
+}
+
+// name returns the login of a GitHub or GitLab user
+func (u user) name() string {
+	if u.Login != "" {
+		return u.Login
+	}
+	return u.Username
+}
+
+// linkNext matches the URL of the next page in a Link header
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Client reads issues through the REST API of a tracker
+type Client struct {
+	tracker Tracker
+	token   string
+	http    *http.Client
+}
+
+// New returns a client for the tracker authenticating with token, or
+// anonymously if it is empty, which is enough for public repositories
+func New(tracker Tracker, token string) *Client {
+	return &Client{tracker: tracker, token: token, http: &http.Client{Timeout: time.Minute}}
+}
+
+// Fetch reads an issue and its comments. GitLab system notes, such as label
+// changes, are left out.
+func (c *Client) Fetch(number int) (*Issue, error) {
+	if c.tracker.Kind == GitLab {
+		return c.fetchGitLab(number)
+	}
+	return c.fetchGitHub(number)
+}
+
+// fetchGitHub reads an issue from the GitHub API. Pull requests are issues
+// there, so their numbers work too.
+func (c *Client) fetchGitHub(number int) (*Issue, error) {
+	issueURL := fmt.Sprintf("%s/repos/%s/issues/%d", strings.TrimSuffix(c.tracker.API, "/"), c.tracker.Project, number)
+
+	var issue struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		User    user   `json:"user"`
+		State   string `json:"state"`
+		HTMLURL string `json:"html_url"`
+		Labels  []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	if _, err := c.getJSON(issueURL, &issue); err != nil {
+		return nil, err
+	}
+	result := &Issue{
+		Number: number,
+		Title:  issue.Title,
+		Body:   text(issue.Body),
+		Author: issue.User.name(),
+		State:  issue.State,
+		URL:    issue.HTMLURL,
+	}
+	for _, label := range issue.Labels {
+		result.Labels = append(result.Labels, label.Name)
+	}
+
+	var comments []struct {
+		User      user      `json:"user"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := c.getPages(issueURL+"/comments", &comments); err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		result.Comments = append(result.Comments, Comment{Author: comment.User.name(), Body: text(comment.Body), Created: comment.CreatedAt})
+	}
+	return result, nil
+}
+
+// fetchGitLab reads an issue from the GitLab API, where projects are
+// addressed by their URL-encoded path
+func (c *Client) fetchGitLab(number int) (*Issue, error) {
+	issueURL := fmt.Sprintf("%s/projects/%s/issues/%d", strings.TrimSuffix(c.tracker.API, "/"), url.PathEscape(c.tracker.Project), number)
+
+	var issue struct {
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Author      user     `json:"author"`
+		State       string   `json:"state"`
+		WebURL      string   `json:"web_url"`
+		Labels      []string `json:"labels"`
+	}
+	if _, err := c.getJSON(issueURL, &issue); err != nil {
+		return nil, err
+	}
+	result := &Issue{
+		Number: number,
+		Title:  issue.Title,
+		Body:   text(issue.Description),
+		Author: issue.Author.name(),
+		State:  issue.State,
+		Labels: issue.Labels,
+		URL:    issue.WebURL,
+	}
+	if result.State == "opened" {
+		result.State = "open"
+	}
+
+	var notes []struct {
+		Author    user      `json:"author"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+		System    bool      `json:"system"`
+	}
+	if err := c.getPages(issueURL+"/notes?sort=asc&order_by=created_at", &notes); err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		if !note.System {
+			result.Comments = append(result.Comments, Comment{Author: note.Author.name(), Body: text(note.Body), Created: note.CreatedAt})
+		}
+	}
+	return result, nil
+}
+
+// text returns the Markdown of a body with Unix line endings and without
+// surrounding blank lines
+func text(body string) string {
+	return strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+}
+
+// getPages decodes every page of a list endpoint into the slice pointed to
+// by items, following the Link headers
+func (c *Client) getPages(endpoint string, items any) error {
+	separator := "?"
+	if strings.Contains(endpoint, "?") {
+		separator = "&"
+	}
+
+	var all []json.RawMessage
+	for next := endpoint + separator + "per_page=100"; next != ""; {
+		var page []json.RawMessage
+		header, err := c.getJSON(next, &page)
+		if err != nil {
+			return err
+		}
+		all = append(all, page...)
+
+		next = ""
+		if match := linkNext.FindStringSubmatch(header.Get("Link")); match != nil {
+			next = match[1]
+		}
+	}
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, items)
+}
+
+// getJSON decodes the JSON response to a GET request into value, returning
+// the response headers
+func (c *Client) getJSON(endpoint string, value any) (http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.tracker.Kind == GitHub {
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message any `json:"message"` // A list of errors on GitLab
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiError) == nil && apiError.Message != nil {
+			message = fmt.Sprint(apiError.Message)
+		}
+		return nil, fmt.Errorf("%s: %s: %s", endpoint, resp.Status, message)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", endpoint, err)
+	}
+	return resp.Header, nil
+}
@@ -0,0 +1,150 @@
+package jsimports
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/transform"
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// importPatterns match the module specifiers of static imports and
+// re-exports, side-effect imports, require calls, and dynamic imports
+var importPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?:^|[\s;])(?:import|export)\s[^'"` + "`" + `;]*?\sfrom\s*['"]([^'"]+)['"]`),
+	regexp.MustCompile(`(?:^|[\s;])import\s*['"]([^'"]+)['"]`),
+	regexp.MustCompile(`\b(?:require|import)\s*\(\s*['"]([^'"]+)['"]\s*\)`),
+}
+
+// resolveExtensions are tried, in order, for specifiers without a file
+// extension and for directory index files
+var resolveExtensions = []string{".ts", ".tsx", ".mts", ".cts", ".js", ".jsx", ".mjs", ".cjs", ".json"}
+
+// manifests are files of the source root included for context
+var manifests = []string{"package.json", "tsconfig.json"}
+
+// Files returns the absolute paths of the entrypoints below root and every
+// file reachable from them through relative imports, plus the package.json
+// and tsconfig.json of root. Bare specifiers (packages) and aliases are not
+// followed.
+func Files(root string, entries []string) ([]string, error) {
+	var files []string
+	seen := map[string]bool{}
+	var queue []string
+
+	for _, entry := range entries {
+		path, ok := resolve(filepath.Join(root, filepath.FromSlash(entry)))
+		if !ok {
+			return nil, fmt.Errorf("entrypoint '%s' not found", entry)
+		}
+		if !seen[path] {
+			seen[path] = true
+			queue = append(queue, path)
+		}
+	}
+
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		files = append(files, path)
+
+		for _, spec := range imports(path) {
+			if !IsRelative(spec) {
+				continue
+			}
+
+			target, ok := resolve(filepath.Join(filepath.Dir(path), filepath.FromSlash(spec)))
+			if ok && !seen[target] {
+				seen[target] = true
+				queue = append(queue, target)
+			}
+		}
+	}
+
+	for _, name := range manifests {
+		path := filepath.Join(root, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && !seen[path] {
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
+
+// IsRelative reports whether a module specifier refers to a file relative
+// to the importing one rather than to a package
+func IsRelative(spec string) bool {
+	return strings.HasPrefix(spec, "./") || strings.HasPrefix(spec, "../") || spec == "." || spec == ".."
+}
+
+// imports returns the module specifiers imported by a source file
+func imports(path string) []string {
+	lang := utils.DetectLanguage(path)
+	if !IsScript(lang) {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return Specifiers(lang, string(content))
+}
+
+// IsScript reports whether files of a language can import modules
+func IsScript(language string) bool {
+	switch language {
+	case "javascript", "typescript", "jsx", "tsx", "vue":
+		return true
+	}
+	return false
+}
+
+// Specifiers returns the module specifiers imported by the content of a
+// script in the given language, ignoring those in comments
+func Specifiers(language, content string) []string {
+	source := transform.StripComments(language, content, false)
+
+	var specs []string
+	for _, pattern := range importPatterns {
+		for _, match := range pattern.FindAllStringSubmatch(source, -1) {
+			specs = append(specs, strings.SplitN(match[1], "?", 2)[0])
+		}
+	}
+	return specs
+}
+
+// resolve finds the file a relative specifier refers to: the path itself,
+// the path with a script extension, a TypeScript source imported by its
+// compiled .js name, or a directory's index file
+func resolve(path string) (string, bool) {
+	for _, candidate := range Candidates(path) {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			abs, err := filepath.Abs(candidate)
+			if err != nil {
+				return candidate, true
+			}
+			return abs, true
+		}
+	}
+	return "", false
+}
+
+// Candidates returns the paths a relative specifier joined to the importing
+// file's directory may refer to, in the order they are tried
+func Candidates(path string) []string {
+	candidates := []string{path}
+	for _, ext := range resolveExtensions {
+		candidates = append(candidates, path+ext)
+	}
+	if ext := filepath.Ext(path); ext == ".js" || ext == ".jsx" || ext == ".mjs" || ext == ".cjs" {
+		base := strings.TrimSuffix(path, ext)
+		candidates = append(candidates, base+".ts", base+".tsx", base+".mts", base+".cts")
+	}
+	for _, ext := range resolveExtensions {
+		candidates = append(candidates, filepath.Join(path, "index"+ext))
+	}
+	return candidates
+}
@@ -0,0 +1,95 @@
+package license
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// spdxPattern matches an explicit SPDX license identifier line
+var spdxPattern = regexp.MustCompile(`SPDX-License-Identifier:\s*([\w.+\-() ]+)`)
+
+// titleLength is how much of the normalized text is searched for license
+// titles; licenses often mention others further down (GPL-3.0 refers to the
+// AGPL, MPL-2.0 lists the GPL family as secondary licenses)
+const titleLength = 300
+
+// signature identifies a license by phrases that must all appear in its
+// normalized text, or only in its title when title is set
+type signature struct {
+	id      string
+	title   bool
+	phrases []string
+}
+
+// signatures are checked in order, so licenses whose text extends another
+// (BSD-3-Clause extending BSD-2-Clause) come first
+var signatures = []signature{
+	{"AGPL-3.0", true, []string{"gnu affero general public license", "version 3"}},
+	{"LGPL-3.0", true, []string{"gnu lesser general public license", "version 3"}},
+	{"LGPL-2.1", true, []string{"gnu lesser general public license", "version 2.1"}},
+	{"GPL-3.0", true, []string{"gnu general public license", "version 3"}},
+	{"GPL-2.0", true, []string{"gnu general public license", "version 2"}},
+	{"Apache-2.0", true, []string{"apache license", "version 2.0"}},
+	{"MPL-2.0", true, []string{"mozilla public license", "2.0"}},
+	{"EPL-2.0", true, []string{"eclipse public license", "2.0"}},
+	{"BSL-1.0", true, []string{"boost software license", "version 1.0"}},
+	{"CC0-1.0", true, []string{"cc0 1.0 universal"}},
+	{"Unlicense", false, []string{"this is free and unencumbered software released into the public domain"}},
+	{"BSD-3-Clause", false, []string{"redistribution and use in source and binary forms", "neither the name"}},
+	{"BSD-2-Clause", false, []string{"redistribution and use in source and binary forms"}},
+	{"ISC", false, []string{"permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", false, []string{"permission is hereby granted, free of charge"}},
+}
+
+// IsLicenseFile reports whether a file name looks like a license or notice
+// file (LICENSE, LICENCE, COPYING, NOTICE, UNLICENSE, with any extension)
+func IsLicenseFile(name string) bool {
+	base := strings.ToUpper(strings.TrimSuffix(name, filepath.Ext(name)))
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING", "UNLICENSE"} {
+		if strings.HasPrefix(base, prefix) {
+			return true
+		}
+	}
+	return IsNoticeFile(name)
+}
+
+// IsNoticeFile reports whether a file name looks like a NOTICE file
+func IsNoticeFile(name string) bool {
+	return strings.HasPrefix(strings.ToUpper(name), "NOTICE")
+}
+
+// Identify returns the SPDX identifier of the license text, or an empty
+// string if it is not recognized
+func Identify(content string) string {
+	if match := spdxPattern.FindStringSubmatch(content); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+
+	// Normalize case and whitespace so line wrapping does not matter
+	text := strings.Join(strings.Fields(strings.ToLower(content)), " ")
+	title := text
+	if len(title) > titleLength {
+		title = title[:titleLength]
+	}
+
+	for _, sig := range signatures {
+		haystack := text
+		if sig.title {
+			haystack = title
+		}
+
+		matched := true
+		for _, phrase := range sig.phrases {
+			if !strings.Contains(haystack, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sig.id
+		}
+	}
+
+	return ""
+}
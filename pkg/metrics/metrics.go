@@ -0,0 +1,178 @@
+// Package metrics computes size and complexity figures of source files:
+// code, comment, and blank lines, and cyclomatic complexity.
+package metrics
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/transform"
+)
+
+// Lines counts the lines of a file by kind. A line holding both code and a
+// comment counts as code.
+type Lines struct {
+	Code    int
+	Comment int
+	Blank   int
+}
+
+// Complexity is the cyclomatic complexity of a file: the sum over its
+// functions of one plus their decision points. Languages without a parser
+// count the decision points of the whole file plus one, and have no Max.
+type Complexity struct {
+	Total   int    // Complexity of the file
+	Max     int    // Complexity of the most complex function
+	MaxFunc string // Name of the most complex function
+}
+
+// cFamilyDecisions matches the branch points of C-like languages
+var cFamilyDecisions = regexp.MustCompile(`\b(?:if|for|while|case|catch)\b|&&|\|\|`)
+
+// decisionPatterns match the branch points of languages measured by their
+// keywords and operators, after removing comments
+var decisionPatterns = map[string]*regexp.Regexp{
+	"javascript": cFamilyDecisions, "jsx": cFamilyDecisions,
+	"typescript": cFamilyDecisions, "tsx": cFamilyDecisions,
+	"java": cFamilyDecisions, "kotlin": cFamilyDecisions, "scala": cFamilyDecisions,
+	"c": cFamilyDecisions, "cpp": cFamilyDecisions, "csharp": cFamilyDecisions,
+	"swift": cFamilyDecisions, "dart": cFamilyDecisions, "php": cFamilyDecisions,
+	"rust":   regexp.MustCompile(`\b(?:if|for|while|loop)\b|=>|&&|\|\|`),
+	"python": regexp.MustCompile(`\b(?:if|elif|for|while|except|and|or|case)\b`),
+	"ruby":   regexp.MustCompile(`\b(?:if|elsif|unless|for|while|until|when|rescue)\b|&&|\|\|`),
+	"bash":   regexp.MustCompile(`\b(?:if|elif|for|while|until)\b|&&|\|\|`),
+}
+
+// CountLines counts the code, comment, and blank lines of content in the
+// given language. Languages whose comments are not recognized have no
+// comment lines.
+func CountLines(language, content string) Lines {
+	var lines Lines
+	nonBlank := 0
+	for _, line := range splitLines(content) {
+		if strings.TrimSpace(line) == "" {
+			lines.Blank++
+		} else {
+			nonBlank++
+		}
+	}
+
+	lines.Code = nonBlank
+	if transform.SupportsCommentStripping(language) {
+		// Stripping drops the lines that held only comments
+		lines.Code = 0
+		for _, line := range splitLines(transform.StripComments(language, content, false)) {
+			if strings.TrimSpace(line) != "" {
+				lines.Code++
+			}
+		}
+		lines.Code = min(lines.Code, nonBlank)
+	}
+	lines.Comment = nonBlank - lines.Code
+	return lines
+}
+
+// splitLines splits content into lines, without an empty line after a
+// final newline
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// SupportsComplexity reports whether the complexity of files in the
+// language can be computed
+func SupportsComplexity(language string) bool {
+	return language == "go" || decisionPatterns[language] != nil
+}
+
+// CyclomaticComplexity computes the complexity of content in the given
+// language. It reports false for unsupported languages and Go files that
+// do not parse.
+func CyclomaticComplexity(language, content string) (Complexity, bool) {
+	if language == "go" {
+		return goComplexity(content)
+	}
+	pattern := decisionPatterns[language]
+	if pattern == nil {
+		return Complexity{}, false
+	}
+	code := content
+	if transform.SupportsCommentStripping(language) {
+		code = transform.StripComments(language, content, false)
+	}
+	return Complexity{Total: 1 + len(pattern.FindAllStringIndex(code, -1))}, true
+}
+
+// goComplexity sums the complexity of the functions of a Go file. Function
+// literals count toward the function containing them.
+func goComplexity(content string) (Complexity, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.SkipObjectResolution)
+	if err != nil {
+		return Complexity{}, false
+	}
+
+	var result Complexity
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		complexity := 1
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+				complexity++
+			case *ast.CaseClause:
+				// The default clause is not a branch of its own
+				if n.List != nil {
+					complexity++
+				}
+			case *ast.CommClause:
+				if n.Comm != nil {
+					complexity++
+				}
+			case *ast.BinaryExpr:
+				if n.Op == token.LAND || n.Op == token.LOR {
+					complexity++
+				}
+			}
+			return true
+		})
+
+		result.Total += complexity
+		if complexity > result.Max {
+			result.Max = complexity
+			result.MaxFunc = funcName(fn)
+		}
+	}
+	return result, true
+}
+
+// funcName returns the name of a function, with the receiver type of a
+// method, such as "Config.Validate"
+func funcName(fn *ast.FuncDecl) string {
+	if fn.Recv == nil || len(fn.Recv.List) == 0 {
+		return fn.Name.Name
+	}
+	expr := fn.Recv.List[0].Type
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name + "." + fn.Name.Name
+		default:
+			return fn.Name.Name
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package migrations
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migration tools recognized by their file naming
+const (
+	GolangMigrate = "golang-migrate"
+	Flyway        = "flyway"
+	Django        = "django"
+	Rails         = "rails"
+)
+
+// File is a migration file recognized in a migration directory
+type File struct {
+	Name       string // Base name of the file
+	Version    string // Version as written in the name (empty for repeatable migrations)
+	Down       bool   // Whether the file reverts a migration (.down.sql, Flyway undo)
+	Repeatable bool   // Whether the file is a Flyway repeatable migration, applied after all versions
+}
+
+// namePatterns recognizes migration files of each tool; the first group is
+// the version
+var namePatterns = []struct {
+	kind    string
+	pattern *regexp.Regexp
+}{
+	{GolangMigrate, regexp.MustCompile(`^(\d+)_[^.]+\.(up|down)\.sql$`)},
+	{Flyway, regexp.MustCompile(`^([VU]\d+(?:[._]\d+)*|R)__.+\.sql$`)},
+	{Rails, regexp.MustCompile(`^(\d{14})_\w+\.rb$`)},
+	{Django, regexp.MustCompile(`^(\d{4})_\w+\.py$`)},
+}
+
+// minMigrations is the number of files that make a directory a migration
+// directory rather than a folder that happens to hold one numbered file
+const minMigrations = 2
+
+// Detect recognizes a migration directory by the names of its files and
+// returns the tool and its migration files, oldest first with repeatable
+// migrations last. Django migrations must be in a directory named
+// migrations. It returns an empty kind for any other directory.
+func Detect(dir string, names []string) (string, []File) {
+	for _, tool := range namePatterns {
+		if tool.kind == Django && dir != "migrations" {
+			continue
+		}
+
+		var files []File
+		for _, name := range names {
+			match := tool.pattern.FindStringSubmatch(name)
+			if match == nil {
+				continue
+			}
+
+			file := File{Name: name, Version: match[1]}
+			switch tool.kind {
+			case GolangMigrate:
+				file.Down = match[2] == "down"
+			case Flyway:
+				file.Down = strings.HasPrefix(file.Version, "U")
+				file.Repeatable = file.Version == "R"
+				file.Version = strings.TrimLeft(file.Version, "VUR")
+			}
+			files = append(files, file)
+		}
+
+		if len(files) >= minMigrations {
+			sort.SliceStable(files, func(i, j int) bool {
+				if files[i].Repeatable != files[j].Repeatable {
+					return files[j].Repeatable
+				}
+				if c := compareVersions(files[i].Version, files[j].Version); c != 0 {
+					return c < 0
+				}
+				return files[i].Name < files[j].Name
+			})
+			return tool.kind, files
+		}
+	}
+
+	return "", nil
+}
+
+// IsSQL reports whether the migrations of a tool are SQL scripts, from which
+// a schema can be inferred
+func IsSQL(kind string) bool {
+	return kind == GolangMigrate || kind == Flyway
+}
+
+// Latest returns the names of the files belonging to the n newest versions,
+// along with every repeatable migration
+func Latest(files []File, n int) map[string]bool {
+	var versions []string
+	for i := len(files) - 1; i >= 0; i-- {
+		file := files[i]
+		if file.Repeatable || len(versions) > 0 && versions[len(versions)-1] == file.Version {
+			continue
+		}
+		versions = append(versions, file.Version)
+	}
+	if len(versions) > n {
+		versions = versions[:n]
+	}
+
+	keep := map[string]bool{}
+	for _, file := range files {
+		keep[file.Name] = file.Repeatable
+		for _, version := range versions {
+			if compareVersions(file.Version, version) == 0 {
+				keep[file.Name] = true
+			}
+		}
+	}
+	return keep
+}
+
+// compareVersions compares dotted or underscored numeric versions part by
+// part, so that 1.10 follows 1.9 and 010 equals 10
+func compareVersions(a, b string) int {
+	split := func(version string) []string {
+		return strings.FieldsFunc(version, func(r rune) bool { return r == '.' || r == '_' })
+	}
+	as, bs := split(a), split(b)
+
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y uint64
+		if i < len(as) {
+			x, _ = strconv.ParseUint(as[i], 10, 64)
+		}
+		if i < len(bs) {
+			y, _ = strconv.ParseUint(bs[i], 10, 64)
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,478 @@
+package migrations
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Statements recognized when replaying migrations
+var (
+	createTablePattern = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL\s+|LOCAL\s+)?(TEMP|TEMPORARY)\s+|UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + namePattern + `)\s*\((.*)\)([^)]*)$`)
+	alterTablePattern  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(` + namePattern + `)\s+(.*)$`)
+	renameTablePattern = regexp.MustCompile(`(?is)^RENAME\s+TABLE\s+(` + namePattern + `)\s+TO\s+(` + namePattern + `)$`)
+	dropTablePattern   = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	createIndexPattern = regexp.MustCompile(`(?is)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(` + namePattern + `)\s+ON\s+(?:ONLY\s+)?(` + namePattern + `)`)
+	createPattern      = regexp.MustCompile(`(?is)^CREATE\s+(?:OR\s+REPLACE\s+)?(?:MATERIALIZED\s+|RECURSIVE\s+)?(VIEW|TYPE|SEQUENCE|FUNCTION|PROCEDURE|TRIGGER|EXTENSION|SCHEMA|DOMAIN)\s+(?:IF\s+NOT\s+EXISTS\s+)?(` + namePattern + `)`)
+	dropPattern        = regexp.MustCompile(`(?is)^DROP\s+(?:MATERIALIZED\s+)?(INDEX|VIEW|TYPE|SEQUENCE|FUNCTION|PROCEDURE|TRIGGER|EXTENSION|SCHEMA|DOMAIN)\s+(?:CONCURRENTLY\s+)?(?:IF\s+EXISTS\s+)?(` + namePattern + `)`)
+
+	// Actions of an ALTER TABLE statement
+	addPattern         = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(.*)$`)
+	dropColumnPattern  = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?(` + namePattern + `)(?:\s+(?:CASCADE|RESTRICT))?$`)
+	dropConstraint     = regexp.MustCompile(`(?is)^DROP\s+(?:CONSTRAINT|INDEX|KEY|FOREIGN\s+KEY)\s+(?:IF\s+EXISTS\s+)?(` + namePattern + `)`)
+	renameColumn       = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?(` + namePattern + `)\s+TO\s+(` + namePattern + `)$`)
+	renameTo           = regexp.MustCompile(`(?is)^RENAME\s+(?:TO|AS)\s+(` + namePattern + `)$`)
+	alterColumnType    = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(` + namePattern + `)\s+(?:SET\s+DATA\s+)?TYPE\s+(.*?)(?:\s+USING\s+.*)?$`)
+	alterColumnNotNull = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(` + namePattern + `)\s+(SET|DROP)\s+NOT\s+NULL$`)
+	alterColumnDefault = regexp.MustCompile(`(?is)^ALTER\s+(?:COLUMN\s+)?(` + namePattern + `)\s+(?:SET\s+DEFAULT\s+(.*)|DROP\s+DEFAULT)$`)
+	modifyColumn       = regexp.MustCompile(`(?is)^MODIFY\s+(?:COLUMN\s+)?(.*)$`)
+	changeColumn       = regexp.MustCompile(`(?is)^CHANGE\s+(?:COLUMN\s+)?(` + namePattern + `)\s+(.*)$`)
+
+	constraintPattern = regexp.MustCompile(`(?i)^(CONSTRAINT|PRIMARY\s+KEY|FOREIGN\s+KEY|UNIQUE|CHECK|EXCLUDE|INDEX|KEY|FULLTEXT|SPATIAL)\b`)
+	notNullPattern    = regexp.MustCompile(`(?i)\s+NOT\s+NULL\b`)
+	defaultPattern    = regexp.MustCompile(`(?i)\s+DEFAULT\s+('[^']*'|\([^)]*\)|\S+)`)
+	columnTailPattern = regexp.MustCompile(`(?i)\s+(NOT\s+NULL|NULL|DEFAULT|PRIMARY|REFERENCES|UNIQUE|CHECK|GENERATED|COLLATE|CONSTRAINT|AUTO_INCREMENT)\b`)
+	spacePattern      = regexp.MustCompile(`\s+`)
+)
+
+// namePattern matches a possibly qualified and quoted identifier
+const namePattern = "(?:[\\w$]+|\"[^\"]+\"|`[^`]+`|\\[[^\\]]+\\])(?:\\.(?:[\\w$]+|\"[^\"]+\"|`[^`]+`|\\[[^\\]]+\\]))*"
+
+// schema is the database schema built up by replaying migrations
+type schema struct {
+	tables  []*table          // Tables in creation order
+	objects []*object         // Indexes, views, types, and other objects in creation order
+	byName  map[string]*table // Tables by normalized name
+}
+
+// table is a table of the inferred schema
+type table struct {
+	name        string
+	columns     []*column
+	constraints []string
+	options     string   // Text after the column list, such as ENGINE=InnoDB
+	alters      []string // ALTER TABLE actions that could not be folded into the definition
+}
+
+// column is a column definition: its name followed by its type and constraints
+type column struct {
+	name string
+	def  string
+}
+
+// object is a schema object other than a table, kept as its latest statement
+type object struct {
+	kind, name string
+	table      string // Normalized name of the indexed table (indexes only)
+	statement  string
+}
+
+// InferSchema replays the SQL of migrations, oldest first, and returns the
+// resulting schema as CREATE statements. Tables are rebuilt from CREATE
+// TABLE, ALTER TABLE, and DROP TABLE statements; indexes, views, types,
+// functions, and similar objects keep their latest definition. Data changes
+// and statements that are not understood are left out.
+func InferSchema(scripts []string) string {
+	s := &schema{byName: map[string]*table{}}
+	for _, script := range scripts {
+		for _, statement := range SplitStatements(script) {
+			s.apply(statement)
+		}
+	}
+	return s.String()
+}
+
+// apply folds one statement into the schema
+func (s *schema) apply(statement string) {
+	if match := createTablePattern.FindStringSubmatch(statement); match != nil {
+		if match[1] == "" {
+			s.createTable(match[2], match[3], strings.TrimSpace(match[4]))
+		}
+		return
+	}
+	if match := renameTablePattern.FindStringSubmatch(statement); match != nil {
+		s.renameTable(match[1], match[2])
+		return
+	}
+	if match := alterTablePattern.FindStringSubmatch(statement); match != nil {
+		if t := s.byName[normalizeName(match[1])]; t != nil {
+			for _, action := range splitTopLevel(match[2]) {
+				s.alterTable(t, action)
+			}
+		}
+		return
+	}
+	if match := dropTablePattern.FindStringSubmatch(statement); match != nil {
+		for _, name := range splitTopLevel(match[1]) {
+			s.dropTable(name)
+		}
+		return
+	}
+	if match := createIndexPattern.FindStringSubmatch(statement); match != nil {
+		s.setObject(&object{kind: "INDEX", name: normalizeName(match[1]), table: normalizeName(match[2]), statement: statement})
+		return
+	}
+	if match := createPattern.FindStringSubmatch(statement); match != nil {
+		s.setObject(&object{kind: strings.ToUpper(match[1]), name: normalizeName(match[2]), statement: statement})
+		return
+	}
+	if match := dropPattern.FindStringSubmatch(statement); match != nil {
+		s.dropObject(strings.ToUpper(match[1]), normalizeName(match[2]))
+	}
+}
+
+// createTable adds a table, replacing any earlier table of the same name
+func (s *schema) createTable(name, body, options string) {
+	s.dropTable(name)
+
+	t := &table{name: name, options: options}
+	for _, item := range splitTopLevel(body) {
+		t.add(item)
+	}
+	s.tables = append(s.tables, t)
+	s.byName[normalizeName(name)] = t
+}
+
+// renameTable renames a table and moves its indexes along
+func (s *schema) renameTable(from, to string) {
+	t := s.byName[normalizeName(from)]
+	if t == nil {
+		return
+	}
+	delete(s.byName, normalizeName(from))
+	t.name = to
+	s.byName[normalizeName(to)] = t
+
+	for _, o := range s.objects {
+		if o.table == normalizeName(from) {
+			o.table = normalizeName(to)
+		}
+	}
+}
+
+// dropTable removes a table and its indexes
+func (s *schema) dropTable(name string) {
+	key := normalizeName(name)
+	t := s.byName[key]
+	if t == nil {
+		return
+	}
+	delete(s.byName, key)
+
+	tables := s.tables[:0]
+	for _, other := range s.tables {
+		if other != t {
+			tables = append(tables, other)
+		}
+	}
+	s.tables = tables
+
+	objects := s.objects[:0]
+	for _, o := range s.objects {
+		if o.table != key {
+			objects = append(objects, o)
+		}
+	}
+	s.objects = objects
+}
+
+// setObject adds an object or replaces its earlier definition in place
+func (s *schema) setObject(o *object) {
+	for i, existing := range s.objects {
+		if existing.kind == o.kind && existing.name == o.name {
+			s.objects[i] = o
+			return
+		}
+	}
+	s.objects = append(s.objects, o)
+}
+
+// dropObject removes an object
+func (s *schema) dropObject(kind, name string) {
+	for i, o := range s.objects {
+		if o.kind == kind && o.name == name {
+			s.objects = append(s.objects[:i], s.objects[i+1:]...)
+			return
+		}
+	}
+}
+
+// alterTable folds one ALTER TABLE action into a table
+func (s *schema) alterTable(t *table, action string) {
+	switch {
+	case dropConstraint.MatchString(action):
+		name := normalizeName(dropConstraint.FindStringSubmatch(action)[1])
+		t.dropConstraint(name)
+
+	case addPattern.MatchString(action):
+		t.add(addPattern.FindStringSubmatch(action)[1])
+
+	case dropColumnPattern.MatchString(action):
+		t.dropColumn(dropColumnPattern.FindStringSubmatch(action)[1])
+
+	case renameTo.MatchString(action):
+		s.renameTable(t.name, renameTo.FindStringSubmatch(action)[1])
+
+	case renameColumn.MatchString(action):
+		match := renameColumn.FindStringSubmatch(action)
+		if c := t.column(match[1]); c != nil {
+			c.def = match[2] + strings.TrimPrefix(c.def, c.name)
+			c.name = match[2]
+		}
+
+	case alterColumnType.MatchString(action):
+		match := alterColumnType.FindStringSubmatch(action)
+		if c := t.column(match[1]); c != nil {
+			tail := ""
+			if loc := columnTailPattern.FindStringIndex(c.def); loc != nil {
+				tail = c.def[loc[0]:]
+			}
+			c.def = c.name + " " + match[2] + tail
+		}
+
+	case alterColumnNotNull.MatchString(action):
+		match := alterColumnNotNull.FindStringSubmatch(action)
+		if c := t.column(match[1]); c != nil {
+			c.def = notNullPattern.ReplaceAllString(c.def, "")
+			if strings.EqualFold(match[2], "SET") {
+				c.def += " NOT NULL"
+			}
+		}
+
+	case alterColumnDefault.MatchString(action):
+		match := alterColumnDefault.FindStringSubmatch(action)
+		if c := t.column(match[1]); c != nil {
+			c.def = defaultPattern.ReplaceAllString(c.def, "")
+			if match[2] != "" {
+				c.def += " DEFAULT " + match[2]
+			}
+		}
+
+	case modifyColumn.MatchString(action):
+		t.add(modifyColumn.FindStringSubmatch(action)[1])
+
+	case changeColumn.MatchString(action):
+		match := changeColumn.FindStringSubmatch(action)
+		if c := t.column(match[1]); c != nil {
+			c.name, c.def = columnName(match[2]), match[2]
+		}
+
+	default:
+		t.alters = append(t.alters, action)
+	}
+}
+
+// add adds a column or table constraint, replacing a column of the same name
+func (t *table) add(item string) {
+	if constraintPattern.MatchString(item) {
+		t.constraints = append(t.constraints, item)
+		return
+	}
+
+	name := columnName(item)
+	if c := t.column(name); c != nil {
+		c.name, c.def = name, item
+		return
+	}
+	t.columns = append(t.columns, &column{name: name, def: item})
+}
+
+// column returns the column with the given name, or nil
+func (t *table) column(name string) *column {
+	for _, c := range t.columns {
+		if normalizeName(c.name) == normalizeName(name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// dropColumn removes a column
+func (t *table) dropColumn(name string) {
+	for i, c := range t.columns {
+		if normalizeName(c.name) == normalizeName(name) {
+			t.columns = append(t.columns[:i], t.columns[i+1:]...)
+			return
+		}
+	}
+}
+
+// dropConstraint removes a named table constraint
+func (t *table) dropConstraint(name string) {
+	for i, constraint := range t.constraints {
+		fields := strings.Fields(constraint)
+		for j := 0; j+1 < len(fields); j++ {
+			if strings.EqualFold(fields[j], "CONSTRAINT") || strings.EqualFold(fields[j], "KEY") || strings.EqualFold(fields[j], "INDEX") {
+				if normalizeName(fields[j+1]) == name {
+					t.constraints = append(t.constraints[:i], t.constraints[i+1:]...)
+					return
+				}
+			}
+		}
+	}
+}
+
+// String renders the schema as SQL
+func (s *schema) String() string {
+	var builder strings.Builder
+
+	for _, t := range s.tables {
+		items := make([]string, 0, len(t.columns)+len(t.constraints))
+		for _, c := range t.columns {
+			items = append(items, c.def)
+		}
+		items = append(items, t.constraints...)
+
+		builder.WriteString("CREATE TABLE " + t.name + " (\n    " + strings.Join(items, ",\n    ") + "\n)")
+		if t.options != "" {
+			builder.WriteString(" " + t.options)
+		}
+		builder.WriteString(";\n")
+		for _, action := range t.alters {
+			builder.WriteString("ALTER TABLE " + t.name + " " + action + ";\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	for _, o := range s.objects {
+		builder.WriteString(o.statement + ";\n")
+	}
+
+	return strings.TrimRight(builder.String(), "\n") + "\n"
+}
+
+// SplitStatements splits a SQL script into statements with comments removed
+// and whitespace collapsed, honoring string literals, quoted identifiers,
+// and PostgreSQL dollar-quoted bodies
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+
+	flush := func() {
+		if statement := strings.TrimSpace(spacePattern.ReplaceAllString(current.String(), " ")); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(script); {
+		rest := script[i:]
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			current.WriteByte(' ')
+			i += end
+
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4
+			}
+			current.WriteByte(' ')
+			i += end
+
+		case rest[0] == '\'' || rest[0] == '"' || rest[0] == '`':
+			end := quotedEnd(rest, rest[0])
+			current.WriteString(rest[:end])
+			i += end
+
+		case rest[0] == '$':
+			if tag := dollarTag(rest); tag != "" {
+				end := strings.Index(rest[len(tag):], tag)
+				if end < 0 {
+					end = len(rest)
+				} else {
+					end += 2 * len(tag)
+				}
+				current.WriteString(rest[:end])
+				i += end
+				continue
+			}
+			current.WriteByte('$')
+			i++
+
+		case rest[0] == ';':
+			flush()
+			i++
+
+		default:
+			current.WriteByte(rest[0])
+			i++
+		}
+	}
+	flush()
+
+	return statements
+}
+
+// dollarTagPattern matches the opening of a dollar-quoted string
+var dollarTagPattern = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+// dollarTag returns the dollar-quote tag at the start of s, such as $$ or
+// $body$, or an empty string
+func dollarTag(s string) string {
+	return dollarTagPattern.FindString(s)
+}
+
+// quotedEnd returns the length of the quoted string or identifier at the
+// start of s, where a doubled quote is an escaped quote
+func quotedEnd(s string, quote byte) int {
+	for i := 1; i < len(s); i++ {
+		if s[i] == '\\' && quote == '\'' {
+			i++
+			continue
+		}
+		if s[i] == quote {
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// splitTopLevel splits a list on commas outside parentheses and quotes
+func splitTopLevel(list string) []string {
+	var items []string
+	depth, start := 0, 0
+
+	for i := 0; i < len(list); i++ {
+		switch c := list[i]; c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case '\'', '"', '`':
+			i += quotedEnd(list[i:], c) - 1
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(list[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if item := strings.TrimSpace(list[start:]); item != "" {
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// columnName returns the name at the start of a column definition
+func columnName(def string) string {
+	return namePatternAtStart.FindString(def)
+}
+
+// namePatternAtStart matches an identifier at the start of a definition
+var namePatternAtStart = regexp.MustCompile(`^(?:` + namePattern + `)`)
+
+// normalizeName strips quotes and lowercases an identifier for comparison
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Trim(name, "\"`[]"))
+}
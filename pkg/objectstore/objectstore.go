@@ -0,0 +1,198 @@
+package objectstore
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// object is a single object in a bucket
+type object struct {
+	Key  string // Full object key
+	Size int64  // Size in bytes
+}
+
+// provider lists and downloads objects through a cloud CLI
+type provider struct {
+	scheme   string
+	cli      string
+	product  string // Name of the CLI package to install
+	list     func(bucket, prefix string) ([]object, error)
+	download func(bucket, key, dest string) error
+}
+
+// providers maps URL schemes to their providers
+var providers = map[string]*provider{
+	"s3": {scheme: "s3", cli: "aws", product: "AWS CLI", list: listS3, download: downloadS3},
+	"gs": {scheme: "gs", cli: "gcloud", product: "Google Cloud CLI", list: listGCS, download: downloadGCS},
+}
+
+// IsURL reports whether source is an s3:// or gs:// URL
+func IsURL(source string) bool {
+	scheme, _, ok := strings.Cut(source, "://")
+	return ok && providers[scheme] != nil
+}
+
+// Fetch downloads the objects under an s3:// or gs:// URL that pass the
+// include/exclude patterns and size limits of cfg into a new temporary
+// directory. It returns the local directory to analyze and a cleanup
+// function removing it.
+func Fetch(source string, cfg *config.Config) (string, func(), error) {
+	scheme, rest, _ := strings.Cut(source, "://")
+	p := providers[scheme]
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return "", nil, fmt.Errorf("invalid %s URL: %s", scheme, source)
+	}
+
+	if _, err := exec.LookPath(p.cli); err != nil {
+		return "", nil, fmt.Errorf("%s:// sources are read with the %s command, which was not found in PATH; install the %s", scheme, p.cli, p.product)
+	}
+
+	objects, err := p.list(bucket, prefix)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Name the local root after the prefix so the digest reads naturally
+	name := path.Base(strings.TrimSuffix(prefix, "/"))
+	if prefix == "" || name == "." || name == "/" {
+		name = bucket
+	}
+
+	tmp, err := os.MkdirTemp("", "ingest-"+scheme+"-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	root := filepath.Join(tmp, name)
+
+	// Keys are made relative to the last "/" of the prefix, so both
+	// "dir/" and "dir/partial-name" prefixes keep sensible paths
+	base := prefix[:strings.LastIndex(prefix, "/")+1]
+	if strings.HasSuffix(prefix, "/") {
+		base = prefix
+	}
+
+	files, total := 0, int64(0)
+	for _, obj := range objects {
+		rel := strings.TrimPrefix(obj.Key, base)
+		if rel == "" || strings.HasSuffix(rel, "/") {
+			continue // Skip directory placeholder objects
+		}
+		if path.IsAbs(rel) {
+			slog.Warn("Skipping object with an absolute key", "key", obj.Key)
+			continue
+		}
+
+		// Keys come from the bucket, so they must not escape the directory
+		dest := filepath.Join(root, filepath.FromSlash(rel))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			cleanup()
+			return "", nil, fmt.Errorf("invalid object key in %s: %s", source, obj.Key)
+		}
+		if !cfg.ShouldIncludeTree(root, dest) {
+			continue
+		}
+
+		// Apply the same limits as local traversal before downloading
+		if obj.Size > cfg.MaxFileSize || files >= cfg.MaxFiles || total+obj.Size > cfg.MaxTotalSize {
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+		if err := p.download(bucket, obj.Key, dest); err != nil {
+			cleanup()
+			return "", nil, err
+		}
+
+		files++
+		total += obj.Size
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return root, cleanup, nil
+}
+
+// listS3 lists objects with the aws CLI, which paginates automatically
+func listS3(bucket, prefix string) ([]object, error) {
+	out, err := run("aws", "s3api", "list-objects-v2", "--bucket", bucket, "--prefix", prefix, "--output", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Contents []object `json:"Contents"`
+	}
+	if len(bytes.TrimSpace(out)) > 0 {
+		if err := json.Unmarshal(out, &result); err != nil {
+			return nil, fmt.Errorf("parsing aws output: %w", err)
+		}
+	}
+	return result.Contents, nil
+}
+
+// downloadS3 copies a single object with the aws CLI
+func downloadS3(bucket, key, dest string) error {
+	_, err := run("aws", "s3", "cp", "--only-show-errors", "s3://"+bucket+"/"+key, dest)
+	return err
+}
+
+// listGCS lists objects with the gcloud CLI
+func listGCS(bucket, prefix string) ([]object, error) {
+	out, err := run("gcloud", "storage", "objects", "list", "gs://"+bucket+"/"+prefix+"**", "--format=json(name,size)")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []struct {
+		Name string `json:"name"`
+		Size string `json:"size"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return nil, fmt.Errorf("parsing gcloud output: %w", err)
+	}
+
+	objects := make([]object, 0, len(result))
+	for _, item := range result {
+		size, _ := strconv.ParseInt(item.Size, 10, 64)
+		objects = append(objects, object{Key: item.Name, Size: size})
+	}
+	return objects, nil
+}
+
+// downloadGCS copies a single object with the gcloud CLI
+func downloadGCS(bucket, key, dest string) error {
+	_, err := run("gcloud", "storage", "cp", "--no-user-output-enabled", "gs://"+bucket+"/"+key, dest)
+	return err
+}
+
+// run executes a CLI command and returns its standard output
+func run(name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	slog.Debug("Running "+name, "args", strings.Join(args, " "))
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
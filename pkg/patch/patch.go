@@ -0,0 +1,115 @@
+// Package patch reads unified diffs, such as those of git diff or a pull
+// request, to find the files they touch.
+package patch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// File is a file touched by a diff
+type File struct {
+	Path      string // Slash-separated path after the change, or before it if deleted
+	Added     int    // Lines added
+	Removed   int    // Lines removed
+	IsNew     bool   // Whether the diff creates the file
+	IsDeleted bool   // Whether the diff deletes the file
+}
+
+// hunkHeader matches the header of a hunk with its old and new line counts
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,(\d+))? \+\d+(?:,(\d+))? @@`)
+
+// Parse returns the files touched by a unified diff in the order they
+// appear. It understands git's extended headers, including renames, and
+// plain diffs made of "---" and "+++" lines. The "a/" and "b/" prefixes of
+// git paths are removed.
+func Parse(diff string) []*File {
+	var files []*File
+	var current *File
+	oldLeft, newLeft := 0, 0 // Lines of the current hunk still to read
+
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+
+		// Hunk lines are counted, so removed lines starting with "--" are
+		// not taken for headers
+		if oldLeft > 0 || newLeft > 0 {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				current.Added++
+				newLeft--
+			case strings.HasPrefix(line, "-"):
+				current.Removed++
+				oldLeft--
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				oldLeft--
+				newLeft--
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			// The path is refined by the rename or ---/+++ lines that follow
+			_, path, _ := strings.Cut(line, " b/")
+			current = &File{Path: path}
+			files = append(files, current)
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			oldPath := diffPath(strings.TrimPrefix(line, "--- "), "a/")
+			newPath := diffPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+			i++
+			if current == nil || current.Added+current.Removed > 0 {
+				// A plain diff has no other header
+				current = &File{}
+				files = append(files, current)
+			}
+			current.Path = newPath
+			if newPath == "" {
+				current.Path = oldPath
+			}
+			current.IsNew = current.IsNew || oldPath == ""
+			current.IsDeleted = current.IsDeleted || newPath == ""
+
+		case strings.HasPrefix(line, "@@") && current != nil:
+			if m := hunkHeader.FindStringSubmatch(line); m != nil {
+				oldLeft, newLeft = hunkCount(m[1]), hunkCount(m[2])
+			}
+
+		case current != nil && strings.HasPrefix(line, "rename to "):
+			current.Path = strings.TrimPrefix(line, "rename to ")
+		case current != nil && strings.HasPrefix(line, "new file mode"):
+			current.IsNew = true
+		case current != nil && strings.HasPrefix(line, "deleted file mode"):
+			current.IsDeleted = true
+		}
+	}
+	return files
+}
+
+// hunkCount returns a line count of a hunk header, which is 1 if omitted
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// diffPath returns the path of a ---/+++ line without the git prefix and a
+// trailing timestamp, or an empty path for /dev/null
+func diffPath(value, prefix string) string {
+	path, _, _ := strings.Cut(value, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	if unquoted, ok := strings.CutPrefix(path, `"`); ok {
+		path = strings.TrimSuffix(unquoted, `"`)
+	}
+	return strings.TrimPrefix(path, prefix)
+}
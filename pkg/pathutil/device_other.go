@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package pathutil
+
+import "io/fs"
+
+// Device reports that mount points cannot be detected on this platform
+func Device(path string, info fs.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package pathutil
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Device returns the ID of the device holding the file described by info,
+// which changes at mount points
+func Device(path string, info fs.FileInfo) (uint64, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(stat.Dev), true
+}
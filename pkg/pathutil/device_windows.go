@@ -0,0 +1,31 @@
+//go:build windows
+
+package pathutil
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Device returns the serial number of the volume holding path, which
+// changes at volumes mounted in a directory
+func Device(path string, info fs.FileInfo) (uint64, bool) {
+	name, err := syscall.UTF16PtrFromString(Long(path))
+	if err != nil {
+		return 0, false
+	}
+
+	// Directories can only be opened with backup semantics
+	handle, err := syscall.CreateFile(name, 0, syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, false
+	}
+	defer syscall.CloseHandle(handle)
+
+	var data syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &data); err != nil {
+		return 0, false
+	}
+	return uint64(data.VolumeSerialNumber), true
+}
@@ -0,0 +1,26 @@
+package pathutil
+
+import "strings"
+
+// reservedNames are the device names Windows reserves in every directory,
+// with or without an extension
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// IsReservedName reports whether name is a reserved device name such as
+// CON or NUL.txt on this platform. Opening one reads from the device rather
+// than a file, so traversal must skip it. Always false outside Windows.
+func IsReservedName(name string) bool {
+	return reservedNamesApply && isReservedName(name)
+}
+
+// isReservedName reports whether name is a Windows reserved device name
+func isReservedName(name string) bool {
+	base, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(base, " "))]
+}
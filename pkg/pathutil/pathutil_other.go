@@ -0,0 +1,10 @@
+//go:build !windows
+
+package pathutil
+
+const reservedNamesApply = false
+
+// Long returns path unchanged; only Windows limits path lengths
+func Long(path string) string {
+	return path
+}
@@ -0,0 +1,32 @@
+//go:build windows
+
+package pathutil
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+const reservedNamesApply = true
+
+// maxPath is the length from which paths need the extended-length prefix;
+// directories are limited to MAX_PATH minus room for an 8.3 file name
+const maxPath = 248
+
+// Long returns path in extended-length form (\\?\C:\... or \\?\UNC\...)
+// when it is too long for the legacy Win32 APIs, so deep checkouts can be
+// traversed. Other paths are returned unchanged.
+func Long(path string) string {
+	if len(path) < maxPath || strings.HasPrefix(path, `\\?\`) {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
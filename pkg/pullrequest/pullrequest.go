@@ -0,0 +1,250 @@
+// Package pullrequest fetches GitHub pull requests for review: their
+// description, diff, and comments, through the REST API.
+package pullrequest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Ref identifies a pull request
+type Ref struct {
+	API    string // Base URL of the REST API, such as https://api.github.com
+	Owner  string // Owner of the repository
+	Repo   string // Name of the repository
+	Number int    // Number of the pull request
+}
+
+// PullRequest is a pull request with the discussion around it
+type PullRequest struct {
+	Number   int       // Number of the pull request
+	Title    string    // Title
+	Body     string    // Description
+	Author   string    // Login of the author
+	State    string    // open, closed, or merged
+	URL      string    // Web URL
+	Base     string    // Branch the changes are merged into
+	Head     string    // Branch holding the changes
+	HeadSHA  string    // Commit at the head of the changes
+	Diff     string    // Unified diff of the changes
+	Comments []Comment // Conversation comments, reviews, and review comments, oldest first
+}
+
+// Comment is a conversation comment, a review, or a review comment on a line
+type Comment struct {
+	Author  string    // Login of the author
+	Body    string    // Text
+	Created time.Time // Time the comment was made or the review submitted
+	Review  string    // State of a review, such as approved, empty for comments
+	Path    string    // File of a review comment, empty otherwise
+	Line    int       // Line of a review comment in the changed file, 0 if outdated
+}
+
+// user is the author of an API object
+type user struct {
+	Login string `json:"login"`
+}
+
+// linkNext matches the URL of the next page in a Link header
+var linkNext = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// Client reads pull requests through the REST API
+type Client struct {
+	token string
+	http  *http.Client
+}
+
+// New returns a client authenticating with token, or anonymously if it is
+// empty, which is enough for public repositories within the API rate limit
+func New(token string) *Client {
+	return &Client{token: token, http: &http.Client{Timeout: time.Minute}}
+}
+
+// Fetch reads the pull request, its diff, and every conversation comment,
+// review, and review comment. Reviews without text are kept when they
+// approve or request changes.
+func (c *Client) Fetch(ref Ref) (*PullRequest, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s", strings.TrimSuffix(ref.API, "/"), ref.Owner, ref.Repo)
+	pullURL := fmt.Sprintf("%s/pulls/%d", base, ref.Number)
+
+	var pull struct {
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		User    user   `json:"user"`
+		State   string `json:"state"`
+		Merged  bool   `json:"merged"`
+		HTMLURL string `json:"html_url"`
+		Base    struct {
+			Ref string `json:"ref"`
+		} `json:"base"`
+		Head struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	}
+	if _, err := c.getJSON(pullURL, &pull); err != nil {
+		return nil, err
+	}
+	pr := &PullRequest{
+		Number:  ref.Number,
+		Title:   pull.Title,
+		Body:    text(pull.Body),
+		Author:  pull.User.Login,
+		State:   pull.State,
+		URL:     pull.HTMLURL,
+		Base:    pull.Base.Ref,
+		Head:    pull.Head.Ref,
+		HeadSHA: pull.Head.SHA,
+	}
+	if pull.Merged {
+		pr.State = "merged"
+	}
+
+	diff, _, err := c.get(pullURL, "application/vnd.github.diff")
+	if err != nil {
+		return nil, err
+	}
+	pr.Diff = string(diff)
+
+	var comments []struct {
+		User      user      `json:"user"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	if err := c.getPages(fmt.Sprintf("%s/issues/%d/comments", base, ref.Number), &comments); err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		pr.Comments = append(pr.Comments, Comment{Author: comment.User.Login, Body: text(comment.Body), Created: comment.CreatedAt})
+	}
+
+	var reviews []struct {
+		User        user      `json:"user"`
+		Body        string    `json:"body"`
+		State       string    `json:"state"`
+		SubmittedAt time.Time `json:"submitted_at"`
+	}
+	if err := c.getPages(pullURL+"/reviews", &reviews); err != nil {
+		return nil, err
+	}
+	for _, review := range reviews {
+		state := strings.ToLower(strings.ReplaceAll(review.State, "_", " "))
+		body := text(review.Body)
+		// Line comments of a review are listed on their own
+		if state == "pending" || (body == "" && state == "commented") {
+			continue
+		}
+		pr.Comments = append(pr.Comments, Comment{Author: review.User.Login, Body: body, Created: review.SubmittedAt, Review: state})
+	}
+
+	var lineComments []struct {
+		User      user      `json:"user"`
+		Body      string    `json:"body"`
+		CreatedAt time.Time `json:"created_at"`
+		Path      string    `json:"path"`
+		Line      int       `json:"line"`
+	}
+	if err := c.getPages(pullURL+"/comments", &lineComments); err != nil {
+		return nil, err
+	}
+	for _, comment := range lineComments {
+		pr.Comments = append(pr.Comments, Comment{
+			Author:  comment.User.Login,
+			Body:    text(comment.Body),
+			Created: comment.CreatedAt,
+			Path:    comment.Path,
+			Line:    comment.Line,
+		})
+	}
+
+	sort.SliceStable(pr.Comments, func(i, j int) bool {
+		return pr.Comments[i].Created.Before(pr.Comments[j].Created)
+	})
+	return pr, nil
+}
+
+// text returns the Markdown of a body with Unix line endings and without
+// surrounding blank lines
+func text(body string) string {
+	return strings.TrimSpace(strings.ReplaceAll(body, "\r\n", "\n"))
+}
+
+// getPages decodes every page of a list endpoint into the slice pointed to
+// by items, following the Link headers
+func (c *Client) getPages(url string, items any) error {
+	var all []json.RawMessage
+	for url += "?per_page=100"; url != ""; {
+		var page []json.RawMessage
+		header, err := c.getJSON(url, &page)
+		if err != nil {
+			return err
+		}
+		all = append(all, page...)
+
+		url = ""
+		if match := linkNext.FindStringSubmatch(header.Get("Link")); match != nil {
+			url = match[1]
+		}
+	}
+
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, items)
+}
+
+// getJSON decodes the JSON response to a GET request into value, returning
+// the response headers
+func (c *Client) getJSON(url string, value any) (http.Header, error) {
+	data, header, err := c.get(url, "application/vnd.github+json")
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", url, err)
+	}
+	return header, nil
+}
+
+// get sends a GET request accepting the given media type and returns the
+// response body and headers
+func (c *Client) get(url, accept string) ([]byte, http.Header, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &apiError) == nil && apiError.Message != "" {
+			message = apiError.Message
+		}
+		return nil, nil, fmt.Errorf("%s: %s: %s", url, resp.Status, message)
+	}
+	return data, resp.Header, nil
+}
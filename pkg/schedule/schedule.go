@@ -0,0 +1,221 @@
+// Package schedule parses cron-like schedules and computes when they next
+// fire.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinInterval is the shortest interval accepted by @every
+const MinInterval = time.Minute
+
+// searchLimit bounds the search for the next matching minute; every valid
+// five-field schedule fires within it, leap days included
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// descriptors maps the @ shorthands to the five-field schedules they stand for
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// monthNames and dayNames are the names accepted in the month and
+// day-of-week fields
+var (
+	monthNames = map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}
+	dayNames = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// field describes one of the five fields of a schedule
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var fields = []field{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	{name: "day of week", min: 0, max: 7, names: dayNames},
+}
+
+// Schedule is a parsed schedule
+type Schedule struct {
+	spec  string
+	every time.Duration // Interval of an @every schedule, 0 otherwise
+
+	// Bit sets of the matching values of each field, as in fields
+	minute, hour, dom, month, dow uint64
+
+	// Whether the day fields start with *, such as * or */2. As in cron, a
+	// day must then match both fields; when neither does, a day matching
+	// either one fires.
+	domAny, dowAny bool
+}
+
+// Parse parses a schedule: five cron fields (minute, hour, day of month,
+// month, day of week) with *, lists, ranges, and steps, such as
+// "*/15 9-17 * * mon-fri"; a shorthand such as @hourly or @daily; or
+// "@every DURATION", such as "@every 30m", at least a minute.
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %w", spec, err)
+		}
+		if every < MinInterval {
+			return nil, fmt.Errorf("invalid schedule '%s': interval shorter than %s", spec, MinInterval)
+		}
+		return &Schedule{spec: spec, every: every}, nil
+	}
+
+	expanded := spec
+	if strings.HasPrefix(spec, "@") {
+		var ok bool
+		if expanded, ok = descriptors[strings.ToLower(spec)]; !ok {
+			return nil, fmt.Errorf("invalid schedule '%s': unknown shorthand", spec)
+		}
+	}
+
+	parts := strings.Fields(expanded)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("invalid schedule '%s': expected 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(parts))
+	}
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		set, err := parseField(part, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule '%s': %s: %w", spec, fields[i].name, err)
+		}
+		sets[i] = set
+	}
+
+	s := &Schedule{
+		spec:   spec,
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: strings.HasPrefix(parts[2], "*"),
+		dowAny: strings.HasPrefix(parts[4], "*"),
+	}
+	// Sunday is both 0 and 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	if s.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("invalid schedule '%s': never fires", spec)
+	}
+	return s, nil
+}
+
+// String returns the schedule as it was written
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first time after t that the schedule fires, in the
+// location of t, or the zero time if it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Add(s.every)
+	}
+
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.Add(searchLimit); next.Before(limit); {
+		switch {
+		case !has(s.month, int(next.Month())):
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+		case !s.matchesDay(next):
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+		case !has(s.hour, next.Hour()):
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+		case !has(s.minute, next.Minute()):
+			next = next.Add(time.Minute)
+		default:
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t matches the day fields
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom, dow := has(s.dom, t.Day()), has(s.dow, int(t.Weekday()))
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// has reports whether value is in the bit set
+func has(set uint64, value int) bool {
+	return set&(1<<uint(value)) != 0
+}
+
+// parseField parses a comma-separated list of values, ranges, and steps
+// into a bit set
+func parseField(part string, f field) (uint64, error) {
+	var set uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step '%s'", stepPart)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = parseValue(first, f); err != nil {
+				return 0, err
+			}
+			high = low
+			if isRange {
+				if high, err = parseValue(last, f); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				// A single value with a step, such as 5/15, runs to the end
+				high = f.max
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range '%s'", rangePart)
+			}
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << uint(value)
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a number or name within the bounds of a field
+func parseValue(s string, f field) (int, error) {
+	if value, ok := f.names[strings.ToLower(s)]; ok {
+		return value, nil
+	}
+	value, err := strconv.Atoi(s)
+	if err != nil || value < f.min || value > f.max {
+		return 0, fmt.Errorf("invalid value '%s' (expected %d-%d)", s, f.min, f.max)
+	}
+	return value, nil
+}
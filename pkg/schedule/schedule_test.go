@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Thursday
+	base := time.Date(2026, 1, 15, 10, 7, 0, 0, time.UTC)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", base, at(1, 15, 10, 15)},
+		{"5/15 * * * *", base, at(1, 15, 10, 20)},
+		{"0 9-17 * * mon-fri", base, at(1, 15, 11, 0)},
+		{"0 9 * * MON-FRI", at(1, 16, 17, 0), at(1, 19, 9, 0)},
+		{"30 8 * jan,feb sat", base, at(1, 17, 8, 30)},
+		{"@daily", base, at(1, 16, 0, 0)},
+		{"@hourly", base, at(1, 15, 11, 0)},
+		{"@weekly", base, at(1, 18, 0, 0)},
+		{"@monthly", base, at(2, 1, 0, 0)},
+		{"0 0 * * 7", base, at(1, 18, 0, 0)},
+		{"@every 30m", base, at(1, 15, 10, 37)},
+		{"0 0 29 2 *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Without a * in either day field, a day matching either one fires
+		{"0 0 1,15 * mon", base, at(1, 19, 0, 0)},
+		// With */N, a day must match both fields
+		{"0 0 */2 * *", base, at(1, 17, 0, 0)},
+		{"0 0 */2 * sun", base, at(1, 25, 0, 0)},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Parse(%s) failed: %v", test.spec, err)
+			continue
+		}
+		if got := s.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("Parse(%s).Next(%s) = %s, want %s", test.spec, test.from, got, test.want)
+		}
+		if s.String() != test.spec {
+			t.Errorf("Parse(%s).String() = %s", test.spec, s.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@every 30s",
+		"@every soon",
+		"@sometimes",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * * 8",
+		"* * * foo *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"0 0 30 2 *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%s) succeeded, want an error", spec)
+		}
+	}
+}
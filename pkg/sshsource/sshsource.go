@@ -0,0 +1,259 @@
+package sshsource
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/config"
+)
+
+// remotePattern matches scp-style remote paths such as user@host:/path.
+// The user part is required so Windows drive letters are never mistaken
+// for hosts.
+var remotePattern = regexp.MustCompile(`^([^@/\s]+@[^:/\s]+):(.*)$`)
+
+// IsRemote reports whether source is an scp-style user@host:/path
+func IsRemote(source string) bool {
+	return remotePattern.MatchString(source)
+}
+
+// Fetch lists the files below a user@host:/path source over ssh, filters
+// them with the include/exclude patterns and size limits of cfg, and copies
+// the matching files into a new temporary directory by streaming them as tar
+// archives. The remote host needs only sh, find, wc, and a tar that accepts
+// file names as arguments. It returns the local directory to analyze and a
+// cleanup function removing it.
+func Fetch(source string, cfg *config.Config) (string, func(), error) {
+	match := remotePattern.FindStringSubmatch(source)
+	if match == nil {
+		return "", nil, fmt.Errorf("invalid remote path: %s", source)
+	}
+	host, dir := match[1], match[2]
+	if dir == "" {
+		dir = "."
+	}
+
+	if _, err := exec.LookPath("ssh"); err != nil {
+		return "", nil, fmt.Errorf("remote sources require the ssh command-line tool: %w", err)
+	}
+
+	// List regular files with their sizes relative to the remote directory
+	listing, err := run(host, nil, fmt.Sprintf("cd %s && %s", shellQuote(dir), listCommand))
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := path.Base(strings.TrimSuffix(dir, "/"))
+	if name == "." || name == "/" {
+		name = strings.SplitN(host, "@", 2)[1]
+	}
+
+	tmp, err := os.MkdirTemp("", "ingest-ssh-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(tmp) }
+	root := filepath.Join(tmp, name)
+
+	var selected []string
+	files, total := 0, int64(0)
+	for _, entry := range strings.Split(string(listing), "\x00") {
+		sizeField, rel, ok := strings.Cut(entry, " ")
+		rel = strings.TrimPrefix(rel, "./")
+		if !ok || rel == "" {
+			continue
+		}
+		size, _ := strconv.ParseInt(strings.TrimSpace(sizeField), 10, 64)
+
+		if !cfg.ShouldIncludeTree(root, filepath.Join(root, filepath.FromSlash(rel))) {
+			continue
+		}
+		if size > cfg.MaxFileSize || files >= cfg.MaxFiles || total+size > cfg.MaxTotalSize {
+			continue
+		}
+
+		selected = append(selected, rel)
+		files++
+		total += size
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if files == 0 {
+		return root, cleanup, nil
+	}
+
+	// Stream only the selected files in one connection, unpacking them as
+	// they arrive
+	if err := stream(host, strings.NewReader(archiveScript(dir, selected)), root); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+
+	return root, cleanup, nil
+}
+
+// listCommand prints the size and path of every regular file below the
+// current directory, each entry terminated by a NUL byte. wc is read from
+// standard input so it prints the size alone; BSD wc pads it with spaces.
+const listCommand = `find . -type f -exec sh -c 'for f; do printf "%s %s\0" "$(wc -c < "$f")" "$f"; done' sh {} +`
+
+// blockSize is the size of a tar block
+const blockSize = 512
+
+// maxArgBytes bounds the file names passed to one tar command, well below
+// the argument size limits of common systems
+const maxArgBytes = 32 * 1024
+
+// archiveScript returns a shell script writing the files below dir as tar
+// archives to standard output. Names are passed as arguments in batches of
+// at most maxArgBytes, one tar command per batch, rather than through GNU or
+// bsdtar options reading a file list.
+func archiveScript(dir string, files []string) string {
+	var script strings.Builder
+	script.WriteString("cd " + shellQuote(dir) + " || exit 1\n")
+
+	var batch strings.Builder
+	flush := func() {
+		if batch.Len() > 0 {
+			script.WriteString("tar -cf -" + batch.String() + " || exit 1\n")
+			batch.Reset()
+		}
+	}
+	for _, file := range files {
+		// The ./ prefix keeps names starting with - from being read as options
+		arg := " " + shellQuote("./"+file)
+		if batch.Len()+len(arg) > maxArgBytes {
+			flush()
+		}
+		batch.WriteString(arg)
+	}
+	flush()
+	return script.String()
+}
+
+// stream runs a shell script on the remote host and unpacks the tar archives
+// it writes below root while they are read, without holding them in memory
+func stream(host string, script io.Reader, root string) error {
+	var stderr bytes.Buffer
+	slog.Debug("Running ssh", "host", host, "command", "sh -s")
+	cmd := exec.Command("ssh", "-o", "BatchMode=yes", host, "sh -s")
+	cmd.Stdin = script
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("ssh %s: %v", host, err)
+	}
+
+	if err := extractAll(stdout, root); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("ssh %s: %v: %s", host, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// extractAll unpacks the regular files of consecutive tar archives below
+// root until the stream ends, skipping the zero blocks that end and pad
+// each archive
+func extractAll(stream io.Reader, root string) error {
+	reader := bufio.NewReader(stream)
+	zero := make([]byte, blockSize)
+	for {
+		block, err := reader.Peek(blockSize)
+		if err == io.EOF && len(block) == 0 {
+			return nil
+		}
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(block, zero) {
+			reader.Discard(blockSize)
+			continue
+		}
+		if err := extract(reader, root); err != nil {
+			return err
+		}
+	}
+}
+
+// extract unpacks regular files from a tar archive below root
+func extract(archive io.Reader, root string) error {
+	reader := tar.NewReader(archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// Refuse entries that would escape the destination
+		dest := filepath.Join(root, filepath.FromSlash(header.Name))
+		if !strings.HasPrefix(dest, root+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in remote archive: %s", header.Name)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		file, err := os.Create(dest)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(file, reader); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+	}
+}
+
+// run executes a shell command on the remote host and returns its output
+func run(host string, stdin io.Reader, command string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	slog.Debug("Running ssh", "host", host, "command", command)
+	cmd := exec.Command("ssh", "-o", "BatchMode=yes", host, command)
+	cmd.Stdin = stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ssh %s: %v: %s", host, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// shellQuote quotes a string for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
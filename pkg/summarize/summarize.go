@@ -0,0 +1,88 @@
+package summarize
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultPrompt asks for a summary that still tells a reader where things are
+const DefaultPrompt = "Summarize the following source file in at most five sentences: " +
+	"its purpose, the main types, functions, or sections it defines, and how it " +
+	"fits into the project. Reply with the summary only."
+
+// Client summarizes files with a model served by Ollama
+type Client struct {
+	Model  string // Model name, such as llama3.2 or qwen2.5-coder:7b
+	Prompt string // Instructions placed before each file
+
+	url  string
+	http *http.Client
+}
+
+// New returns a client for a URL of the form http://host:port/model, such as
+// http://localhost:11434/llama3.2. An empty prompt uses DefaultPrompt.
+func New(rawURL, prompt string) (*Client, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("'%s' is not an http(s) URL of the form http://host:port/model", rawURL)
+	}
+	model := strings.Trim(u.Path, "/")
+	if model == "" {
+		return nil, fmt.Errorf("'%s' does not name a model, as in http://localhost:11434/llama3.2", rawURL)
+	}
+	if prompt == "" {
+		prompt = DefaultPrompt
+	}
+
+	return &Client{
+		Model:  model,
+		Prompt: prompt,
+		url:    u.Scheme + "://" + u.Host + "/api/generate",
+		http:   &http.Client{Timeout: 10 * time.Minute},
+	}, nil
+}
+
+// Summarize returns the model's summary of a file. Generation is
+// deterministic so that digests of the same tree stay comparable.
+func (c *Client) Summarize(path, content string) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":   c.Model,
+		"prompt":  fmt.Sprintf("%s\n\nFile: %s\n\n```\n%s\n```", c.Prompt, path, strings.TrimSuffix(content, "\n")),
+		"stream":  false,
+		"options": map[string]any{"temperature": 0, "seed": 0},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.http.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s: %s", c.url, resp.Status, strings.TrimSpace(string(data)))
+	}
+
+	var response struct {
+		Response string `json:"response"`
+	}
+	if err := json.Unmarshal(data, &response); err != nil {
+		return "", fmt.Errorf("invalid generate response: %w", err)
+	}
+	return strings.TrimSpace(response.Response), nil
+}
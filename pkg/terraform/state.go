@@ -0,0 +1,159 @@
+package terraform
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// Redacted replaces the values removed from state files
+const Redacted = "[redacted]"
+
+// Unreadable replaces the content of state files that cannot be parsed, and
+// so cannot be redacted
+const Unreadable = "[Terraform state omitted: not valid JSON, so it could not be redacted]\n"
+
+// secretAttribute matches the names of resource attributes holding secrets
+// even when the provider does not mark them sensitive
+var secretAttribute = regexp.MustCompile(`(?i)(password|secret|token|private_key|access_key|credential)`)
+
+// state is the part of a state file deciding what is sensitive
+type state struct {
+	Outputs map[string]struct {
+		Sensitive bool `json:"sensitive"`
+	} `json:"outputs"`
+	Resources []struct {
+		Instances []struct {
+			SensitiveAttributes []json.RawMessage `json:"sensitive_attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// span is a range of bytes holding a JSON value
+type span struct {
+	start, end int64
+}
+
+// RedactState replaces the secrets of a Terraform state file with
+// [redacted], keeping its layout: the values of sensitive outputs, the
+// resource attributes listed in sensitive_attributes, and attributes whose
+// names suggest secrets, such as password or private_key. It returns the
+// redacted content and the number of values replaced. Content that is not
+// valid JSON is replaced entirely.
+func RedactState(content string) (string, int) {
+	var parsed state
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return Unreadable, 0
+	}
+
+	// Attributes named by sensitive_attributes, per resource and instance
+	sensitive := map[[2]int][]string{}
+	for r, resource := range parsed.Resources {
+		for i, instance := range resource.Instances {
+			for _, raw := range instance.SensitiveAttributes {
+				if name := sensitiveAttribute(raw); name != "" {
+					sensitive[[2]int{r, i}] = append(sensitive[[2]int{r, i}], name)
+				}
+			}
+		}
+	}
+
+	redact := func(path []string) bool {
+		switch {
+		case len(path) == 3 && path[0] == "outputs" && path[2] == "value":
+			return parsed.Outputs[path[1]].Sensitive
+		case len(path) == 6 && path[0] == "resources" && path[2] == "instances" && path[4] == "attributes":
+			r, _ := strconv.Atoi(path[1])
+			i, _ := strconv.Atoi(path[3])
+			return slices.Contains(sensitive[[2]int{r, i}], path[5]) || secretAttribute.MatchString(path[5])
+		}
+		return false
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader([]byte(content)))
+	var spans []span
+	if err := walk(decoder, nil, redact, &spans); err != nil {
+		return Unreadable, 0
+	}
+
+	// Replace from the end so earlier offsets stay valid
+	result := []byte(content)
+	count := 0
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		// Empty values hold nothing to hide
+		switch string(result[s.start:s.end]) {
+		case "null", `""`, "[]", "{}":
+			continue
+		}
+		replacement, _ := json.Marshal(Redacted)
+		result = slices.Concat(result[:s.start], replacement, result[s.end:])
+		count++
+	}
+	return string(result), count
+}
+
+// sensitiveAttribute returns the top-level attribute named by a path of
+// sensitive_attributes, such as [{"type":"get_attr","value":"password"}],
+// or "" if it cannot be read
+func sensitiveAttribute(raw json.RawMessage) string {
+	var steps []struct {
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if json.Unmarshal(raw, &steps) != nil || len(steps) == 0 || steps[0].Type != "get_attr" {
+		return ""
+	}
+	var name string
+	if json.Unmarshal(steps[0].Value, &name) != nil {
+		return ""
+	}
+	return name
+}
+
+// walk reads the next JSON value from decoder, at path, recording the spans
+// of the object members for which redact is true
+func walk(decoder *json.Decoder, path []string, redact func([]string) bool, spans *[]span) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	switch token {
+	case json.Delim('{'):
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			member := append(slices.Clip(path), key.(string))
+			if !redact(member) {
+				if err := walk(decoder, member, redact, spans); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// The raw value ends at the offset the decoder stops at
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return err
+			}
+			end := decoder.InputOffset()
+			*spans = append(*spans, span{start: end - int64(len(value)), end: end})
+		}
+		_, err = decoder.Token()
+		return err
+
+	case json.Delim('['):
+		for index := 0; decoder.More(); index++ {
+			if err := walk(decoder, append(slices.Clip(path), strconv.Itoa(index)), redact, spans); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+// Package terraform summarizes Terraform configurations, listing their
+// providers, modules, and resources, and redacts the secrets of state files.
+package terraform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Resource is a managed resource or data source declared in a configuration
+type Resource struct {
+	Data     bool   // Whether it is a data source
+	Type     string // Resource type, such as aws_instance
+	Name     string // Local name
+	Provider string // Provider name, from the provider argument or the type prefix
+}
+
+// Module is a module call
+type Module struct {
+	Name    string // Local name
+	Source  string // Source address
+	Version string // Version constraint (may be empty)
+}
+
+// Provider is a provider required or configured by a configuration
+type Provider struct {
+	Name    string // Local name, such as aws
+	Source  string // Source address, such as hashicorp/aws (may be empty)
+	Version string // Version constraint (may be empty)
+}
+
+// Config holds the declarations of Terraform files
+type Config struct {
+	Providers []Provider
+	Modules   []Module
+	Resources []Resource
+}
+
+var (
+	// resourceHeader matches the first line of a resource or data block
+	resourceHeader = regexp.MustCompile(`^\s*(resource|data)\s+"([^"]+)"\s+"([^"]+)"\s*\{`)
+
+	// namedHeader matches the first line of a module or provider block
+	namedHeader = regexp.MustCompile(`^\s*(module|provider)\s+"([^"]+)"\s*\{`)
+
+	// terraformHeader matches the first line of the terraform block
+	terraformHeader = regexp.MustCompile(`^\s*terraform\s*\{`)
+
+	// requiredProvidersHeader matches the first line of required_providers
+	requiredProvidersHeader = regexp.MustCompile(`^\s*required_providers\s*\{`)
+
+	// providerRequirement matches an entry of required_providers, either an
+	// object or a legacy version string
+	providerRequirement = regexp.MustCompile(`^\s*([\w-]+)\s*=\s*(\{|"([^"]*)")`)
+
+	// stringArgument matches an argument set to a string, such as source
+	stringArgument = regexp.MustCompile(`\b(source|version)\s*=\s*"([^"]*)"`)
+
+	// providerArgument matches the provider argument of a resource, such as
+	// provider = aws.west
+	providerArgument = regexp.MustCompile(`^\s*provider\s*=\s*([\w-]+)`)
+
+	// heredocStart matches the opening of a heredoc string
+	heredocStart = regexp.MustCompile(`<<-?([A-Za-z_][\w-]*)\s*$`)
+)
+
+// IsConfig reports whether the file name is a Terraform configuration file
+func IsConfig(name string) bool {
+	return strings.HasSuffix(name, ".tf")
+}
+
+// IsState reports whether the file name is a Terraform state file or backup
+func IsState(name string) bool {
+	return strings.HasSuffix(name, ".tfstate") || strings.HasSuffix(name, ".tfstate.backup")
+}
+
+// Parse extracts the providers, modules, and resources declared in a
+// Terraform file, in declaration order. It reads the native syntax line by
+// line, so expressions are not evaluated.
+func Parse(content string) *Config {
+	cfg := &Config{}
+	depth := 0
+	heredoc := ""
+	inComment := false
+
+	var resource *Resource // Block being read at depth 1
+	var module *Module
+	var provider *Provider
+	inTerraform, inRequired := false, false
+
+	for _, line := range strings.Split(content, "\n") {
+		if heredoc != "" {
+			if strings.TrimSpace(line) == heredoc {
+				heredoc = ""
+			}
+			continue
+		}
+
+		var code string
+		code, inComment = stripComment(line, inComment)
+		switch {
+		case depth == 0:
+			resource, module, provider, inTerraform = nil, nil, nil, false
+			if m := resourceHeader.FindStringSubmatch(code); m != nil {
+				cfg.Resources = append(cfg.Resources, Resource{Data: m[1] == "data", Type: m[2], Name: m[3], Provider: typeProvider(m[2])})
+				resource = &cfg.Resources[len(cfg.Resources)-1]
+			} else if m := namedHeader.FindStringSubmatch(code); m != nil && m[1] == "module" {
+				cfg.Modules = append(cfg.Modules, Module{Name: m[2]})
+				module = &cfg.Modules[len(cfg.Modules)-1]
+			} else if m != nil {
+				cfg.addProvider(Provider{Name: m[2]})
+			} else if terraformHeader.MatchString(code) {
+				inTerraform = true
+			}
+
+		case depth == 1 && resource != nil:
+			if m := providerArgument.FindStringSubmatch(code); m != nil {
+				resource.Provider = m[1]
+			}
+
+		case depth == 1 && module != nil:
+			for _, m := range stringArgument.FindAllStringSubmatch(code, -1) {
+				if m[1] == "source" {
+					module.Source = m[2]
+				} else {
+					module.Version = m[2]
+				}
+			}
+
+		case depth == 1 && inTerraform:
+			inRequired = requiredProvidersHeader.MatchString(code)
+
+		case depth == 2 && inRequired:
+			provider = nil
+			if m := providerRequirement.FindStringSubmatch(code); m != nil {
+				p := cfg.addProvider(Provider{Name: m[1], Version: m[3]})
+				if m[2] == "{" {
+					provider = p
+					setRequirement(provider, code)
+				}
+			}
+
+		case depth == 3 && provider != nil:
+			setRequirement(provider, code)
+		}
+
+		depth = max(0, depth+braceDelta(code))
+		if depth < 2 {
+			inRequired = false
+		}
+		if m := heredocStart.FindStringSubmatch(code); m != nil {
+			heredoc = m[1]
+		}
+	}
+	return cfg
+}
+
+// Add merges the declarations of another file of the same module
+func (c *Config) Add(other *Config) {
+	for _, provider := range other.Providers {
+		c.addProvider(provider)
+	}
+	c.Modules = append(c.Modules, other.Modules...)
+	c.Resources = append(c.Resources, other.Resources...)
+}
+
+// Empty reports whether no providers, modules, or resources are declared
+func (c *Config) Empty() bool {
+	return len(c.Providers) == 0 && len(c.Modules) == 0 && len(c.Resources) == 0
+}
+
+// addProvider records a provider, merging it with an earlier declaration of
+// the same name, and returns it
+func (c *Config) addProvider(provider Provider) *Provider {
+	for i := range c.Providers {
+		if existing := &c.Providers[i]; existing.Name == provider.Name {
+			if provider.Source != "" {
+				existing.Source = provider.Source
+			}
+			if provider.Version != "" {
+				existing.Version = provider.Version
+			}
+			return existing
+		}
+	}
+	c.Providers = append(c.Providers, provider)
+	return &c.Providers[len(c.Providers)-1]
+}
+
+// setRequirement reads the source and version of a required provider
+func setRequirement(provider *Provider, code string) {
+	for _, m := range stringArgument.FindAllStringSubmatch(code, -1) {
+		if m[1] == "source" {
+			provider.Source = m[2]
+		} else {
+			provider.Version = m[2]
+		}
+	}
+}
+
+// typeProvider returns the provider implied by a resource type: the part
+// before the first underscore
+func typeProvider(resourceType string) string {
+	name, _, _ := strings.Cut(resourceType, "_")
+	return name
+}
+
+// stripComment removes the comments of a line outside strings: a trailing #
+// or // comment and /* */ comments, which may continue from the previous
+// line if inComment is set. It returns the code and whether a comment
+// continues on the next line.
+func stripComment(line string, inComment bool) (string, bool) {
+	var code strings.Builder
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inComment:
+			if strings.HasPrefix(line[i:], "*/") {
+				inComment = false
+				i++
+			}
+			continue
+		case inString && c == '\\':
+			code.WriteByte(c)
+			i++
+			if i < len(line) {
+				code.WriteByte(line[i])
+			}
+			continue
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '#', strings.HasPrefix(line[i:], "//"):
+			return code.String(), false
+		case strings.HasPrefix(line[i:], "/*"):
+			inComment = true
+			i++
+			continue
+		}
+		code.WriteByte(c)
+	}
+	return code.String(), inComment
+}
+
+// braceDelta returns the change in block depth over a line: opening minus
+// closing braces and brackets outside strings. Braces of template
+// interpolations such as "${var.name}" are part of their string.
+func braceDelta(code string) int {
+	delta := 0
+	inString := false
+	interpolation := 0
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case inString && c == '\\':
+			i++
+		case inString && c == '$' && i+1 < len(code) && code[i+1] == '{':
+			interpolation++
+			i++
+		case inString && interpolation > 0 && c == '}':
+			interpolation--
+		case inString && interpolation == 0 && c == '"':
+			inString = false
+		case inString:
+		case c == '"':
+			inString = true
+		case c == '{':
+			delta++
+		case c == '}':
+			delta--
+		}
+	}
+	return delta
+}
@@ -0,0 +1,301 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// commentSyntax describes how comments and string literals look in a
+// family of languages
+type commentSyntax struct {
+	lineMarkers []string // Line comment markers
+	blockStart  string   // Block comment opener (empty if none)
+	blockEnd    string   // Block comment closer
+	quotes      []string // String delimiters, longest first
+	rawQuote    string   // Delimiter whose strings have no escapes
+	hashAtWord  bool     // Line markers only start a comment at the start of a word (shell)
+	docStrings  bool     // Triple-quoted strings starting a line are docstrings (Python)
+}
+
+var (
+	cFamilySyntax = &commentSyntax{
+		lineMarkers: []string{"//"},
+		blockStart:  "/*",
+		blockEnd:    "*/",
+		quotes:      []string{`"`, `'`, "`"},
+	}
+	goSyntax = &commentSyntax{
+		lineMarkers: []string{"//"},
+		blockStart:  "/*",
+		blockEnd:    "*/",
+		quotes:      []string{`"`, `'`, "`"},
+		rawQuote:    "`",
+	}
+	pythonSyntax = &commentSyntax{
+		lineMarkers: []string{"#"},
+		quotes:      []string{`"""`, `'''`, `"`, `'`},
+		docStrings:  true,
+	}
+	shellSyntax = &commentSyntax{
+		lineMarkers: []string{"#"},
+		quotes:      []string{`"`, `'`},
+		rawQuote:    `'`,
+		hashAtWord:  true,
+	}
+)
+
+// commentSyntaxes maps language identifiers to their comment syntax
+var commentSyntaxes = map[string]*commentSyntax{
+	"go":         goSyntax,
+	"javascript": cFamilySyntax, "jsx": cFamilySyntax,
+	"typescript": cFamilySyntax, "tsx": cFamilySyntax,
+	"java": cFamilySyntax, "kotlin": cFamilySyntax, "scala": cFamilySyntax,
+	"c": cFamilySyntax, "cpp": cFamilySyntax, "csharp": cFamilySyntax,
+	"swift": cFamilySyntax, "rust": cFamilySyntax, "dart": cFamilySyntax,
+	"python": pythonSyntax,
+	"bash":   shellSyntax, "zsh": shellSyntax, "fish": shellSyntax,
+}
+
+// goDeclPattern matches lines that Go doc comments attach to
+var goDeclPattern = regexp.MustCompile(`^\s*(package|func|type|var|const)\b`)
+
+// SupportsCommentStripping reports whether comments can be stripped for the language
+func SupportsCommentStripping(language string) bool {
+	return commentSyntaxes[language] != nil
+}
+
+// StripComments removes comments from source code in the given language,
+// leaving string literals untouched. Doc comments (Go comments attached to
+// declarations, /** */ blocks, /// and //! lines, Python docstrings) are
+// kept when keepDoc is set. Compiler directives such as //go:build and
+// shebang lines are always kept. Lines left empty by removing a comment are
+// dropped. Content in unsupported languages is returned unchanged.
+func StripComments(language, content string, keepDoc bool) string {
+	syntax := commentSyntaxes[language]
+	if syntax == nil {
+		return content
+	}
+
+	s := &commentStripper{syntax: syntax, language: language, src: content, keepDoc: keepDoc, dirty: map[int]bool{}}
+	s.run()
+	return s.result()
+}
+
+// commentStripper scans source code once, copying everything except comments
+type commentStripper struct {
+	syntax   *commentSyntax
+	language string
+	src      string
+	keepDoc  bool
+
+	out     strings.Builder
+	line    int          // Current output line
+	dirty   map[int]bool // Output lines that had a comment removed
+	sawCode bool         // Code has been seen, so comments no longer lead the file
+	docs    []docSpan    // Doc comments, docstrings, and the leading comment
+}
+
+// docSpan is the source range of a doc comment or docstring
+type docSpan struct {
+	start, end int
+	docString  bool
+}
+
+// run scans the whole source
+func (s *commentStripper) run() {
+	for i := 0; i < len(s.src); {
+		// Shebang lines are never comments
+		if i == 0 && strings.HasPrefix(s.src, "#!") {
+			i = s.copyTo(i, s.lineEnd(i))
+			continue
+		}
+
+		if quote := s.quoteAt(i); quote != "" {
+			end := s.stringEnd(i, quote)
+			isDoc := s.syntax.docStrings && len(quote) == 3 && s.atLineStart(i)
+			if isDoc {
+				s.docs = append(s.docs, docSpan{start: i, end: end, docString: true})
+			}
+			s.sawCode = s.sawCode || !isDoc
+			if isDoc && !s.keepDoc {
+				i = s.drop(i, end)
+			} else {
+				i = s.copyTo(i, end)
+			}
+			continue
+		}
+
+		if s.syntax.blockStart != "" && strings.HasPrefix(s.src[i:], s.syntax.blockStart) {
+			end := strings.Index(s.src[i+len(s.syntax.blockStart):], s.syntax.blockEnd)
+			if end < 0 {
+				end = len(s.src)
+			} else {
+				end += i + len(s.syntax.blockStart) + len(s.syntax.blockEnd)
+			}
+
+			isDoc := strings.HasPrefix(s.src[i:], "/**") && !strings.HasPrefix(s.src[i:], "/**/")
+			if isDoc || !s.sawCode {
+				s.docs = append(s.docs, docSpan{start: i, end: end})
+			}
+			if s.keepDoc && isDoc {
+				i = s.copyTo(i, end)
+			} else {
+				i = s.drop(i, end)
+			}
+			continue
+		}
+
+		if marker := s.lineMarkerAt(i); marker != "" {
+			end := s.lineEnd(i)
+			if s.isDocComment(i, marker) || !s.sawCode && !s.isDirective(i, marker) {
+				s.docs = append(s.docs, docSpan{start: i, end: end})
+			}
+			if s.keepLineComment(i, marker) {
+				i = s.copyTo(i, end)
+			} else {
+				i = s.drop(i, end)
+			}
+			continue
+		}
+
+		s.sawCode = s.sawCode || !strings.ContainsRune(" \t\r\n", rune(s.src[i]))
+		i = s.copyTo(i, i+1)
+	}
+}
+
+// quoteAt returns the string delimiter starting at i, if any
+func (s *commentStripper) quoteAt(i int) string {
+	for _, quote := range s.syntax.quotes {
+		if strings.HasPrefix(s.src[i:], quote) {
+			// An apostrophe inside a shell word (don't) is not a quote
+			if s.syntax.hashAtWord && i > 0 && isWordByte(s.src[i-1]) && quote == `'` {
+				return ""
+			}
+			return quote
+		}
+	}
+	return ""
+}
+
+// stringEnd returns the index just past the string literal starting at i
+func (s *commentStripper) stringEnd(i int, quote string) int {
+	j := i + len(quote)
+	for j < len(s.src) {
+		if s.src[j] == '\\' && quote != s.syntax.rawQuote {
+			j += 2
+			continue
+		}
+		if strings.HasPrefix(s.src[j:], quote) {
+			return j + len(quote)
+		}
+		// Single-line strings end at the newline if unterminated
+		if s.src[j] == '\n' && len(quote) == 1 && quote != "`" && !s.syntax.hashAtWord {
+			return j
+		}
+		j++
+	}
+	return len(s.src)
+}
+
+// lineMarkerAt returns the line comment marker starting at i, if any
+func (s *commentStripper) lineMarkerAt(i int) string {
+	for _, marker := range s.syntax.lineMarkers {
+		if !strings.HasPrefix(s.src[i:], marker) {
+			continue
+		}
+		// In shells, # inside a word ($#, ${#x}, a#b) is not a comment
+		if s.syntax.hashAtWord && i > 0 && !strings.ContainsRune(" \t\n;|&(", rune(s.src[i-1])) {
+			return ""
+		}
+		return marker
+	}
+	return ""
+}
+
+// keepLineComment reports whether the line comment at i must be kept
+func (s *commentStripper) keepLineComment(i int, marker string) bool {
+	return s.isDirective(i, marker) || s.keepDoc && s.isDocComment(i, marker)
+}
+
+// isDirective reports whether the line comment at i is a compiler directive
+// or build constraint, which change behavior
+func (s *commentStripper) isDirective(i int, marker string) bool {
+	rest := s.src[i+len(marker):]
+	if s.language == "go" && (strings.HasPrefix(rest, "go:") || strings.HasPrefix(rest, " +build") || strings.HasPrefix(rest, "line ")) {
+		return true
+	}
+	return strings.HasPrefix(rest, "/ <reference")
+}
+
+// isDocComment reports whether the line comment at i is a doc comment
+func (s *commentStripper) isDocComment(i int, marker string) bool {
+	rest := s.src[i+len(marker):]
+
+	// Rust and C# doc comments
+	if marker == "//" && (strings.HasPrefix(rest, "/") || strings.HasPrefix(rest, "!")) {
+		return true
+	}
+
+	// Go doc comments are full-line comment blocks directly above a declaration
+	if s.language == "go" && s.atLineStart(i) {
+		for j := s.lineEnd(i) + 1; j < len(s.src); j = s.lineEnd(j) + 1 {
+			next := strings.TrimSpace(s.src[j:s.lineEnd(j)])
+			if strings.HasPrefix(next, "//") {
+				continue
+			}
+			return goDeclPattern.MatchString(next)
+		}
+	}
+
+	return false
+}
+
+// atLineStart reports whether only whitespace precedes i on its line
+func (s *commentStripper) atLineStart(i int) bool {
+	start := strings.LastIndex(s.src[:i], "\n") + 1
+	return strings.TrimSpace(s.src[start:i]) == ""
+}
+
+// lineEnd returns the index of the newline ending the line containing i
+func (s *commentStripper) lineEnd(i int) int {
+	if end := strings.IndexByte(s.src[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(s.src)
+}
+
+// copyTo copies src[i:end] to the output and returns end
+func (s *commentStripper) copyTo(i, end int) int {
+	s.out.WriteString(s.src[i:end])
+	s.line += strings.Count(s.src[i:end], "\n")
+	return end
+}
+
+// drop skips src[i:end], marking the output line so it can be cleaned up
+func (s *commentStripper) drop(i, end int) int {
+	s.dirty[s.line] = true
+	return end
+}
+
+// result trims lines that lost a comment and drops those left empty
+func (s *commentStripper) result() string {
+	lines := strings.Split(s.out.String(), "\n")
+	kept := make([]string, 0, len(lines))
+
+	for i, line := range lines {
+		if s.dirty[i] {
+			line = strings.TrimRight(line, " \t\r")
+			if line == "" {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+
+	return strings.Join(kept, "\n")
+}
+
+// isWordByte reports whether b can be part of a shell word
+func isWordByte(b byte) bool {
+	return b == '_' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
@@ -0,0 +1,104 @@
+package transform
+
+import (
+	"sort"
+	"strings"
+)
+
+// ExtractDocs reduces source code to its documentation: the comment leading
+// the file (such as a package comment or license header), doc comments with
+// the declaration line that follows each of them, and Python docstrings with
+// the signature they document. Groups are separated by blank lines. Content
+// in unsupported languages is returned unchanged.
+func ExtractDocs(language, content string) string {
+	syntax := commentSyntaxes[language]
+	if syntax == nil {
+		return content
+	}
+
+	s := &commentStripper{syntax: syntax, language: language, src: content, keepDoc: true, dirty: map[int]bool{}}
+	s.run()
+
+	lines := strings.Split(content, "\n")
+	newlines := make([]int, 0, len(lines))
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			newlines = append(newlines, i)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(newlines, offset)
+	}
+
+	// Lines where a doc span starts, so a comment followed by another one
+	// does not take it as its declaration
+	starts := make(map[int]bool, len(s.docs))
+	for _, span := range s.docs {
+		starts[lineOf(span.start)] = true
+	}
+
+	var groups []string
+	last := -1 // Last line already written
+	for _, span := range s.docs {
+		first, end := lineOf(span.start), lineOf(span.end)
+		if span.end > span.start && content[span.end-1] == '\n' {
+			end--
+		}
+
+		// A docstring follows the signature it documents
+		if span.docString {
+			if decl := signatureStart(lines, first); decl > last {
+				first = decl
+			}
+		}
+		if first <= last {
+			first = last + 1
+		}
+		if first > end {
+			continue
+		}
+
+		// A comment precedes the declaration it documents, after any
+		// annotations or decorators
+		decl := ""
+		if !span.docString {
+			for end+1 < len(lines) && !starts[end+1] && strings.TrimSpace(lines[end+1]) != "" {
+				end++
+				if !strings.HasPrefix(strings.TrimSpace(lines[end]), "@") {
+					decl = strings.TrimSuffix(strings.TrimRight(lines[end], " \t\r{"), " ")
+					break
+				}
+			}
+		}
+
+		group := strings.Join(lines[first:end+1], "\n")
+		if decl != "" {
+			group = strings.Join(lines[first:end], "\n") + "\n" + decl
+		}
+		if first == last+1 && len(groups) > 0 {
+			groups[len(groups)-1] += "\n" + group
+		} else {
+			groups = append(groups, group)
+		}
+		last = end
+	}
+
+	return strings.Join(groups, "\n\n")
+}
+
+// signatureStart returns the line of the Python def or class statement that
+// ends just above a docstring on line doc, or doc if there is none
+func signatureStart(lines []string, doc int) int {
+	if doc == 0 || !strings.HasSuffix(strings.TrimSpace(stripHashComment(lines[doc-1])), ":") {
+		return doc
+	}
+	for i := doc - 1; i >= 0 && i >= doc-10; i-- {
+		if pythonDeclPattern.MatchString(lines[i]) {
+			return i
+		}
+		if strings.TrimSpace(lines[i]) == "" {
+			break
+		}
+	}
+	return doc
+}
@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+)
+
+// licenseSimilarity is the share of a header's words that must appear in
+// the LICENSE text for the header to count as a copy of it
+const licenseSimilarity = 0.7
+
+// licenseMinWords is the fewest words a header needs to be compared with the
+// LICENSE text; shorter comments match by chance
+const licenseMinWords = 8
+
+// licenseWord matches the words compared between headers and LICENSE texts
+var licenseWord = regexp.MustCompile(`[a-z0-9]+`)
+
+// LicenseWords returns the set of words of a LICENSE text, for comparing
+// file headers with it
+func LicenseWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range licenseWord.FindAllString(strings.ToLower(text), -1) {
+		words[word] = true
+	}
+	return words
+}
+
+// StripLicenseHeader removes the comment block at the top of a source file
+// in the given language when it is a license header: it starts with a
+// copyright notice or SPDX identifier, mentions being licensed under
+// something, or its words mostly appear in licenseWords. A shebang line is
+// kept, and so are blocks holding compiler directives such as //go:build
+// and Go package documentation.
+// It reports whether a header was removed.
+func StripLicenseHeader(language, content string, licenseWords map[string]bool) (string, bool) {
+	syntax := commentSyntaxes[language]
+	if syntax == nil {
+		return content, false
+	}
+
+	lines := strings.SplitAfter(content, "\n")
+	start := 0
+	if start < len(lines) && strings.HasPrefix(lines[start], "#!") {
+		start++
+	}
+	first := start
+	for first < len(lines) && strings.TrimSpace(lines[first]) == "" {
+		first++
+	}
+
+	end, text := commentBlock(lines[first:], syntax)
+	if end == 0 || !isLicenseHeader(text, licenseWords) {
+		return content, false
+	}
+	end += first
+
+	// A Go comment attached to the package clause is its documentation
+	if language == "go" && end < len(lines) && goDeclPattern.MatchString(lines[end]) {
+		return content, false
+	}
+
+	// Drop the blank lines that separated the header from the code
+	for end < len(lines) && strings.TrimSpace(lines[end]) == "" {
+		end++
+	}
+	return strings.Join(lines[:start], "") + strings.Join(lines[end:], ""), true
+}
+
+// commentBlock returns the number of lines of the comment block starting
+// the given lines and its text without comment markers. A block is a run of
+// line comments up to a blank line, or a block comment. It returns 0 if the
+// lines do not start with a comment or the block holds a directive.
+func commentBlock(lines []string, syntax *commentSyntax) (int, string) {
+	if len(lines) == 0 {
+		return 0, ""
+	}
+	var text strings.Builder
+	first := strings.TrimSpace(lines[0])
+
+	if syntax.blockStart != "" && strings.HasPrefix(first, syntax.blockStart) {
+		for i, line := range lines {
+			body := strings.TrimSpace(line)
+			if i == 0 {
+				body = strings.TrimPrefix(body, syntax.blockStart)
+			}
+			body, rest, closed := strings.Cut(body, syntax.blockEnd)
+			text.WriteString(strings.TrimLeft(body, "*! ") + "\n")
+			if closed {
+				// Code after the comment on its last line is kept whole
+				if strings.TrimSpace(rest) != "" {
+					return 0, ""
+				}
+				return i + 1, text.String()
+			}
+		}
+		return 0, ""
+	}
+
+	n := 0
+	for _, line := range lines {
+		body, ok := trimLineMarker(strings.TrimSpace(line), syntax.lineMarkers)
+		if !ok {
+			break
+		}
+		if strings.HasPrefix(body, "go:") || strings.HasPrefix(body, "+build") {
+			return 0, ""
+		}
+		text.WriteString(strings.TrimSpace(strings.TrimLeft(body, "/!")) + "\n")
+		n++
+	}
+	return n, text.String()
+}
+
+// trimLineMarker removes the line comment marker starting line
+func trimLineMarker(line string, markers []string) (string, bool) {
+	for _, marker := range markers {
+		if strings.HasPrefix(line, marker) {
+			return strings.TrimPrefix(line, marker), true
+		}
+	}
+	return "", false
+}
+
+// isLicenseHeader reports whether the text of a comment block is a license
+// header
+func isLicenseHeader(text string, licenseWords map[string]bool) bool {
+	lower := strings.ToLower(text)
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(lower), "\n")
+	if strings.HasPrefix(firstLine, "copyright") || strings.HasPrefix(firstLine, "(c)") ||
+		strings.Contains(lower, "spdx-license-identifier") || strings.Contains(lower, "licensed under") {
+		return true
+	}
+
+	words := licenseWord.FindAllString(lower, -1)
+	if len(licenseWords) == 0 || len(words) < licenseMinWords {
+		return false
+	}
+	matched := 0
+	for _, word := range words {
+		if licenseWords[word] {
+			matched++
+		}
+	}
+	return float64(matched) >= licenseSimilarity*float64(len(words))
+}
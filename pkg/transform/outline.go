@@ -0,0 +1,587 @@
+package transform
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// outlineIndent indents nested declarations in C-family outlines
+const outlineIndent = "    "
+
+// SupportsOutline reports whether files of the language can be outlined
+func SupportsOutline(language string) bool {
+	switch language {
+	case "go", "python", "java", "javascript", "jsx", "typescript", "tsx":
+		return true
+	}
+	return false
+}
+
+// Outline reduces source code to its declarations: package-level and class
+// members with their signatures and doc comments, without function bodies.
+// Go is parsed with go/parser; Python, Java, and JavaScript/TypeScript are
+// scanned by lightweight parsers that follow indentation or braces. It
+// reports false for unsupported languages and Go files that do not parse.
+func Outline(language, content string) (string, bool) {
+	switch language {
+	case "go":
+		return outlineGo(content)
+	case "python":
+		return outlinePython(content), true
+	case "java", "javascript", "jsx", "typescript", "tsx":
+		o := &braceOutliner{src: content, asi: language != "java", typeBlocks: language != "java"}
+		return o.run(), true
+	}
+	return "", false
+}
+
+// outlineGo removes function bodies, and the comments inside them, from a
+// Go file and reprints it
+func outlineGo(content string) (string, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", content, parser.ParseComments)
+	if err != nil {
+		return "", false
+	}
+
+	var bodies []*ast.BlockStmt
+	for _, decl := range file.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Body != nil {
+			bodies = append(bodies, fn.Body)
+			fn.Body = nil
+		}
+	}
+
+	var comments []*ast.CommentGroup
+	for _, group := range file.Comments {
+		inBody := false
+		for _, body := range bodies {
+			if group.Pos() >= body.Lbrace && group.End() <= body.Rbrace {
+				inBody = true
+				break
+			}
+		}
+		if !inBody {
+			comments = append(comments, group)
+		}
+	}
+	file.Comments = comments
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+// pythonDeclPattern matches Python class and function definitions
+var pythonDeclPattern = regexp.MustCompile(`^(\s*)(async\s+def|def|class)\b`)
+
+// outlinePython keeps class and function definitions with their decorators
+// and docstrings, replacing function bodies with "..."; functions nested in
+// functions are dropped with the body
+func outlinePython(content string) string {
+	lines := strings.Split(content, "\n")
+	var out []string
+	var decorators []string
+	var defIndents []int // Indentation of the enclosing functions
+	inString := ""
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// Skip the inside of multi-line strings that are not docstrings
+		if inString != "" {
+			if strings.Count(line, inString)%2 == 1 {
+				inString = ""
+			}
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+		for len(defIndents) > 0 && indent <= defIndents[len(defIndents)-1] {
+			defIndents = defIndents[:len(defIndents)-1]
+		}
+		inFunction := len(defIndents) > 0
+
+		// The module docstring describes the whole file
+		if i == firstCodeLine(lines) && isDocString(trimmed) {
+			end := docStringEnd(lines, i)
+			out = append(out, lines[i:end+1]...)
+			i = end
+			continue
+		}
+
+		switch match := pythonDeclPattern.FindStringSubmatch(line); {
+		case strings.HasPrefix(trimmed, "@") && !inFunction:
+			decorators = append(decorators, line)
+		case match != nil && !inFunction:
+			out = append(out, decorators...)
+			decorators = nil
+
+			// The signature runs to the colon closing its parentheses
+			end := i
+			for depth := 0; end < len(lines); end++ {
+				depth += bracketBalance(lines[end])
+				if depth <= 0 && strings.HasSuffix(strings.TrimSpace(stripHashComment(lines[end])), ":") {
+					break
+				}
+			}
+			if end == len(lines) {
+				end = len(lines) - 1
+			}
+			out = append(out, lines[i:end+1]...)
+			i = end
+
+			// Keep the docstring that opens the body
+			bodyIndent := match[1] + "    "
+			if next := nextCodeLine(lines, i+1); next >= 0 && isDocString(strings.TrimSpace(lines[next])) {
+				docEnd := docStringEnd(lines, next)
+				out = append(out, lines[next:docEnd+1]...)
+				bodyIndent = lines[next][:len(lines[next])-len(strings.TrimLeft(lines[next], " \t"))]
+				i = docEnd
+			} else if next >= 0 {
+				bodyIndent = lines[next][:len(lines[next])-len(strings.TrimLeft(lines[next], " \t"))]
+			}
+
+			if match[2] != "class" {
+				out = append(out, bodyIndent+"...")
+				defIndents = append(defIndents, indent)
+			}
+		default:
+			decorators = nil
+			for _, quote := range []string{`"""`, `'''`} {
+				if strings.Count(line, quote)%2 == 1 {
+					inString = quote
+				}
+			}
+		}
+	}
+
+	return strings.Join(out, "\n") + "\n"
+}
+
+// firstCodeLine returns the index of the first line that is not blank, a
+// comment, or a shebang
+func firstCodeLine(lines []string) int {
+	return nextCodeLine(lines, 0)
+}
+
+// nextCodeLine returns the index of the first line from start that is not
+// blank or a comment, or -1
+func nextCodeLine(lines []string, start int) int {
+	for i := start; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			return i
+		}
+	}
+	return -1
+}
+
+// isDocString reports whether a trimmed line opens a triple-quoted string
+func isDocString(trimmed string) bool {
+	trimmed = strings.TrimLeft(trimmed, "rRuU")
+	return strings.HasPrefix(trimmed, `"""`) || strings.HasPrefix(trimmed, `'''`)
+}
+
+// docStringEnd returns the index of the line closing the docstring opened
+// on line start
+func docStringEnd(lines []string, start int) int {
+	trimmed := strings.TrimLeft(strings.TrimSpace(lines[start]), "rRuU")
+	quote := trimmed[:3]
+	if strings.Count(trimmed, quote) >= 2 {
+		return start
+	}
+	for i := start + 1; i < len(lines); i++ {
+		if strings.Contains(lines[i], quote) {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// bracketBalance returns the opened minus the closed brackets of a line
+func bracketBalance(line string) int {
+	line = stripHashComment(line)
+	return strings.Count(line, "(") + strings.Count(line, "[") + strings.Count(line, "{") -
+		strings.Count(line, ")") - strings.Count(line, "]") - strings.Count(line, "}")
+}
+
+// stripHashComment removes a trailing # comment outside of string literals
+func stripHashComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Kinds of blocks tracked by the brace outliner
+const (
+	blockContainer = iota // Class, interface, or enum body whose members are outlined
+	blockSkip             // Function body or statement block left out
+	blockInline           // Block inside an expression, such as an object literal argument
+	blockVerbatim         // TypeScript interface, enum, or type alias kept whole
+	blockNested           // Any block inside a skipped, inline, or verbatim block
+)
+
+var (
+	// containerPattern matches declarations of types whose members are outlined
+	containerPattern = regexp.MustCompile(`\b(class|interface|enum|record|namespace|module)\b`)
+
+	// verbatimPattern matches TypeScript declarations kept whole
+	verbatimPattern = regexp.MustCompile(`^(export\s+)?(default\s+)?(declare\s+)?((interface|enum|const\s+enum)\b|type\s+[\w$]+[^=]*=)`)
+
+	// controlPattern matches statements whose blocks are never declarations
+	controlPattern = regexp.MustCompile(`^(if|else|for|while|do|switch|try|catch|finally|with|return)\b`)
+
+	// callPattern matches statements that call a function rather than
+	// declare one
+	callPattern = regexp.MustCompile(`^(await|new|return|throw|yield|delete|void|typeof)?\s*[\w$.]+\s*(<[^()]*>)?\s*\(`)
+
+	// decoratorsPattern matches a statement made only of decorators or annotations
+	decoratorsPattern = regexp.MustCompile(`^(@[\w$.]+(\([^()]*\))?\s*)+$`)
+
+	// spacePattern matches runs of whitespace collapsed in signatures
+	spacePattern = regexp.MustCompile(`\s+`)
+)
+
+// outlineBlock is an open brace block of the brace outliner
+type outlineBlock struct {
+	kind  int
+	start int    // Source offset of the opening brace (verbatim blocks)
+	head  string // Declaration text before the brace (verbatim and inline blocks)
+}
+
+// braceOutliner outlines Java and JavaScript/TypeScript by scanning braces,
+// strings, and comments, keeping type declarations and the signatures of
+// their members along with /** */ doc comments
+type braceOutliner struct {
+	src        string
+	asi        bool // Line breaks may end statements (JavaScript)
+	typeBlocks bool // Keep TypeScript interfaces, enums, and type aliases whole
+
+	out    strings.Builder
+	stack  []outlineBlock
+	stmt   strings.Builder // Text of the current statement
+	doc    string          // Doc comment preceding the current statement
+	parens int             // Open parentheses and brackets in the statement
+}
+
+// run scans the whole source and returns the outline
+func (o *braceOutliner) run() string {
+	for i := 0; i < len(o.src); {
+		c := o.src[i]
+
+		switch {
+		case strings.HasPrefix(o.src[i:], "/*"):
+			end := strings.Index(o.src[i+2:], "*/")
+			if end < 0 {
+				end = len(o.src)
+			} else {
+				end += i + 4
+			}
+			if o.outlining() && strings.HasPrefix(o.src[i:], "/**") && strings.TrimSpace(o.stmt.String()) == "" {
+				o.doc = o.src[i:end]
+			}
+			i = end
+			continue
+
+		case strings.HasPrefix(o.src[i:], "//"):
+			end := strings.IndexByte(o.src[i:], '\n')
+			if end < 0 {
+				end = len(o.src)
+			} else {
+				end += i
+			}
+			i = end
+			continue
+
+		case c == '"' || c == '\'' || c == '`', c == '/' && o.asi && o.regexAllowed(i):
+			end := o.stringEnd(i)
+			if c == '/' {
+				end = o.regexEnd(i)
+			}
+			if o.outlining() {
+				o.stmt.WriteString(o.src[i:end])
+			}
+			i = end
+			continue
+		}
+
+		i++
+		if !o.outlining() {
+			o.track(c, i)
+			continue
+		}
+
+		switch c {
+		case '{':
+			o.open(i - 1)
+		case '}':
+			o.closeContainer()
+		case ';':
+			o.endStatement()
+		case '(', '[':
+			o.parens++
+			o.stmt.WriteByte(c)
+		case ')', ']':
+			o.parens--
+			o.stmt.WriteByte(c)
+		case '\n':
+			if o.asi && o.statementEndsAtNewline(i) {
+				o.endStatement()
+			} else {
+				o.stmt.WriteByte(c)
+			}
+		default:
+			o.stmt.WriteByte(c)
+		}
+	}
+
+	return o.out.String()
+}
+
+// outlining reports whether the scanner is at the top level or directly in
+// a container body, where declarations are outlined
+func (o *braceOutliner) outlining() bool {
+	return len(o.stack) == 0 || o.stack[len(o.stack)-1].kind == blockContainer
+}
+
+// track follows braces inside skipped, inline, and verbatim blocks
+func (o *braceOutliner) track(c byte, i int) {
+	switch c {
+	case '{':
+		o.stack = append(o.stack, outlineBlock{kind: blockNested})
+	case '}':
+		block := o.stack[len(o.stack)-1]
+		o.stack = o.stack[:len(o.stack)-1]
+
+		switch block.kind {
+		case blockVerbatim:
+			o.emit(o.doc, block.head+" "+o.src[block.start:i])
+			o.doc = ""
+			o.stmt.Reset()
+		case blockInline:
+			o.stmt.WriteString("{...}")
+		}
+	}
+}
+
+// open classifies the statement before a brace and enters its block
+func (o *braceOutliner) open(at int) {
+	head := o.signature()
+
+	switch {
+	case o.typeBlocks && verbatimPattern.MatchString(head):
+		o.stack = append(o.stack, outlineBlock{kind: blockVerbatim, start: at, head: head})
+		return
+
+	case o.parens > 0 || o.isExpression(head):
+		o.stack = append(o.stack, outlineBlock{kind: blockInline, start: at, head: head})
+		return
+
+	case containerPattern.MatchString(stripParens(head)) && !strings.Contains(head, "=>") && !controlPattern.MatchString(head):
+		o.emit(o.doc, head+" {")
+		o.stack = append(o.stack, outlineBlock{kind: blockContainer})
+
+	case (strings.Contains(head, "(") || strings.Contains(head, "=>")) && !controlPattern.MatchString(head):
+		o.emit(o.doc, head+" { ... }")
+		o.stack = append(o.stack, outlineBlock{kind: blockSkip, start: at, head: head})
+
+	default:
+		o.stack = append(o.stack, outlineBlock{kind: blockSkip, start: at, head: head})
+	}
+
+	o.resetStatement()
+}
+
+// closeContainer leaves a container body
+func (o *braceOutliner) closeContainer() {
+	o.resetStatement()
+	if len(o.stack) == 0 {
+		return
+	}
+	o.stack = o.stack[:len(o.stack)-1]
+	o.emit("", "}")
+}
+
+// endStatement handles a statement ending in a semicolon or line break,
+// keeping abstract, interface, and overload signatures
+func (o *braceOutliner) endStatement() {
+	head := o.signature()
+	if isSignature(head) {
+		o.emit(o.doc, head+";")
+	} else if o.typeBlocks && verbatimPattern.MatchString(head) {
+		o.emit(o.doc, head+";")
+	}
+	o.resetStatement()
+}
+
+// isSignature reports whether a statement without a body declares a
+// function: an abstract or interface method, or an overload signature
+func isSignature(head string) bool {
+	if !strings.Contains(head, "(") || strings.Contains(stripParens(head), "=") || controlPattern.MatchString(head) ||
+		strings.HasPrefix(head, "import") || strings.HasPrefix(head, "package") {
+		return false
+	}
+
+	// A call has a single name before its arguments; a signature has a
+	// return type, modifiers, or a return type annotation
+	close := strings.LastIndex(head, ")")
+	return !callPattern.MatchString(head) || strings.HasPrefix(strings.TrimSpace(head[close+1:]), ":")
+}
+
+// resetStatement starts a new statement
+func (o *braceOutliner) resetStatement() {
+	o.stmt.Reset()
+	o.doc = ""
+	o.parens = 0
+}
+
+// signature returns the current statement with whitespace collapsed
+func (o *braceOutliner) signature() string {
+	return strings.TrimSpace(spacePattern.ReplaceAllString(o.stmt.String(), " "))
+}
+
+// isExpression reports whether a brace opens an expression, such as an
+// object literal assigned to a variable or a Java lambda body
+func (o *braceOutliner) isExpression(head string) bool {
+	if head == "" {
+		return len(o.stack) > 0
+	}
+	if strings.HasSuffix(head, "=>") {
+		return false
+	}
+	last := head[len(head)-1]
+	return strings.ContainsRune("=,:?([!&|+-", rune(last)) || strings.HasSuffix(head, "->") || strings.HasSuffix(head, "return")
+}
+
+// statementEndsAtNewline reports whether a line break at i-1 ends the
+// statement under automatic semicolon insertion
+func (o *braceOutliner) statementEndsAtNewline(i int) bool {
+	head := o.signature()
+	if head == "" || o.parens > 0 || decoratorsPattern.MatchString(head) {
+		return false
+	}
+	if strings.ContainsRune(",=+-*/&|?:<({[.", rune(head[len(head)-1])) || strings.HasSuffix(head, "=>") {
+		return false
+	}
+
+	next := strings.TrimLeft(o.src[i:], " \t\r\n")
+	if strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/*") {
+		return true
+	}
+	return next == "" || !strings.ContainsRune(".?:{=>)&|,+-*/", rune(next[0]))
+}
+
+// emit writes a declaration indented to the container depth, preceded by
+// its doc comment
+func (o *braceOutliner) emit(doc, text string) {
+	indent := strings.Repeat(outlineIndent, len(o.stack))
+	if doc != "" {
+		for _, line := range strings.Split(doc, "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "*") {
+				line = " " + line
+			}
+			o.out.WriteString(indent + line + "\n")
+		}
+	}
+	o.out.WriteString(indent + text + "\n")
+}
+
+// stringEnd returns the index just past the string literal starting at i
+func (o *braceOutliner) stringEnd(i int) int {
+	quote := o.src[i]
+	for j := i + 1; j < len(o.src); j++ {
+		switch o.src[j] {
+		case '\\':
+			j++
+		case quote:
+			return j + 1
+		case '\n':
+			if quote != '`' {
+				return j
+			}
+		}
+	}
+	return len(o.src)
+}
+
+// regexAllowed reports whether the slash at i starts a regular expression
+// literal rather than a division, judging by the preceding token
+func (o *braceOutliner) regexAllowed(i int) bool {
+	if strings.HasPrefix(o.src[i:], "//") || strings.HasPrefix(o.src[i:], "/*") {
+		return false
+	}
+	text := strings.TrimRight(o.src[:i], " \t\r\n")
+	if text == "" {
+		return true
+	}
+	for _, keyword := range []string{"return", "typeof", "case", "in", "of"} {
+		if strings.HasSuffix(text, keyword) && (len(text) == len(keyword) || !isWordByte(text[len(text)-len(keyword)-1])) {
+			return true
+		}
+	}
+	return strings.ContainsRune("(,=:[!&|?{};+-*%<>~^", rune(text[len(text)-1]))
+}
+
+// regexEnd returns the index just past the regular expression literal
+// starting at i
+func (o *braceOutliner) regexEnd(i int) int {
+	inClass := false
+	for j := i + 1; j < len(o.src); j++ {
+		switch o.src[j] {
+		case '\\':
+			j++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '/':
+			if !inClass {
+				return j + 1
+			}
+		case '\n':
+			return j
+		}
+	}
+	return len(o.src)
+}
+
+// stripParens removes parenthesized text, leaving the declaration keywords
+func stripParens(s string) string {
+	var builder strings.Builder
+	depth := 0
+	for _, r := range s {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
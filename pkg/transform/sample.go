@@ -0,0 +1,94 @@
+package transform
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// dataExtensions lists delimited data files and whether their fields may be
+// quoted across lines
+var dataExtensions = map[string]bool{
+	".csv": true,
+	".tsv": false, ".tab": false, ".psv": false,
+}
+
+// SampleRows shortens a delimited data file to its header row and the first
+// n data rows, followed by a marker such as "[... 48,201 more rows ...]".
+// Quoted CSV fields may span lines. Other files and data files with at most
+// n rows are returned unchanged.
+func SampleRows(path, content string, n int) string {
+	quoted, ok := dataExtensions[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return content
+	}
+
+	// Find where the header and the first n rows end, then count the rest
+	rows, cut := -1, -1
+	inQuotes := false
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '"':
+			inQuotes = quoted && !inQuotes
+		case '\n':
+			if inQuotes {
+				continue
+			}
+			rows++
+			if rows == n {
+				cut = i
+			}
+		}
+	}
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		rows++
+	}
+
+	if cut < 0 || rows <= n {
+		return content
+	}
+	noun := "rows"
+	if rows-n == 1 {
+		noun = "row"
+	}
+	return fmt.Sprintf("%s\n[... %s more %s ...]", content[:cut], formatCount(rows-n), noun)
+}
+
+// formatCount formats a count with thousands separators, such as 48,201
+func formatCount(count int) string {
+	digits := strconv.Itoa(count)
+	var builder strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteRune(digit)
+	}
+	return builder.String()
+}
+
+// logFilePattern matches log files, including rotated ones such as app.log.1
+var logFilePattern = regexp.MustCompile(`(?i)\.log(\.\d+)?$`)
+
+// TailLines shortens a log file to its last n lines, preceded by a marker
+// such as "[... 1,204 earlier lines ...]". Other files and logs with at most
+// n lines are returned unchanged.
+func TailLines(path, content string, n int) string {
+	if !logFilePattern.MatchString(path) {
+		return content
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) <= n {
+		return content
+	}
+
+	skipped := len(lines) - n
+	noun := "lines"
+	if skipped == 1 {
+		noun = "line"
+	}
+	return fmt.Sprintf("[... %s earlier %s ...]\n%s", formatCount(skipped), noun, strings.Join(lines[skipped:], "\n"))
+}
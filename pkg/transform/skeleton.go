@@ -0,0 +1,564 @@
+package transform
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// maxSkeletonKeys limits the keys shown for one object; objects used as maps
+// keyed by IDs would otherwise be as long as the data
+const maxSkeletonKeys = 50
+
+// skeletonFormats maps file extensions to the name of their format
+var skeletonFormats = map[string]string{
+	".json": "JSON", ".geojson": "JSON", ".har": "JSON",
+	".yaml": "YAML", ".yml": "YAML",
+}
+
+// shape is the structure of a value, merged across the elements of arrays:
+// the scalar types seen, and the shape of objects and arrays seen
+type shape struct {
+	scalars []string // Scalar types in order of appearance (string, number, bool, null)
+	object  *objectShape
+	array   *arrayShape
+}
+
+// objectShape is the structure of objects: the union of their keys
+type objectShape struct {
+	keys   []string
+	fields map[string]*shape
+}
+
+// arrayShape is the structure of arrays: their length range and the merged
+// shape of their elements
+type arrayShape struct {
+	minLen, maxLen int
+	elem           *shape
+}
+
+// Skeleton replaces a JSON or YAML document with its structure: keys, value
+// types, and array lengths, with the shapes of array elements merged. It
+// reports false for other files and documents that do not parse.
+func Skeleton(path, content string) (string, bool) {
+	format := skeletonFormats[strings.ToLower(filepath.Ext(path))]
+
+	var root *shape
+	var err error
+	switch format {
+	case "JSON":
+		root, err = jsonShape(content)
+	case "YAML":
+		root, err = yamlShape(content)
+	default:
+		return "", false
+	}
+	if err != nil {
+		return "", false
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("[%s skeleton of %s: keys, types, and array lengths; values omitted]\n",
+		format, utils.FormatSize(int64(len(content)))))
+	root.render(&builder, "")
+	builder.WriteString("\n")
+	return builder.String(), true
+}
+
+// merge folds another shape into s
+func (s *shape) merge(other *shape) {
+	for _, scalar := range other.scalars {
+		s.addScalar(scalar)
+	}
+
+	if other.object != nil {
+		if s.object == nil {
+			s.object = &objectShape{fields: map[string]*shape{}}
+		}
+		for _, key := range other.object.keys {
+			s.object.set(key, other.object.fields[key])
+		}
+	}
+
+	if other.array != nil {
+		if s.array == nil {
+			s.array = &arrayShape{minLen: other.array.minLen, maxLen: other.array.maxLen}
+		}
+		s.array.minLen = min(s.array.minLen, other.array.minLen)
+		s.array.maxLen = max(s.array.maxLen, other.array.maxLen)
+		if other.array.elem != nil {
+			s.array.add(other.array.elem)
+		}
+	}
+}
+
+// addScalar records a scalar type
+func (s *shape) addScalar(scalar string) {
+	for _, existing := range s.scalars {
+		if existing == scalar {
+			return
+		}
+	}
+	s.scalars = append(s.scalars, scalar)
+}
+
+// set merges the shape of a field into the object
+func (o *objectShape) set(key string, value *shape) {
+	if existing, ok := o.fields[key]; ok {
+		existing.merge(value)
+		return
+	}
+	o.keys = append(o.keys, key)
+	merged := &shape{}
+	merged.merge(value)
+	o.fields[key] = merged
+}
+
+// add merges the shape of an element into the array
+func (a *arrayShape) add(elem *shape) {
+	if a.elem == nil {
+		a.elem = &shape{}
+	}
+	a.elem.merge(elem)
+}
+
+// render writes the shape, with nested lines indented below indent
+func (s *shape) render(builder *strings.Builder, indent string) {
+	parts := 0
+	separate := func() {
+		if parts > 0 {
+			builder.WriteString(" | ")
+		}
+		parts++
+	}
+
+	for _, scalar := range s.scalars {
+		separate()
+		builder.WriteString(scalar)
+	}
+
+	if s.object != nil {
+		separate()
+		if len(s.object.keys) == 0 {
+			builder.WriteString("{}")
+		} else {
+			builder.WriteString("{\n")
+			for i, key := range s.object.keys {
+				if i == maxSkeletonKeys {
+					builder.WriteString(fmt.Sprintf("%s  ... %d more keys\n", indent, len(s.object.keys)-i))
+					break
+				}
+				builder.WriteString(indent + "  " + skeletonKey(key) + ": ")
+				s.object.fields[key].render(builder, indent+"  ")
+				builder.WriteString("\n")
+			}
+			builder.WriteString(indent + "}")
+		}
+	}
+
+	if s.array != nil {
+		separate()
+		length := fmt.Sprint(s.array.maxLen)
+		if s.array.minLen != s.array.maxLen {
+			length = fmt.Sprintf("%d-%d", s.array.minLen, s.array.maxLen)
+		}
+		if s.array.elem == nil {
+			builder.WriteString("[]")
+		} else {
+			builder.WriteString("[" + length + " × ")
+			s.array.elem.render(builder, indent)
+			builder.WriteString("]")
+		}
+	}
+
+	if parts == 0 {
+		builder.WriteString("unknown")
+	}
+}
+
+// plainKeyPattern matches keys shown without quotes
+var plainKeyPattern = regexp.MustCompile(`^[\w$.@/<-]+$`)
+
+// skeletonKey quotes keys that would be ambiguous unquoted
+func skeletonKey(key string) string {
+	if plainKeyPattern.MatchString(key) {
+		return key
+	}
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+// jsonShape reads the shape of a JSON document, keeping keys in order
+func jsonShape(content string) (*shape, error) {
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+
+	root, err := readJSONShape(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, errors.New("trailing data after JSON value")
+	}
+	return root, nil
+}
+
+// readJSONShape reads the shape of the next JSON value
+func readJSONShape(decoder *json.Decoder) (*shape, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token := token.(type) {
+	case json.Delim:
+		if token == '{' {
+			object := &objectShape{fields: map[string]*shape{}}
+			for decoder.More() {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := readJSONShape(decoder)
+				if err != nil {
+					return nil, err
+				}
+				object.set(fmt.Sprint(key), value)
+			}
+			_, err := decoder.Token()
+			return &shape{object: object}, err
+		}
+
+		array := &arrayShape{}
+		for decoder.More() {
+			elem, err := readJSONShape(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array.add(elem)
+			array.maxLen++
+		}
+		array.minLen = array.maxLen
+		_, err := decoder.Token()
+		return &shape{array: array}, err
+
+	case string:
+		return &shape{scalars: []string{"string"}}, nil
+	case json.Number:
+		return &shape{scalars: []string{"number"}}, nil
+	case bool:
+		return &shape{scalars: []string{"bool"}}, nil
+	default:
+		return &shape{scalars: []string{"null"}}, nil
+	}
+}
+
+// yamlLine is a significant line of a YAML document
+type yamlLine struct {
+	indent int
+	text   string // Text without indentation and trailing comment
+}
+
+// Patterns recognized in YAML documents
+var (
+	yamlKeyPattern    = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s#'"{\[][^#]*?|<<)\s*:(?:\s+|$)`)
+	yamlNumberPattern = regexp.MustCompile(`^[-+]?(\d[\d_]*(\.\d*)?([eE][-+]?\d+)?|\.\d+([eE][-+]?\d+)?|0x[0-9a-fA-F]+|0o[0-7]+|\.inf|\.Inf|\.INF|\.nan|\.NaN|\.NAN)$`)
+	yamlPropsPattern  = regexp.MustCompile(`^((&[^\s]+|![^\s]*)(\s+|$))+`)
+)
+
+// yamlShape reads the shape of a YAML stream in block style. Several
+// documents are merged like the elements of an array.
+func yamlShape(content string) (*shape, error) {
+	var documents [][]yamlLine
+	var current []yamlLine
+	block := -1 // Indentation of the key owning a block scalar, or -1
+
+	for _, raw := range strings.Split(content, "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+		trimmed := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(trimmed)
+
+		// Lines of block scalars are content, not structure
+		if block >= 0 {
+			if trimmed == "" || indent > block {
+				continue
+			}
+			block = -1
+		}
+
+		if trimmed == "---" || strings.HasPrefix(trimmed, "--- ") || trimmed == "..." {
+			if indent == 0 {
+				if len(current) > 0 {
+					documents = append(documents, current)
+				}
+				current = nil
+				continue
+			}
+		}
+		if strings.HasPrefix(trimmed, "%") && indent == 0 {
+			continue // Directive
+		}
+
+		text := strings.TrimSpace(stripYAMLComment(trimmed))
+		if text == "" {
+			continue
+		}
+		current = append(current, yamlLine{indent: indent, text: text})
+
+		// A block scalar continues on lines indented past its key, or past
+		// the dash of a sequence item
+		offset, entry := yamlEntry(text)
+		value := entry
+		if key := yamlKeyPattern.FindString(entry); key != "" {
+			value = entry[len(key):]
+		} else {
+			offset = 0
+		}
+		value = yamlPropsPattern.ReplaceAllString(strings.TrimSpace(value), "")
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			block = indent + offset
+		}
+	}
+	if len(current) > 0 {
+		documents = append(documents, current)
+	}
+
+	if len(documents) == 0 {
+		return nil, errors.New("empty YAML document")
+	}
+	if len(documents) == 1 {
+		return parseYAML(documents[0]), nil
+	}
+
+	array := &arrayShape{minLen: len(documents), maxLen: len(documents)}
+	for _, document := range documents {
+		array.add(parseYAML(document))
+	}
+	return &shape{array: array}, nil
+}
+
+// parseYAML parses the lines of one document
+func parseYAML(lines []yamlLine) *shape {
+	p := &yamlParser{lines: lines}
+	return p.node(lines[0].indent)
+}
+
+// yamlParser is a recursive descent parser over the lines of a document
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// node parses the value starting at the current line, indented by indent
+func (p *yamlParser) node(indent int) *shape {
+	if p.pos >= len(p.lines) {
+		return &shape{scalars: []string{"null"}}
+	}
+	line := p.lines[p.pos]
+
+	switch {
+	case line.text == "-" || strings.HasPrefix(line.text, "- "):
+		return p.sequence(line.indent)
+	case yamlKeyPattern.MatchString(line.text):
+		return p.mapping(line.indent)
+	}
+
+	// A plain scalar, possibly continued on more indented lines
+	p.pos++
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		p.pos++
+	}
+	return yamlScalar(line.text)
+}
+
+// sequence parses the items of a block sequence
+func (p *yamlParser) sequence(indent int) *shape {
+	array := &arrayShape{}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || line.text != "-" && !strings.HasPrefix(line.text, "- ") {
+			break
+		}
+
+		rest := strings.TrimSpace(strings.TrimPrefix(line.text, "-"))
+		if rest == "" {
+			// The item is on the following, more indented lines
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				array.add(p.node(p.lines[p.pos].indent))
+			} else {
+				array.add(&shape{scalars: []string{"null"}})
+			}
+		} else {
+			// The item starts on this line, as if indented past the dash
+			p.lines[p.pos] = yamlLine{indent: indent + len(line.text) - len(rest), text: rest}
+			array.add(p.node(p.lines[p.pos].indent))
+		}
+		array.maxLen++
+	}
+	array.minLen = array.maxLen
+	return &shape{array: array}
+}
+
+// mapping parses the entries of a block mapping
+func (p *yamlParser) mapping(indent int) *shape {
+	object := &objectShape{fields: map[string]*shape{}}
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent {
+			break
+		}
+		match := yamlKeyPattern.FindStringSubmatch(line.text)
+		if match == nil {
+			break
+		}
+
+		key := unquoteYAML(strings.TrimSpace(match[1]))
+		value := strings.TrimSpace(line.text[len(match[0]):])
+		value = yamlPropsPattern.ReplaceAllString(value, "")
+		p.pos++
+
+		switch {
+		case value != "":
+			// Skip the continuation lines of multi-line plain scalars
+			object.set(key, yamlScalar(value))
+			for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				p.pos++
+			}
+		case p.pos < len(p.lines) && (p.lines[p.pos].indent > indent ||
+			p.lines[p.pos].indent == indent && strings.HasPrefix(p.lines[p.pos].text+" ", "- ")):
+			// A nested block, or a sequence indented like its key
+			object.set(key, p.node(p.lines[p.pos].indent))
+		default:
+			object.set(key, &shape{scalars: []string{"null"}})
+		}
+	}
+	return &shape{object: object}
+}
+
+// yamlEntry strips the dashes of sequence items starting a line and returns
+// the remaining text and how far it is indented past the line's indentation
+func yamlEntry(text string) (int, string) {
+	offset := 0
+	for strings.HasPrefix(text, "- ") {
+		rest := strings.TrimLeft(text[1:], " ")
+		offset += len(text) - len(rest)
+		text = rest
+	}
+	return offset, text
+}
+
+// yamlScalar returns the shape of an inline value: a scalar or a flow
+// collection
+func yamlScalar(value string) *shape {
+	value = yamlPropsPattern.ReplaceAllString(value, "")
+
+	switch {
+	case strings.HasPrefix(value, "{") || strings.HasPrefix(value, "["):
+		return flowShape(value)
+	case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"),
+		strings.HasPrefix(value, `"`), strings.HasPrefix(value, "'"):
+		return &shape{scalars: []string{"string"}}
+	case strings.HasPrefix(value, "*"):
+		return &shape{scalars: []string{"alias"}}
+	case value == "" || value == "~" || value == "null" || value == "Null" || value == "NULL":
+		return &shape{scalars: []string{"null"}}
+	case value == "true" || value == "false" || value == "True" || value == "False" || value == "TRUE" || value == "FALSE":
+		return &shape{scalars: []string{"bool"}}
+	case yamlNumberPattern.MatchString(value):
+		return &shape{scalars: []string{"number"}}
+	}
+	return &shape{scalars: []string{"string"}}
+}
+
+// flowShape returns the shape of a single-line flow collection such as
+// [a, b] or {x: 1}
+func flowShape(value string) *shape {
+	if strings.HasSuffix(value, "]") && strings.HasPrefix(value, "[") {
+		array := &arrayShape{}
+		for _, item := range splitFlow(value[1 : len(value)-1]) {
+			array.add(yamlScalar(item))
+			array.maxLen++
+		}
+		array.minLen = array.maxLen
+		return &shape{array: array}
+	}
+
+	if strings.HasSuffix(value, "}") && strings.HasPrefix(value, "{") {
+		object := &objectShape{fields: map[string]*shape{}}
+		for _, item := range splitFlow(value[1 : len(value)-1]) {
+			key, val, _ := strings.Cut(item, ":")
+			object.set(unquoteYAML(strings.TrimSpace(key)), yamlScalar(strings.TrimSpace(val)))
+		}
+		return &shape{object: object}
+	}
+
+	// Flow collections spanning lines are not followed
+	return &shape{scalars: []string{"flow"}}
+}
+
+// splitFlow splits the items of a flow collection on top-level commas
+func splitFlow(list string) []string {
+	var items []string
+	depth, start := 0, 0
+	var quote byte
+
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(list[start:i]))
+			start = i + 1
+		}
+	}
+	if item := strings.TrimSpace(list[start:]); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
+
+// stripYAMLComment removes a comment, which starts with # at the start of
+// the line or after whitespace, outside of quotes
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.IndexByte(" \t:[{,-", line[i-1]) >= 0 {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// unquoteYAML removes the quotes around a key
+func unquoteYAML(key string) string {
+	if len(key) >= 2 && (key[0] == '"' && key[len(key)-1] == '"' || key[0] == '\'' && key[len(key)-1] == '\'') {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
@@ -0,0 +1,119 @@
+package transform
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/agris/ingest-clone/pkg/utils"
+)
+
+// minEmbeddedLength is the shortest base64 payload worth replacing; tiny
+// inline icons cost fewer tokens than the placeholder that would replace them
+const minEmbeddedLength = 128
+
+var (
+	// dataURIPattern matches base64 data URIs such as those embedded in Markdown, HTML, CSS, and SVG
+	dataURIPattern = regexp.MustCompile(`data:([\w.+-]+/[\w.+-]+)((?:;[\w.+-]+=[\w.+-]+)*);base64,([A-Za-z0-9+/=]+)`)
+
+	// svgDataURIPattern matches base64 data URIs in SVG attributes, where
+	// editors wrap the payload across lines or with character references
+	svgDataURIPattern = regexp.MustCompile(`data:([\w.+-]+/[\w.+-]+)((?:;[\w.+-]+=[\w.+-]+)*);base64,((?:[A-Za-z0-9+/=\s]|&#(?:10|13|x[aAdD]);)+)`)
+
+	// notebookOutputPattern matches base64 payloads in Jupyter notebook output
+	// bundles, which are stored as JSON strings keyed by MIME type
+	notebookOutputPattern = regexp.MustCompile(`("((?:image|application|audio|video)/[\w.+-]+)"\s*:\s*)"((?:[A-Za-z0-9+/=]|\\n)+)"`)
+)
+
+// StripEmbeddedBase64 replaces embedded base64 blobs with short placeholders
+// such as "[embedded image/png, 12.3 KB removed]"
+func StripEmbeddedBase64(path, content string) string {
+	if strings.ToLower(filepath.Ext(path)) == ".svg" {
+		content = svgDataURIPattern.ReplaceAllStringFunc(content, func(match string) string {
+			parts := svgDataURIPattern.FindStringSubmatch(match)
+			payload := svgPayloadNoise.Replace(parts[3])
+			if len(payload) < minEmbeddedLength {
+				return match
+			}
+			return embeddedPlaceholder(parts[1], payload)
+		})
+	}
+
+	content = dataURIPattern.ReplaceAllStringFunc(content, func(match string) string {
+		parts := dataURIPattern.FindStringSubmatch(match)
+		if len(parts[3]) < minEmbeddedLength {
+			return match
+		}
+		return embeddedPlaceholder(parts[1], parts[3])
+	})
+
+	if strings.ToLower(filepath.Ext(path)) == ".ipynb" {
+		content = notebookOutputPattern.ReplaceAllStringFunc(content, func(match string) string {
+			parts := notebookOutputPattern.FindStringSubmatch(match)
+			payload := strings.ReplaceAll(parts[3], `\n`, "")
+			if len(payload) < minEmbeddedLength {
+				return match
+			}
+			// Keep the notebook valid JSON by replacing only the string value
+			return fmt.Sprintf(`%s"%s"`, parts[1], embeddedPlaceholder(parts[2], payload))
+		})
+	}
+
+	return content
+}
+
+// svgPayloadNoise removes the line breaks and character references that
+// SVG editors insert into base64 payloads
+var svgPayloadNoise = strings.NewReplacer("\n", "", "\r", "", " ", "", "\t", "",
+	"&#10;", "", "&#13;", "", "&#xa;", "", "&#xA;", "", "&#xd;", "", "&#xD;", "")
+
+// embeddedPlaceholder describes a removed base64 payload by its decoded size
+func embeddedPlaceholder(mimeType, payload string) string {
+	size := int64(len(strings.TrimRight(payload, "="))) * 3 / 4
+	return fmt.Sprintf("[embedded %s, %s removed]", mimeType, utils.FormatSize(size))
+}
+
+// NormalizeEOL converts CRLF and lone CR line endings to LF
+func NormalizeEOL(content string) string {
+	if !strings.Contains(content, "\r") {
+		return content
+	}
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	return strings.ReplaceAll(content, "\r", "\n")
+}
+
+// StripTrailingWhitespace removes spaces and tabs at the end of every line,
+// preserving CRLF line endings
+func StripTrailingWhitespace(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if strings.HasSuffix(line, "\r") {
+			lines[i] = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t") + "\r"
+		} else {
+			lines[i] = strings.TrimRight(line, " \t")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CollapseBlankLines limits runs of blank (whitespace-only) lines to at most max lines
+func CollapseBlankLines(content string, max int) string {
+	lines := strings.Split(content, "\n")
+	result := make([]string, 0, len(lines))
+
+	blanks := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			blanks++
+			if blanks > max {
+				continue
+			}
+		} else {
+			blanks = 0
+		}
+		result = append(result, line)
+	}
+
+	return strings.Join(result, "\n")
+}
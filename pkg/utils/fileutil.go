@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 )
 
@@ -78,3 +79,15 @@ func FormatTokenCount(count int) string {
 
 	return fmt.Sprintf("%.1fM", float64(count)/1000000)
 }
+
+// testNamePattern matches the names of test files across languages: Go
+// _test.go files, JS/TS .test and .spec files, Python test_*.py and
+// *_test.py, JUnit-style *Test and *Tests classes, and Ruby specs
+var testNamePattern = regexp.MustCompile(`(_test\.go|\.(test|spec)\.[cm]?[jt]sx?|^test_.*\.py|_test\.py|^conftest\.py|(Tests?|IT)\.(java|kt|scala|cs|php|swift)|_(spec|test)\.rb)$`)
+
+// IsTestFile reports whether a path names a test file, by its name, or a
+// __tests__ directory or a file inside one
+func IsTestFile(path string) bool {
+	path = filepath.ToSlash(path)
+	return testNamePattern.MatchString(filepath.Base(path)) || strings.Contains("/"+path+"/", "/__tests__/")
+}
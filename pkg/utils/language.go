@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// languagesByExt maps file extensions to language identifiers, using the
+// names common Markdown renderers accept for code fences
+var languagesByExt = map[string]string{
+	".go": "go", ".py": "python", ".rb": "ruby", ".rs": "rust",
+	".js": "javascript", ".mjs": "javascript", ".cjs": "javascript", ".jsx": "jsx",
+	".ts": "typescript", ".tsx": "tsx", ".java": "java", ".kt": "kotlin",
+	".scala": "scala", ".swift": "swift", ".c": "c", ".h": "c",
+	".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp", ".hpp": "cpp",
+	".cs": "csharp", ".php": "php", ".pl": "perl", ".lua": "lua",
+	".sh": "bash", ".bash": "bash", ".zsh": "zsh", ".fish": "fish",
+	".ps1": "powershell", ".sql": "sql", ".html": "html", ".htm": "html",
+	".css": "css", ".scss": "scss", ".less": "less", ".xml": "xml",
+	".svg": "xml", ".json": "json", ".yaml": "yaml", ".yml": "yaml",
+	".toml": "toml", ".ini": "ini", ".md": "markdown", ".markdown": "markdown",
+	".rst": "rst", ".tf": "hcl", ".hcl": "hcl", ".proto": "protobuf",
+	".dart": "dart", ".ex": "elixir", ".exs": "elixir", ".erl": "erlang",
+	".hs": "haskell", ".ml": "ocaml", ".r": "r", ".vue": "vue",
+	".ipynb": "json", ".csv": "csv", ".tsv": "tsv", ".txt": "text",
+}
+
+// languagesByName maps well-known file names to language identifiers
+var languagesByName = map[string]string{
+	"makefile": "makefile", "gnumakefile": "makefile", "dockerfile": "dockerfile",
+	"cmakelists.txt": "cmake", "gemfile": "ruby", "rakefile": "ruby",
+	"go.mod": "go-mod", "go.sum": "text",
+}
+
+// languagesByInterpreter maps shebang interpreters, without version
+// suffixes, to language identifiers
+var languagesByInterpreter = map[string]string{
+	"sh": "bash", "bash": "bash", "dash": "bash", "ash": "bash", "ksh": "bash",
+	"zsh": "zsh", "fish": "fish", "python": "python", "pypy": "python",
+	"node": "javascript", "nodejs": "javascript", "deno": "typescript", "bun": "javascript",
+	"ts-node": "typescript", "ruby": "ruby", "perl": "perl", "php": "php",
+	"lua": "lua", "rscript": "r", "pwsh": "powershell", "awk": "awk",
+	"gawk": "awk", "tclsh": "tcl", "elixir": "elixir", "escript": "erlang",
+	"runhaskell": "haskell", "groovy": "groovy", "make": "makefile",
+}
+
+// DetectLanguage returns the language identifier for a file based on its
+// name, or an empty string if the language is unknown
+func DetectLanguage(path string) string {
+	name := strings.ToLower(filepath.Base(path))
+	if lang, ok := languagesByName[name]; ok {
+		return lang
+	}
+
+	return languagesByExt[strings.ToLower(filepath.Ext(name))]
+}
+
+// DetectContentLanguage returns the language identifier for a file based
+// on its name, falling back to the shebang line of extensionless scripts
+func DetectContentLanguage(path, content string) string {
+	if lang := DetectLanguage(path); lang != "" {
+		return lang
+	}
+	return ShebangLanguage(content)
+}
+
+// ShebangLanguage returns the language identifier named by the shebang line
+// of content (#!/bin/sh, #!/usr/bin/env python3), or an empty string
+func ShebangLanguage(content string) string {
+	if !strings.HasPrefix(content, "#!") {
+		return ""
+	}
+
+	line, _, _ := strings.Cut(content[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// env runs the first argument that is not an option or assignment
+	interpreter := fields[0]
+	if filepath.Base(interpreter) == "env" {
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") && !strings.Contains(field, "=") {
+				interpreter = field
+				break
+			}
+		}
+	}
+
+	// Drop the directory and version suffixes such as python3.12
+	name := strings.ToLower(filepath.Base(interpreter))
+	name = strings.TrimRight(name, "0123456789.")
+	return languagesByInterpreter[name]
+}
+
+// EstimateTokens estimates the number of tokens in a piece of text
+func EstimateTokens(content string) int {
+	// Simple estimation: 1 token ≈ 4 characters
+	return len(content) / 4
+}